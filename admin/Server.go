@@ -0,0 +1,224 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/collector/nagflux"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/target"
+	"github.com/spitefulgrog/nagflux/wal"
+	"github.com/kdar/factorlog"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+//Target is a running target as it is seen by the API.
+type Target struct {
+	Target data.Target
+	//Connector is inspected by the interfaces it implements like IsAlive, DatabaseExists or target.HasWorker.
+	Connector interface{}
+	Queue     chan collector.Printable
+	//Drainer is nil if the target has no write-ahead log.
+	Drainer *wal.Drainer
+}
+
+//Provider returns the running targets, they may change on a reload.
+type Provider interface {
+	Targets() []Target
+}
+
+//TargetState is the JSON representation of a target.
+type TargetState struct {
+	Name           string        `json:"name"`
+	Type           data.Datatype `json:"type"`
+	QueueLength    int           `json:"queue_length"`
+	QueueCapacity  int           `json:"queue_capacity"`
+	Alive          *bool         `json:"alive,omitempty"`
+	DatabaseExists *bool         `json:"database_exists,omitempty"`
+	Workers        *int          `json:"workers,omitempty"`
+	Paused         bool          `json:"paused"`
+	PausedManually bool          `json:"paused_manually"`
+	WalPending     *int64        `json:"wal_pending_bytes,omitempty"`
+	WalEvicted     *int64        `json:"wal_evicted_bytes,omitempty"`
+}
+
+//Server provides the admin API, every request needs the token if one is configured.
+type Server struct {
+	provider Provider
+	token    string
+	log      *factorlog.FactorLog
+}
+
+//NewServer creates the API, call Register or ListenAndServe to make it reachable.
+func NewServer(provider Provider, token string) *Server {
+	return &Server{provider: provider, token: token, log: logging.GetLogger()}
+}
+
+//Start serves the API on the given address. If it's the address of the prometheus metrics their listener is used.
+func (s *Server) Start(address, prometheusAddress string) {
+	if address == prometheusAddress {
+		s.Register(http.DefaultServeMux)
+	} else {
+		mux := http.NewServeMux()
+		s.Register(mux)
+		go func() {
+			if err := http.ListenAndServe(address, mux); err != nil {
+				s.log.Warn(err.Error())
+			}
+		}()
+	}
+	s.log.Infof("serving admin API at %s/admin/targets", address)
+}
+
+//Register adds the handlers to the mux.
+func (s *Server) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/admin/targets", s.authorized(s.listTargets))
+	mux.HandleFunc("/admin/targets/pause", s.authorized(s.post(s.pause(true))))
+	mux.HandleFunc("/admin/targets/resume", s.authorized(s.post(s.pause(false))))
+	mux.HandleFunc("/admin/targets/addworker", s.authorized(s.post(s.addWorker)))
+	mux.HandleFunc("/admin/targets/removeworker", s.authorized(s.post(s.removeWorker)))
+	mux.HandleFunc("/admin/targets/replay", s.authorized(s.post(s.replay)))
+}
+
+//Checks the bearer token, the comparison takes constant time.
+func (s *Server) authorized(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(given), []byte(s.token)) != 1 {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		handler(w, r)
+	}
+}
+
+//Wraps an action, which is applied to the targets selected by the query parameters name and type.
+func (s *Server) post(action func(Target) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			http.Error(w, "The parameter name is missing", http.StatusBadRequest)
+			return
+		}
+		datatype := data.Datatype(r.URL.Query().Get("type"))
+		var states []TargetState
+		for _, t := range s.provider.Targets() {
+			if t.Target.Name != name || (datatype != "" && t.Target.Datatype != datatype) {
+				continue
+			}
+			if err := action(t); err != nil {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			s.log.Infof("Admin API: %s on %s(%s)", r.URL.Path, t.Target.Name, t.Target.Datatype)
+			states = append(states, state(t))
+		}
+		if len(states) == 0 {
+			http.Error(w, "Target not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, states)
+	}
+}
+
+func (s *Server) listTargets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	states := []TargetState{}
+	for _, t := range s.provider.Targets() {
+		states = append(states, state(t))
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].Type != states[j].Type {
+			return states[i].Type < states[j].Type
+		}
+		return states[i].Name < states[j].Name
+	})
+	writeJSON(w, states)
+}
+
+//While a target is paused Nagflux stops reading new data, like it does if a target with StopPullingDataIfDown is down.
+func (s *Server) pause(value bool) func(Target) error {
+	return func(t Target) error {
+		config.StoreManualPause(t.Target, value)
+		return nil
+	}
+}
+
+func (s *Server) addWorker(t Target) error {
+	hasWorker, ok := t.Connector.(target.HasWorker)
+	if !ok {
+		return fmt.Errorf("%s(%s) has no workers", t.Target.Name, t.Target.Datatype)
+	}
+	hasWorker.AddWorker()
+	return nil
+}
+
+func (s *Server) removeWorker(t Target) error {
+	hasWorker, ok := t.Connector.(target.HasWorker)
+	if !ok {
+		return fmt.Errorf("%s(%s) has no workers", t.Target.Name, t.Target.Datatype)
+	}
+	hasWorker.RemoveWorker()
+	return nil
+}
+
+//Imports the dumpfile into the write-ahead log and drains it.
+func (s *Server) replay(t Target) error {
+	if t.Drainer == nil {
+		return fmt.Errorf("%s(%s) has no write-ahead log", t.Target.Name, t.Target.Datatype)
+	}
+	dumpFile := nagflux.GenDumpfileName(config.GetConfig().Main.DumpFile, t.Target)
+	return t.Drainer.Replay(dumpFile, t.Target.Datatype == data.Elasticsearch)
+}
+
+func state(t Target) TargetState {
+	result := TargetState{
+		Name: t.Target.Name, Type: t.Target.Datatype,
+		QueueLength: len(t.Queue), QueueCapacity: cap(t.Queue),
+	}
+	result.Paused, result.PausedManually = config.GetPause(t.Target)
+	if c, ok := t.Connector.(interface {
+		IsAlive() bool
+	}); ok {
+		alive := c.IsAlive()
+		result.Alive = &alive
+	}
+	if c, ok := t.Connector.(interface {
+		DatabaseExists() bool
+	}); ok {
+		exists := c.DatabaseExists()
+		result.DatabaseExists = &exists
+	}
+	if c, ok := t.Connector.(interface {
+		AmountWorkers() int
+	}); ok {
+		workers := c.AmountWorkers()
+		result.Workers = &workers
+	}
+	if t.Drainer != nil {
+		pending, evicted := t.Drainer.Pending(), t.Drainer.Evicted()
+		result.WalPending, result.WalEvicted = &pending, &evicted
+	}
+	return result
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		logging.GetLogger().Warn(err)
+	}
+}
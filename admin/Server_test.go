@@ -0,0 +1,95 @@
+package admin
+
+import (
+	"encoding/json"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/logging"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeConnector struct {
+	workers int
+}
+
+func (c *fakeConnector) IsAlive() bool      { return true }
+func (c *fakeConnector) AmountWorkers() int { return c.workers }
+func (c *fakeConnector) AddWorker()         { c.workers++ }
+func (c *fakeConnector) RemoveWorker()      { c.workers-- }
+
+type fakeProvider []Target
+
+func (p fakeProvider) Targets() []Target {
+	return p
+}
+
+func request(handler http.Handler, method, url, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestServer(t *testing.T) {
+	logging.InitTestLogger()
+	connector := &fakeConnector{workers: 2}
+	influxTarget := data.Target{Name: "influx", Datatype: data.InfluxDB}
+	queue := make(chan collector.Printable, 10)
+	queue <- collector.SimplePrintable{}
+	provider := fakeProvider{
+		{Target: influxTarget, Connector: connector, Queue: queue},
+		{Target: data.Target{Name: "file", Datatype: data.JSONFile}, Connector: struct{}{}, Queue: make(chan collector.Printable)},
+	}
+	mux := http.NewServeMux()
+	NewServer(provider, "secret").Register(mux)
+
+	if r := request(mux, "GET", "/admin/targets", ""); r.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", r.Code)
+	}
+
+	r := request(mux, "GET", "/admin/targets", "secret")
+	var states []TargetState
+	if err := json.Unmarshal(r.Body.Bytes(), &states); err != nil {
+		t.Fatal(err, r.Body.String())
+	}
+	if len(states) != 2 || states[0].Name != "influx" || states[0].QueueLength != 1 || states[0].QueueCapacity != 10 {
+		t.Fatalf("Unexpected states: %+v", states)
+	}
+	if states[0].Alive == nil || !*states[0].Alive || states[0].Workers == nil || *states[0].Workers != 2 || states[0].DatabaseExists != nil {
+		t.Errorf("Unexpected influx state: %+v", states[0])
+	}
+	if states[1].Alive != nil || states[1].Workers != nil {
+		t.Errorf("The file target has no state: %+v", states[1])
+	}
+
+	if r := request(mux, "POST", "/admin/targets/addworker?name=influx&type=influx", "secret"); r.Code != http.StatusOK || connector.workers != 3 {
+		t.Errorf("Expected a new worker, got %d: %s", r.Code, r.Body.String())
+	}
+	if r := request(mux, "POST", "/admin/targets/removeworker?name=file", "secret"); r.Code != http.StatusConflict {
+		t.Errorf("Expected a conflict, got %d", r.Code)
+	}
+	if r := request(mux, "POST", "/admin/targets/replay?name=influx", "secret"); r.Code != http.StatusConflict {
+		t.Errorf("Expected a conflict without write-ahead log, got %d", r.Code)
+	}
+	if r := request(mux, "POST", "/admin/targets/pause?name=missing", "secret"); r.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", r.Code)
+	}
+	if r := request(mux, "GET", "/admin/targets/pause?name=influx", "secret"); r.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", r.Code)
+	}
+
+	request(mux, "POST", "/admin/targets/pause?name=influx", "secret")
+	if _, manual := config.GetPause(influxTarget); !manual || !config.IsAnyTargetOnPause() {
+		t.Error("The target should be paused")
+	}
+	request(mux, "POST", "/admin/targets/resume?name=influx", "secret")
+	if _, manual := config.GetPause(influxTarget); manual {
+		t.Error("The target should be resumed")
+	}
+}
@@ -5,17 +5,32 @@ import "strings"
 //Filterable allows to sort the data
 type Filterable struct {
 	Filter string
+	//set if the data has no explicit target, the routing rules may choose others then
+	routable bool
 }
 
 //AllFilterable will be used by everybody
 var AllFilterable = Filterable{Filter: All}
 
+//RoutableFilterable will be used by everybody, unless a routing rule matches
+var RoutableFilterable = NewRoutableFilterable(All)
+
 //EmptyFilterable is the default value
 var EmptyFilterable = Filterable{Filter: ""}
 
 //All will be used by everybody
 const All = "all"
 
+//NewRoutableFilterable creates a filter for data without an explicit target, it's used if no routing rule matches.
+func NewRoutableFilterable(filter string) Filterable {
+	return Filterable{Filter: filter, routable: true}
+}
+
+//Routable returns true if the routing rules may choose the targets instead of the filter.
+func (f Filterable) Routable() bool {
+	return f.routable
+}
+
 //TestTargetFilter tests if the given filter matches with the containing filter
 func (f Filterable) TestTargetFilter(toTest string) bool {
 	//temporary change the value to lower
@@ -47,7 +47,7 @@ var testTargetFilterData = []struct {
 func TestFilterable_TestTargetFilter(t *testing.T) {
 	for i, data := range testTargetFilterData {
 		if data.perfFilter.TestTargetFilter(data.targetToTest) != data.expected {
-			t.Errorf("%d went wrong. Filterable: %s, ToTest: %s", i, data.perfFilter, data.targetToTest)
+			t.Errorf("%d went wrong. Filterable: %s, ToTest: %s", i, data.perfFilter.Filter, data.targetToTest)
 		}
 	}
 }
@@ -55,7 +55,20 @@ func TestFilterable_TestTargetFilter(t *testing.T) {
 func TestFilterable_TestTargetFilterObj(t *testing.T) {
 	for i, data := range testTargetFilterData {
 		if data.perfFilter.TestTargetFilterObj(Filterable{Filter: data.targetToTest}) != data.expected {
-			t.Errorf("%d went wrong. Filterable: %s, ToTest: %s", i, data.perfFilter, data.targetToTest)
+			t.Errorf("%d went wrong. Filterable: %s, ToTest: %s", i, data.perfFilter.Filter, data.targetToTest)
 		}
 	}
 }
+
+func TestFilterable_Routable(t *testing.T) {
+	if AllFilterable.Routable() || (Filterable{Filter: "foo"}).Routable() {
+		t.Error("An explicit target must not be routable")
+	}
+	routable := NewRoutableFilterable("foo")
+	if !routable.Routable() || !routable.TestTargetFilter("foo") || routable.TestTargetFilter("bar") {
+		t.Errorf("Unexpected filter: %v", routable)
+	}
+	if !RoutableFilterable.Routable() || !RoutableFilterable.TestTargetFilter("bar") {
+		t.Error("RoutableFilterable should be used by everybody")
+	}
+}
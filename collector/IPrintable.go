@@ -2,7 +2,9 @@ package collector
 
 //Printable this interface should be used to push data into the queue.
 type Printable interface {
-	PrintForInfluxDB(version string) string
-	PrintForElasticsearch(version, index string) string
+	//Points returns the data as backend neutral points, the targets encode them.
+	Points() []Point
 	TestTargetFilter(string) bool
+	//Routable returns true if the targets may be chosen by the routing rules.
+	Routable() bool
 }
@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"github.com/spitefulgrog/nagflux/data"
+	"sort"
+)
+
+//Kind tells the targets what a point is about, e.g. to choose a topic or to skip messages.
+type Kind string
+
+const (
+	//PerfdataKind are the performance data of the checks.
+	PerfdataKind Kind = "perfdata"
+	//MessageKind are notifications, comments, downtimes and state changes.
+	MessageKind Kind = "message"
+	//CustomKind is everything written to the NagfluxSpoolfileFolder, the tags are up to the user.
+	CustomKind Kind = "custom"
+)
+
+//Point is the backend neutral form of the collected data, the targets render it in their own format.
+type Point struct {
+	Kind        Kind              `json:"kind"`
+	Measurement string            `json:"measurement"`
+	Tags        map[string]string `json:"tags"`
+	Fields      data.Fields       `json:"fields"`
+	//Timestamp in milliseconds
+	Timestamp int64 `json:"timestamp"`
+}
+
+//TagKeys returns the names of the tags in sorted order.
+func (p Point) TagKeys() []string {
+	keys := make([]string, 0, len(p.Tags))
+	for key := range p.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//FieldKeys returns the names of the fields in sorted order.
+func (p Point) FieldKeys() []string {
+	keys := make([]string, 0, len(p.Fields))
+	for key := range p.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//Copy returns the point with own tags and fields, so it can be changed without touching the original.
+func (p Point) Copy() Point {
+	tags := make(map[string]string, len(p.Tags))
+	for key, value := range p.Tags {
+		tags[key] = value
+	}
+	fields := make(data.Fields, len(p.Fields))
+	for key, value := range p.Fields {
+		fields[key] = value
+	}
+	p.Tags, p.Fields = tags, fields
+	return p
+}
+
+//WithHostcheckAlias returns the point with the given alias as service, if it belongs to a hostcheck.
+//The collectors leave the service out for hostchecks, every target has its own alias.
+func (p Point) WithHostcheckAlias(alias string) Point {
+	if p.Kind == CustomKind || p.Tags["service"] != "" {
+		return p
+	}
+	tags := make(map[string]string, len(p.Tags)+1)
+	for key, value := range p.Tags {
+		tags[key] = value
+	}
+	tags["service"] = alias
+	p.Tags = tags
+	return p
+}
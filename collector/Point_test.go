@@ -0,0 +1,38 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithHostcheckAlias(t *testing.T) {
+	t.Parallel()
+	hostcheck := Point{Kind: PerfdataKind, Tags: map[string]string{"host": "h"}}
+	aliased := hostcheck.WithHostcheckAlias("hostcheck")
+	if aliased.Tags["service"] != "hostcheck" {
+		t.Errorf("Expected the alias as service: %v", aliased.Tags)
+	}
+	if _, ok := hostcheck.Tags["service"]; ok {
+		t.Error("The tags of the original point should not change")
+	}
+
+	service := Point{Kind: MessageKind, Tags: map[string]string{"host": "h", "service": "s"}}
+	if !reflect.DeepEqual(service.WithHostcheckAlias("hostcheck"), service) {
+		t.Error("Points with a service should not change")
+	}
+	custom := Point{Kind: CustomKind, Tags: map[string]string{"host": "h"}}
+	if !reflect.DeepEqual(custom.WithHostcheckAlias("hostcheck"), custom) {
+		t.Error("The tags of custom points are up to the user")
+	}
+}
+
+func TestPointKeys(t *testing.T) {
+	t.Parallel()
+	p := Point{Tags: map[string]string{"b": "", "a": "", "c": ""}}
+	if keys := p.TagKeys(); !reflect.DeepEqual(keys, []string{"a", "b", "c"}) {
+		t.Errorf("Expected sorted keys, got %v", keys)
+	}
+	if keys := p.FieldKeys(); len(keys) != 0 {
+		t.Errorf("Expected no keys, got %v", keys)
+	}
+}
@@ -1,5 +1,45 @@
 package collector
 
-import "github.com/spitefulgrog/nagflux/data"
+import (
+	"github.com/spitefulgrog/nagflux/data"
+	"sync"
+)
 
+//ResultQueues contains the queue of every target.
 type ResultQueues map[data.Target]chan Printable
+
+//Targets can be added or removed on a reload while the collectors are running.
+var queuesMutex = &sync.RWMutex{}
+
+//Snapshot returns a copy which can be iterated while targets are added or removed.
+func (r ResultQueues) Snapshot() ResultQueues {
+	queuesMutex.RLock()
+	defer queuesMutex.RUnlock()
+	result := make(ResultQueues, len(r))
+	for target, queue := range r {
+		result[target] = queue
+	}
+	return result
+}
+
+//Get returns the queue of the target.
+func (r ResultQueues) Get(target data.Target) (chan Printable, bool) {
+	queuesMutex.RLock()
+	defer queuesMutex.RUnlock()
+	queue, ok := r[target]
+	return queue, ok
+}
+
+//Set adds or replaces the queue of the target.
+func (r ResultQueues) Set(target data.Target, queue chan Printable) {
+	queuesMutex.Lock()
+	r[target] = queue
+	queuesMutex.Unlock()
+}
+
+//Delete removes the queue of the target.
+func (r ResultQueues) Delete(target data.Target) {
+	queuesMutex.Lock()
+	delete(r, target)
+	queuesMutex.Unlock()
+}
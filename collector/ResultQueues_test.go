@@ -0,0 +1,21 @@
+package collector
+
+import (
+	"github.com/spitefulgrog/nagflux/data"
+	"testing"
+)
+
+func TestResultQueues(t *testing.T) {
+	queues := ResultQueues{}
+	target := data.Target{Name: "foo", Datatype: data.InfluxDB}
+	queue := make(chan Printable)
+	queues.Set(target, queue)
+	snapshot := queues.Snapshot()
+	queues.Delete(target)
+	if _, ok := queues.Get(target); ok {
+		t.Error("The target should be deleted")
+	}
+	if snapshot[target] != queue {
+		t.Error("The snapshot should not change")
+	}
+}
@@ -2,25 +2,14 @@ package collector
 
 import "github.com/spitefulgrog/nagflux/data"
 
-//SimplePrintable can be used to send strings as printable
+//SimplePrintable can be used to send strings as printable, the text is already encoded for the target of the given type.
 type SimplePrintable struct {
 	Filterable
 	Text     string
 	Datatype data.Datatype
 }
 
-//PrintForInfluxDB generates an String for InfluxDB
-func (p SimplePrintable) PrintForInfluxDB(version string) string {
-	if p.Datatype == data.InfluxDB {
-		return p.Text
-	}
-	return ""
-}
-
-//PrintForElasticsearch generates an String for Elasticsearch
-func (p SimplePrintable) PrintForElasticsearch(version, index string) string {
-	if p.Datatype == data.Elasticsearch {
-		return p.Text
-	}
-	return ""
+//Points returns nothing, the targets send the text as it is if the type matches.
+func (p SimplePrintable) Points() []Point {
+	return nil
 }
@@ -0,0 +1,18 @@
+package livestatus
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/data"
+)
+
+//AcknowledgementData adds the start and end of acknowledgements to the livestatus data
+type AcknowledgementData struct {
+	collector.Filterable
+	Data
+	stateType string
+}
+
+//Points returns the acknowledgement as point of the measurement states with the type acknowledgement.
+func (acknowledgement AcknowledgementData) Points() []collector.Point {
+	return []collector.Point{acknowledgement.genStatePoint("acknowledgement", acknowledgement.stateType, data.Fields{})}
+}
@@ -0,0 +1,36 @@
+package livestatus
+
+import (
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/logging"
+	"reflect"
+	"testing"
+)
+
+func TestPointsAcknowledgement(t *testing.T) {
+	logging.InitTestLogger()
+	live := Collector{log: logging.GetLogger(), site: "berlin"}
+	acknowledgement := live.handleQueryForAcknowledgements(logRow{Type: "SERVICE ACKNOWLEDGE ALERT", Time: "1458988932", HostName: "host 1",
+		ServiceDescription: "service 1", StateType: "STARTED", ContactName: "philip", Comment: "on it; really"})
+	if acknowledgement == nil {
+		t.Fatal("Could not parse the acknowledgement")
+	}
+	points := acknowledgement.Points()
+	if len(points) != 1 || points[0].Measurement != "states" || points[0].Timestamp != 1458988932000 {
+		t.Fatalf("Unexpected points: %v", points)
+	}
+	tags := map[string]string{"site": "berlin", "host": "host 1", "service": "service 1", "type": "acknowledgement", "state_type": "STARTED", "author": "philip"}
+	if !reflect.DeepEqual(points[0].Tags, tags) {
+		t.Errorf("Unexpected tags: %v", points[0].Tags)
+	}
+	if fields := (data.Fields{"message": data.String("on it; really")}); !reflect.DeepEqual(points[0].Fields, fields) {
+		t.Errorf("Unexpected fields: %v", points[0].Fields)
+	}
+
+	if live.handleQueryForAcknowledgements(logRow{Type: "HOST ACKNOWLEDGE ALERT", Time: "1458988932"}) != nil {
+		t.Error("An entry without host should be skipped")
+	}
+	if live.handleQueryForAcknowledgements(logRow{Type: "HOST NOTIFICATION", Time: "1458988932", HostName: "host 1"}) != nil {
+		t.Error("Other types should be skipped")
+	}
+}
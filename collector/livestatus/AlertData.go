@@ -0,0 +1,29 @@
+package livestatus
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/data"
+	"strconv"
+)
+
+//AlertData adds the HARD and SOFT state changes of the log to the livestatus data
+type AlertData struct {
+	collector.Filterable
+	Data
+	state     string
+	stateType string
+	attempt   string
+}
+
+//Points returns the state change as point of the measurement states with the type state. The state and the attempt
+//are fields, the state_type tag is HARD or SOFT.
+func (alert AlertData) Points() []collector.Point {
+	fields := data.Fields{}
+	if value, err := strconv.ParseInt(alert.state, 10, 64); err == nil {
+		fields["state"] = data.Int(value)
+	}
+	if value, err := strconv.ParseInt(alert.attempt, 10, 64); err == nil {
+		fields["attempt"] = data.Int(value)
+	}
+	return []collector.Point{alert.genStatePoint("state", alert.stateType, fields)}
+}
@@ -0,0 +1,55 @@
+package livestatus
+
+import (
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/logging"
+	"reflect"
+	"testing"
+)
+
+func TestPointsAlert(t *testing.T) {
+	logging.InitTestLogger()
+	live := Collector{log: logging.GetLogger(), site: "berlin"}
+	for _, test := range []struct {
+		entry  logRow
+		tags   map[string]string
+		fields data.Fields
+	}{
+		{
+			logRow{Type: "SERVICE ALERT", Time: "1458988932", HostName: "host 1", ServiceDescription: "service 1", State: "2", StateType: "HARD", Attempt: "3", PluginOutput: "CRITICAL - load;5;4;3"},
+			map[string]string{"site": "berlin", "host": "host 1", "service": "service 1", "type": "state", "state_type": "HARD"},
+			data.Fields{"message": data.String("CRITICAL - load;5;4;3"), "state": data.Int(2), "attempt": data.Int(3)},
+		},
+		{
+			logRow{Type: "HOST ALERT", Time: "1458988932", HostName: "host 1", State: "1", StateType: "SOFT", Attempt: "1", PluginOutput: "DOWN"},
+			map[string]string{"site": "berlin", "host": "host 1", "type": "state", "state_type": "SOFT"},
+			data.Fields{"message": data.String("DOWN"), "state": data.Int(1), "attempt": data.Int(1)},
+		},
+	} {
+		alert := live.handleQueryForAlerts(test.entry)
+		if alert == nil {
+			t.Fatalf("Could not parse %v", test.entry)
+		}
+		points := alert.Points()
+		if len(points) != 1 {
+			t.Fatalf("Expected one point, got: %v", points)
+		}
+		p := points[0]
+		if p.Measurement != "states" || p.Timestamp != 1458988932000 {
+			t.Errorf("Unexpected point for %s: %v", test.entry.Type, p)
+		}
+		if !reflect.DeepEqual(p.Tags, test.tags) {
+			t.Errorf("Unexpected tags for %s: %v", test.entry.Type, p.Tags)
+		}
+		if !reflect.DeepEqual(p.Fields, test.fields) {
+			t.Errorf("Unexpected fields for %s: %v", test.entry.Type, p.Fields)
+		}
+	}
+
+	if live.handleQueryForAlerts(logRow{Type: "HOST ALERT", Time: "1458988932"}) != nil {
+		t.Error("An entry without host should be skipped")
+	}
+	if live.handleQueryForAlerts(logRow{Type: "HOST FLAPPING ALERT", Time: "1458988932", HostName: "host 1"}) != nil {
+		t.Error("Other types should be skipped")
+	}
+}
@@ -4,8 +4,6 @@ import (
 	"fmt"
 	"github.com/spitefulgrog/nagflux/logging"
 	"github.com/kdar/factorlog"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 )
@@ -16,37 +14,67 @@ type CacheBuilder struct {
 	quit                chan bool
 	log                 *factorlog.FactorLog
 	downtimeCache       Cache
+	metadataCache       MetadataCache
+	metadataTags        []string
 	mutex               *sync.Mutex
 }
 
 const (
 	//Updateinterval on livestatus data.
 	intervalToCheckLivestatusCache = time.Duration(30) * time.Second
+	//Updateinterval on the metadata, which changes just with the configuration of the core.
+	intervalToCheckLivestatusMetadata = time.Duration(5) * time.Minute
 	//QueryForServicesInDowntime livestatusquery for services in downtime.
 	QueryForServicesInDowntime = `GET services
 Columns: downtimes host_name display_name
 Filter: scheduled_downtime_depth > 0
-OutputFormat: csv
+OutputFormat: json
+ColumnHeaders: on
 
 `
 	//QueryForHostsInDowntime livestatusquery for hosts in downtime
 	QueryForHostsInDowntime = `GET hosts
 Columns: downtimes name
 Filter: scheduled_downtime_depth > 0
-OutputFormat: csv
+OutputFormat: json
+ColumnHeaders: on
 
 `
 	//QueryForDowntimeid livestatusquery for downtime start/end
 	QueryForDowntimeid = `GET downtimes
 Columns: id start_time entry_time
-OutputFormat: csv
+OutputFormat: json
+ColumnHeaders: on
+
+`
+	//QueryForHostMetadata livestatusquery for the groups, address and custom variables of every host
+	QueryForHostMetadata = `GET hosts
+Columns: name groups contact_groups address custom_variable_names custom_variable_values
+OutputFormat: json
+ColumnHeaders: on
+
+`
+	//QueryForServiceMetadata livestatusquery for the groups and custom variables of every service
+	QueryForServiceMetadata = `GET services
+Columns: host_name description groups contact_groups custom_variable_names custom_variable_values
+OutputFormat: json
+ColumnHeaders: on
 
 `
 )
 
 //NewLivestatusCacheBuilder constructor, which also starts it immediately.
-func NewLivestatusCacheBuilder(livestatusConnector *Connector) *CacheBuilder {
-	cache := &CacheBuilder{livestatusConnector, make(chan bool, 2), logging.GetLogger(), Cache{make(map[string]map[string]string)}, &sync.Mutex{}}
+//metadataTags are the names of the metadata which are returned by MetadataTags, see IsValidMetadataTag.
+func NewLivestatusCacheBuilder(livestatusConnector *Connector, metadataTags []string) *CacheBuilder {
+	cache := &CacheBuilder{livestatusConnector, make(chan bool, 2), logging.GetLogger(), Cache{make(map[string]map[string]string)},
+		NewMetadataCache(), nil, &sync.Mutex{}}
+	for _, name := range metadataTags {
+		if IsValidMetadataTag(name) {
+			cache.metadataTags = append(cache.metadataTags, name)
+		} else {
+			cache.log.Warn("Unknown MetadataTag, it is ignored: ", name)
+		}
+	}
 	go cache.run(intervalToCheckLivestatusCache)
 	return cache
 }
@@ -60,63 +88,115 @@ func (builder *CacheBuilder) Stop() {
 
 //Loop which caches livestatus downtimes and waits to quit.
 func (builder *CacheBuilder) run(checkInterval time.Duration) {
-	newCache := builder.createLivestatusCache()
-	builder.mutex.Lock()
-	builder.downtimeCache = newCache
-	builder.mutex.Unlock()
+	builder.update()
+	builder.updateMetadata()
+	lastMetadataUpdate := time.Now()
 	for {
 		select {
 		case <-builder.quit:
 			builder.quit <- true
 			return
 		case <-time.After(checkInterval):
-			newCache = builder.createLivestatusCache()
-			builder.mutex.Lock()
-			builder.downtimeCache = newCache
-			builder.mutex.Unlock()
+			builder.update()
+			if time.Since(lastMetadataUpdate) >= intervalToCheckLivestatusMetadata {
+				builder.updateMetadata()
+				lastMetadataUpdate = time.Now()
+			}
 		}
 	}
 }
 
+func (builder *CacheBuilder) update() {
+	newCache := builder.createLivestatusCache()
+	builder.mutex.Lock()
+	builder.downtimeCache = newCache
+	builder.mutex.Unlock()
+}
+
+func (builder *CacheBuilder) updateMetadata() {
+	newMetadata := builder.createMetadataCache()
+	builder.mutex.Lock()
+	builder.metadataCache = newMetadata
+	builder.mutex.Unlock()
+}
+
+//Builds the metadata of the hosts, which are needed for the hostgroups anyway, and of the services if they are used.
+func (builder CacheBuilder) createMetadataCache() MetadataCache {
+	result := NewMetadataCache()
+	hostRows := make(chan Row)
+	serviceRows := make(chan Row)
+	finished := make(chan bool)
+	go builder.livestatusConnector.connectToLivestatus(QueryForHostMetadata, hostRows, finished)
+	jobs := 1
+	if len(builder.metadataTags) > 0 {
+		go builder.livestatusConnector.connectToLivestatus(QueryForServiceMetadata, serviceRows, finished)
+		jobs++
+	}
+	for jobs > 0 {
+		select {
+		case row := <-hostRows:
+			var host metadataRow
+			if err := row.Decode(&host); err != nil || !result.addHost(host) {
+				builder.log.Debug("QueryForHostMetadata unexpected row ", row, err)
+			}
+		case row := <-serviceRows:
+			var service metadataRow
+			if err := row.Decode(&service); err != nil || !result.addService(service) {
+				builder.log.Debug("QueryForServiceMetadata unexpected row ", row, err)
+			}
+		case <-finished:
+			jobs--
+		case <-time.After(intervalToCheckLivestatusCache / 3):
+			builder.log.Info("Livestatus timed out...(metadata)")
+			return result
+		}
+	}
+	return result
+}
+
 //Builds host/service map which are in downtime
 func (builder CacheBuilder) createLivestatusCache() Cache {
 	result := Cache{downtime: make(map[string]map[string]string)}
-	downtimeCsv := make(chan []string)
+	downtimeRows := make(chan Row)
 	finishedDowntime := make(chan bool)
-	hostServiceCsv := make(chan []string)
+	hostServiceRows := make(chan Row)
 	finished := make(chan bool)
-	go builder.livestatusConnector.connectToLivestatus(QueryForDowntimeid, downtimeCsv, finishedDowntime)
-	go builder.livestatusConnector.connectToLivestatus(QueryForHostsInDowntime, hostServiceCsv, finished)
-	go builder.livestatusConnector.connectToLivestatus(QueryForServicesInDowntime, hostServiceCsv, finished)
+	go builder.livestatusConnector.connectToLivestatus(QueryForDowntimeid, downtimeRows, finishedDowntime)
+	go builder.livestatusConnector.connectToLivestatus(QueryForHostsInDowntime, hostServiceRows, finished)
+	go builder.livestatusConnector.connectToLivestatus(QueryForServicesInDowntime, hostServiceRows, finished)
 
 	jobsFinished := 0
 	//contains id to starttime
 	downtimes := map[string]string{}
 	for jobsFinished < 2 {
 		select {
-		case downtimesLine := <-downtimeCsv:
-			if len(downtimesLine) < 3 {
-				builder.log.Debug("downtimesLine", downtimesLine)
+		case row := <-downtimeRows:
+			var downtime downtimeRow
+			if err := row.Decode(&downtime); err != nil {
+				builder.log.Debug("downtimesLine", row, err)
 				break
 			}
-			startTime, _ := strconv.Atoi(downtimesLine[1])
-			entryTime, _ := strconv.Atoi(downtimesLine[2])
+			startTime, _ := downtime.StartTime.Int64()
+			entryTime, _ := downtime.EntryTime.Int64()
 			latestTime := startTime
 			if startTime < entryTime {
 				latestTime = entryTime
 			}
-			for _, id := range strings.Split(downtimesLine[0], ",") {
-				downtimes[id] = fmt.Sprint(latestTime)
-			}
+			downtimes[downtime.ID.String()] = fmt.Sprint(latestTime)
 		case <-finishedDowntime:
 			for jobsFinished < 2 {
 				select {
-				case hostService := <-hostServiceCsv:
-					for _, id := range strings.Split(hostService[0], ",") {
-						if len(hostService) == 2 {
-							result.addDowntime(hostService[1], "", downtimes[id])
-						} else if len(hostService) == 3 {
-							result.addDowntime(hostService[1], hostService[2], downtimes[id])
+				case row := <-hostServiceRows:
+					var hostService downtimeRow
+					if err := row.Decode(&hostService); err != nil {
+						builder.log.Debug("hostServiceLine", row, err)
+						break
+					}
+					for _, id := range hostService.Downtimes {
+						if hostService.HostName == "" {
+							result.addDowntime(hostService.Name, "", downtimes[id.String()])
+						} else {
+							result.addDowntime(hostService.HostName, hostService.DisplayName, downtimes[id.String()])
 						}
 					}
 				case <-finished:
@@ -149,3 +229,28 @@ func (builder CacheBuilder) IsServiceInDowntime(host, service, time string) bool
 	builder.mutex.Unlock()
 	return result
 }
+
+//Hostgroups returns the hostgroups of the host, nil if the host is unknown.
+func (builder *CacheBuilder) Hostgroups(host string) []string {
+	builder.mutex.Lock()
+	defer builder.mutex.Unlock()
+	return builder.metadataCache.hosts[host].Groups
+}
+
+//Knows returns true if the host is known by this livestatus.
+func (builder *CacheBuilder) Knows(host string) bool {
+	builder.mutex.Lock()
+	defer builder.mutex.Unlock()
+	_, known := builder.metadataCache.hosts[host]
+	return known
+}
+
+//MetadataTags returns the configured metadata of the host or service as tags, nil if they are unknown.
+func (builder *CacheBuilder) MetadataTags(host, service string) map[string]string {
+	if len(builder.metadataTags) == 0 {
+		return nil
+	}
+	builder.mutex.Lock()
+	defer builder.mutex.Unlock()
+	return builder.metadataCache.tags(host, service, builder.metadataTags)
+}
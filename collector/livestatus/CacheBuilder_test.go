@@ -9,8 +9,8 @@ import (
 
 func TestNewCacheBuilder(t *testing.T) {
 	logging.InitTestLogger()
-	connector := &Connector{logging.GetLogger(), "localhost:6558", "tcp"}
-	builder := NewLivestatusCacheBuilder(connector)
+	connector := &Connector{Log: logging.GetLogger(), LivestatusAddress: "localhost:6558", ConnectionType: "tcp"}
+	builder := NewLivestatusCacheBuilder(connector, nil)
 	if builder == nil {
 		t.Error("Constructor returned null pointer")
 	}
@@ -19,14 +19,14 @@ func TestNewCacheBuilder(t *testing.T) {
 func DisabledTestServiceInDowntime(t *testing.T) {
 	logging.InitTestLogger()
 	queries := map[string]string{}
-	queries[QueryForServicesInDowntime] = "1,2;host1;service1\n"
-	queries[QueryForHostsInDowntime] = "3,4;host1\n5;host2\n"
-	queries[QueryForDowntimeid] = "1;0;1\n2;2;3\n3;0;1\n4;1;2\n5;2;1\n"
-	livestatus := &MockLivestatus{"localhost:6558", "tcp", queries, true}
+	queries[QueryForServicesInDowntime] = `[["downtimes","host_name","display_name"],[[1,2],"host1","service1"]]`
+	queries[QueryForHostsInDowntime] = `[["downtimes","name"],[[3,4],"host1"],[[5],"host2"]]`
+	queries[QueryForDowntimeid] = `[["id","start_time","entry_time"],[1,0,1],[2,2,3],[3,0,1],[4,1,2],[5,2,1]]`
+	livestatus := &MockLivestatus{LivestatusAddress: "localhost:6558", ConnectionType: "tcp", Queries: queries, isRunning: true}
 	go livestatus.StartMockLivestatus()
-	connector := &Connector{logging.GetLogger(), livestatus.LivestatusAddress, livestatus.ConnectionType}
+	connector := &Connector{Log: logging.GetLogger(), LivestatusAddress: livestatus.LivestatusAddress, ConnectionType: livestatus.ConnectionType}
 
-	cacheBuilder := NewLivestatusCacheBuilder(connector)
+	cacheBuilder := NewLivestatusCacheBuilder(connector, nil)
 	time.Sleep(time.Duration(2) * time.Second)
 
 	cacheBuilder.Stop()
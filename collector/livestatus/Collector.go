@@ -3,10 +3,9 @@ package livestatus
 import (
 	"fmt"
 	"github.com/spitefulgrog/nagflux/collector"
-	"github.com/spitefulgrog/nagflux/config"
 	"github.com/spitefulgrog/nagflux/data"
-	"github.com/spitefulgrog/nagflux/helper"
 	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/wal"
 	"github.com/kdar/factorlog"
 	"regexp"
 	"strings"
@@ -20,6 +19,11 @@ type Collector struct {
 	livestatusConnector *Connector
 	log                 *factorlog.FactorLog
 	logQuery            string
+	alertQuery          string
+	flappingQuery       string
+	acknowledgeQuery    string
+	site                string
+	minutesToWait       int
 }
 
 const (
@@ -27,38 +31,112 @@ const (
 	intervalToCheckLivestatus = time.Duration(2) * time.Minute
 	QueryLivestatusVersion    = `GET status
 Columns: livestatus_version
-OutputFormat: csv
+OutputFormat: json
+ColumnHeaders: on
 
 `
 	//QueryIcinga2ForNotifications livestatusquery for notifications with Icinga2 Livestatus.
 	QueryIcinga2ForNotifications = `GET log
-Columns: type time contact_name message
+Columns: type time host_name service_description state_type contact_name command_name plugin_output comment options
 Filter: type ~ .*NOTIFICATION
 Filter: time < %d
 Negate:
-OutputFormat: csv
+OutputFormat: json
+ColumnHeaders: on
 
 `
 	//QueryNagiosForNotifications livestatusquery for notifications with nagioslike Livestatus.
 	QueryNagiosForNotifications = `GET log
-Columns: type time contact_name message
+Columns: type time host_name service_description state_type contact_name command_name plugin_output comment options
 Filter: type ~ .*NOTIFICATION
 Filter: time > %d
-OutputFormat: csv
+OutputFormat: json
+ColumnHeaders: on
+
+`
+	//QueryIcinga2ForAlerts livestatusquery for HARD and SOFT state changes with Icinga2 Livestatus.
+	QueryIcinga2ForAlerts = `GET log
+Columns: type time host_name service_description state state_type attempt plugin_output
+Filter: type = HOST ALERT
+Filter: type = SERVICE ALERT
+Or: 2
+Filter: time < %d
+Negate:
+OutputFormat: json
+ColumnHeaders: on
+
+`
+	//QueryNagiosForAlerts livestatusquery for HARD and SOFT state changes with nagioslike Livestatus.
+	QueryNagiosForAlerts = `GET log
+Columns: type time host_name service_description state state_type attempt plugin_output
+Filter: type = HOST ALERT
+Filter: type = SERVICE ALERT
+Or: 2
+Filter: time > %d
+OutputFormat: json
+ColumnHeaders: on
+
+`
+	//QueryIcinga2ForFlapping livestatusquery for the start and stop of flapping with Icinga2 Livestatus.
+	QueryIcinga2ForFlapping = `GET log
+Columns: type time host_name service_description state_type comment
+Filter: type = HOST FLAPPING ALERT
+Filter: type = SERVICE FLAPPING ALERT
+Or: 2
+Filter: time < %d
+Negate:
+OutputFormat: json
+ColumnHeaders: on
+
+`
+	//QueryNagiosForFlapping livestatusquery for the start and stop of flapping with nagioslike Livestatus.
+	QueryNagiosForFlapping = `GET log
+Columns: type time host_name service_description state_type comment
+Filter: type = HOST FLAPPING ALERT
+Filter: type = SERVICE FLAPPING ALERT
+Or: 2
+Filter: time > %d
+OutputFormat: json
+ColumnHeaders: on
+
+`
+	//QueryIcinga2ForAcknowledgements livestatusquery for the start and end of acknowledgements with Icinga2 Livestatus.
+	QueryIcinga2ForAcknowledgements = `GET log
+Columns: type time host_name service_description state_type contact_name comment
+Filter: type = HOST ACKNOWLEDGE ALERT
+Filter: type = SERVICE ACKNOWLEDGE ALERT
+Or: 2
+Filter: time < %d
+Negate:
+OutputFormat: json
+ColumnHeaders: on
+
+`
+	//QueryNagiosForAcknowledgements livestatusquery for the start and end of acknowledgements with nagioslike Livestatus.
+	QueryNagiosForAcknowledgements = `GET log
+Columns: type time host_name service_description state_type contact_name comment
+Filter: type = HOST ACKNOWLEDGE ALERT
+Filter: type = SERVICE ACKNOWLEDGE ALERT
+Or: 2
+Filter: time > %d
+OutputFormat: json
+ColumnHeaders: on
 
 `
 	//QueryForComments livestatusquery for comments
 	QueryForComments = `GET comments
 Columns: host_name service_display_name comment entry_time author entry_type
 Filter: entry_time > %d
-OutputFormat: csv
+OutputFormat: json
+ColumnHeaders: on
 
 `
 	//QueryForDowntimes livestatusquery for downtimes
 	QueryForDowntimes = `GET downtimes
 Columns: host_name service_display_name comment entry_time author end_time
 Filter: entry_time > %d
-OutputFormat: csv
+OutputFormat: json
+ColumnHeaders: on
 
 `
 	//Nagios nagioslike Livestatus
@@ -70,13 +148,19 @@ OutputFormat: csv
 )
 
 //NewLivestatusCollector constructor, which also starts it immediately.
-func NewLivestatusCollector(jobs collector.ResultQueues, livestatusConnector *Connector, detectVersion string) *Collector {
+//The messages get the site as tag, if it has a name. minutesToWait is the time to wait for livestatus to detect the version.
+func NewLivestatusCollector(jobs collector.ResultQueues, livestatusConnector *Connector, site, detectVersion string, minutesToWait int) *Collector {
 	live := &Collector{
 		quit:                make(chan bool, 2),
 		jobs:                jobs,
 		livestatusConnector: livestatusConnector,
 		log:                 logging.GetLogger(),
 		logQuery:            QueryNagiosForNotifications,
+		alertQuery:          QueryNagiosForAlerts,
+		flappingQuery:       QueryNagiosForFlapping,
+		acknowledgeQuery:    QueryNagiosForAcknowledgements,
+		site:                site,
+		minutesToWait:       minutesToWait,
 	}
 	if detectVersion == "" {
 		switch getLivestatusVersion(live) {
@@ -85,6 +169,9 @@ func NewLivestatusCollector(jobs collector.ResultQueues, livestatusConnector *Co
 		case Icinga2:
 			live.log.Info("Livestatus type: Icinga2")
 			live.logQuery = QueryIcinga2ForNotifications
+			live.alertQuery = QueryIcinga2ForAlerts
+			live.flappingQuery = QueryIcinga2ForFlapping
+			live.acknowledgeQuery = QueryIcinga2ForAcknowledgements
 		case Naemon:
 			live.log.Info("Livestatus type: Naemon")
 		}
@@ -95,6 +182,9 @@ func NewLivestatusCollector(jobs collector.ResultQueues, livestatusConnector *Co
 		case "Icinga2":
 			live.log.Info("Setting Livestatus version to: Icinga2")
 			live.logQuery = QueryIcinga2ForNotifications
+			live.alertQuery = QueryIcinga2ForAlerts
+			live.flappingQuery = QueryIcinga2ForFlapping
+			live.acknowledgeQuery = QueryIcinga2ForAcknowledgements
 		case "Naemon":
 			live.log.Info("Setting Livestatus version to: Naemon")
 		default:
@@ -131,14 +221,17 @@ func (live Collector) queryData() {
 	printables := make(chan collector.Printable)
 	finished := make(chan bool)
 	go live.requestPrintablesFromLivestatus(live.logQuery, true, printables, finished)
+	go live.requestPrintablesFromLivestatus(live.alertQuery, true, printables, finished)
+	go live.requestPrintablesFromLivestatus(live.flappingQuery, true, printables, finished)
+	go live.requestPrintablesFromLivestatus(live.acknowledgeQuery, true, printables, finished)
 	go live.requestPrintablesFromLivestatus(QueryForComments, true, printables, finished)
 	go live.requestPrintablesFromLivestatus(QueryForDowntimes, true, printables, finished)
 	jobsFinished := 0
-	for jobsFinished < 3 {
+	for jobsFinished < 6 {
 		select {
 		case job := <-printables:
-			for _, j := range live.jobs {
-				j <- job
+			for target, j := range live.jobs.Snapshot() {
+				wal.Deliver(target, j, job, nil, intervalToCheckLivestatus)
 			}
 		case <-finished:
 			jobsFinished++
@@ -154,39 +247,56 @@ func (live Collector) requestPrintablesFromLivestatus(query string, addTimestamp
 		queryWithTimestamp = addTimestampToLivestatusQuery(query)
 	}
 
-	csv := make(chan []string)
+	rows := make(chan Row)
 	finished := make(chan bool)
-	go live.livestatusConnector.connectToLivestatus(queryWithTimestamp, csv, finished)
+	go live.livestatusConnector.connectToLivestatus(queryWithTimestamp, rows, finished)
 
 	for {
 		select {
-		case line := <-csv:
+		case row := <-rows:
 			switch query {
-			case QueryNagiosForNotifications:
-				if printable := live.handleQueryForNotifications(line); printable != nil {
-					printables <- printable
+			case QueryNagiosForNotifications, QueryIcinga2ForNotifications:
+				var entry logRow
+				if live.decode(row, &entry, "QueryForNotifications") {
+					if printable := live.handleQueryForNotifications(entry); printable != nil {
+						printables <- printable
+					}
+				}
+			case QueryNagiosForAlerts, QueryIcinga2ForAlerts:
+				var entry logRow
+				if live.decode(row, &entry, "QueryForAlerts") {
+					if printable := live.handleQueryForAlerts(entry); printable != nil {
+						printables <- printable
+					}
 				}
-			case QueryIcinga2ForNotifications:
-				if printable := live.handleQueryForNotifications(line); printable != nil {
-					printables <- printable
+			case QueryNagiosForFlapping, QueryIcinga2ForFlapping:
+				var entry logRow
+				if live.decode(row, &entry, "QueryForFlapping") {
+					if printable := live.handleQueryForFlapping(entry); printable != nil {
+						printables <- printable
+					}
+				}
+			case QueryNagiosForAcknowledgements, QueryIcinga2ForAcknowledgements:
+				var entry logRow
+				if live.decode(row, &entry, "QueryForAcknowledgements") {
+					if printable := live.handleQueryForAcknowledgements(entry); printable != nil {
+						printables <- printable
+					}
 				}
 			case QueryForComments:
-				if len(line) == 6 {
-					printables <- CommentData{collector.AllFilterable, Data{line[0], line[1], line[2], line[3], line[4]}, line[5]}
-				} else {
-					live.log.Warn("QueryForComments out of range", line)
+				var comment commentRow
+				if live.decode(row, &comment, "QueryForComments") {
+					printables <- CommentData{collector.RoutableFilterable, live.commentData(comment), comment.EntryType.String()}
 				}
 			case QueryForDowntimes:
-				if len(line) == 6 {
-					printables <- DowntimeData{collector.AllFilterable, Data{line[0], line[1], line[2], line[3], line[4]}, line[5]}
-				} else {
-					live.log.Warn("QueryForDowntimes out of range", line)
+				var downtime commentRow
+				if live.decode(row, &downtime, "QueryForDowntimes") {
+					printables <- DowntimeData{collector.RoutableFilterable, live.commentData(downtime), downtime.EndTime.String()}
 				}
 			case QueryLivestatusVersion:
-				if len(line) == 1 {
-					printables <- collector.SimplePrintable{Filterable: collector.AllFilterable, Text: line[0], Datatype: data.InfluxDB}
-				} else {
-					live.log.Warn("QueryLivestatusVersion out of range", line)
+				var status statusRow
+				if live.decode(row, &status, "QueryLivestatusVersion") {
+					printables <- collector.SimplePrintable{Filterable: collector.AllFilterable, Text: status.Version, Datatype: data.InfluxDB}
 				}
 			default:
 				live.log.Fatal("Found unknown query type" + query)
@@ -200,42 +310,129 @@ func (live Collector) requestPrintablesFromLivestatus(query string, addTimestamp
 	}
 }
 
+//Maps the row into target, the row is logged and skipped if the columns don't fit.
+func (live Collector) decode(row Row, target interface{}, query string) bool {
+	if err := row.Decode(target); err != nil {
+		live.log.Warn(query, " has an unexpected row: ", err, " Row: ", row)
+		return false
+	}
+	return true
+}
+
 func addTimestampToLivestatusQuery(query string) string {
 	return fmt.Sprintf(query, time.Now().Add(intervalToCheckLivestatus/100*-150).Unix())
 }
 
-func (live Collector) handleQueryForNotifications(line []string) *NotificationData {
-	switch line[0] {
-	case "HOST NOTIFICATION":
-		if len(line) == 10 {
-			//Custom: host_name, "", message, timestamp, author, notification_type, state
-			return &NotificationData{collector.AllFilterable, Data{line[4], "", line[9], line[1], line[8]}, line[0], line[5]}
-		} else if len(line) == 9 {
-			return &NotificationData{collector.AllFilterable, Data{line[4], "", line[7], line[1], line[2]}, line[0], line[5]}
-		} else if len(line) == 8 {
-			return &NotificationData{collector.AllFilterable, Data{line[4], "", line[7], line[1], line[2]}, line[0], line[5]}
-		} else {
-			live.log.Warn("HOST NOTIFICATION, undefinded linelenght: ", len(line), " Line:", helper.SPrintStringSlice(line))
+func (live Collector) commentData(comment commentRow) Data {
+	return Data{comment.HostName, comment.ServiceDisplayName, comment.Comment, comment.EntryTime.String(), comment.Author, live.site}
+}
+
+//The notification level is the state_type, like CRITICAL or ACKNOWLEDGEMENT (CRITICAL). The contact is the author,
+//unless the notification was sent by someone with a comment.
+func (live Collector) handleQueryForNotifications(entry logRow) *NotificationData {
+	switch entry.Type {
+	case "HOST NOTIFICATION", "SERVICE NOTIFICATION":
+		data := Data{entry.HostName, entry.ServiceDescription, entry.PluginOutput, entry.Time.String(), entry.ContactName, live.site}
+		if author, comment, ok := notificationComment(entry); ok {
+			data.author, data.comment = author, comment
 		}
-	case "SERVICE NOTIFICATION":
-		if len(line) == 11 {
-			//Custom
-			return &NotificationData{collector.AllFilterable, Data{line[4], line[5], line[10], line[1], line[9]}, line[0], line[6]}
-		} else if len(line) == 10 || len(line) == 9 {
-			return &NotificationData{collector.AllFilterable, Data{line[4], line[5], line[8], line[1], line[2]}, line[0], line[6]}
+		return &NotificationData{collector.RoutableFilterable, data, entry.Type, entry.StateType}
+	default:
+		if strings.Contains(entry.Type, "NOTIFICATION SUPPRESSED") {
+			live.log.Debugf("Ignoring suppressed Notification: '%s', Host: %s", entry.Type, entry.HostName)
 		} else {
-			live.log.Warn("SERVICE NOTIFICATION, undefinded linelenght: ", len(line), " Line:", helper.SPrintStringSlice(line))
+			live.log.Warnf("The notification type is unknown: '%s', Host: '%s'", entry.Type, entry.HostName)
+		}
+	}
+	return nil
+}
+
+//Custom notifications and acknowledgements append the author and the comment to the options, which start with
+//contact;host;service;state_type;command for services, hosts have no service. Cores which parse the comment into its
+//column like Checkmk give the end of the options, for the others the comment is the last field and has to be free of ;.
+//The output in between may contain any ;.
+func notificationComment(entry logRow) (string, string, bool) {
+	if !strings.HasPrefix(entry.StateType, "ACKNOWLEDGEMENT") && !strings.HasPrefix(entry.StateType, "CUSTOM") {
+		return "", "", false
+	}
+	columns := []string{entry.ContactName, entry.HostName}
+	if entry.ServiceDescription != "" {
+		columns = append(columns, entry.ServiceDescription)
+	}
+	prefix := strings.Join(append(columns, entry.StateType, entry.CommandName, ""), ";")
+	if !strings.HasPrefix(entry.Options, prefix) {
+		return "", "", false
+	}
+	rest := strings.TrimPrefix(entry.Options, prefix)
+	comment := entry.Comment
+	if comment != "" {
+		if !strings.HasSuffix(rest, ";"+comment) {
+			return "", "", false
+		}
+		rest = strings.TrimSuffix(rest, ";"+comment)
+	} else {
+		end := strings.LastIndex(rest, ";")
+		if end < 0 {
+			return "", "", false
+		}
+		rest, comment = rest[:end], rest[end+1:]
+	}
+	//The output is in front of the author
+	end := strings.LastIndex(rest, ";")
+	if end < 0 {
+		return "", "", false
+	}
+	return rest[end+1:], comment, true
+}
+
+//State changes have the output as message.
+func (live Collector) handleQueryForAlerts(entry logRow) *AlertData {
+	switch entry.Type {
+	case "HOST ALERT", "SERVICE ALERT":
+		if data, ok := live.logData(entry, entry.PluginOutput, ""); ok {
+			return &AlertData{collector.RoutableFilterable, data, entry.State.String(), entry.StateType, entry.Attempt.String()}
 		}
 	default:
-		if strings.Contains(line[0], "NOTIFICATION SUPPRESSED") {
-			live.log.Debugf("Ignoring suppressed Notification: '%s', Line: %s", line[0], helper.SPrintStringSlice(line))
-		} else {
-			live.log.Warnf("The notification type is unknown: '%s', whole line: '%s'", line[0], helper.SPrintStringSlice(line))
+		live.log.Warnf("The alert type is unknown: '%s', Host: '%s'", entry.Type, entry.HostName)
+	}
+	return nil
+}
+
+//Flapping has the comment of the core as message, the state_type is STARTED, STOPPED or DISABLED.
+func (live Collector) handleQueryForFlapping(entry logRow) *FlappingData {
+	switch entry.Type {
+	case "HOST FLAPPING ALERT", "SERVICE FLAPPING ALERT":
+		if data, ok := live.logData(entry, entry.Comment, ""); ok {
+			return &FlappingData{collector.RoutableFilterable, data, entry.StateType}
 		}
+	default:
+		live.log.Warnf("The flapping type is unknown: '%s', Host: '%s'", entry.Type, entry.HostName)
 	}
 	return nil
 }
 
+//Acknowledgements have the comment as message and the contact as author, the state_type is STARTED or EXPIRED.
+func (live Collector) handleQueryForAcknowledgements(entry logRow) *AcknowledgementData {
+	switch entry.Type {
+	case "HOST ACKNOWLEDGE ALERT", "SERVICE ACKNOWLEDGE ALERT":
+		if data, ok := live.logData(entry, entry.Comment, entry.ContactName); ok {
+			return &AcknowledgementData{collector.RoutableFilterable, data, entry.StateType}
+		}
+	default:
+		live.log.Warnf("The acknowledgement type is unknown: '%s', Host: '%s'", entry.Type, entry.HostName)
+	}
+	return nil
+}
+
+//Entries of the log without host are skipped.
+func (live Collector) logData(entry logRow, message, author string) (Data, bool) {
+	if entry.HostName == "" {
+		live.log.Warn("Log entry without host: ", entry.Type)
+		return Data{}, false
+	}
+	return Data{entry.HostName, entry.ServiceDescription, message, entry.Time.String(), author, live.site}, true
+}
+
 func getLivestatusVersion(live *Collector) int {
 	printables := make(chan collector.Printable, 1)
 	finished := make(chan bool, 1)
@@ -243,12 +440,14 @@ func getLivestatusVersion(live *Collector) int {
 	live.requestPrintablesFromLivestatus(QueryLivestatusVersion, false, printables, finished)
 	i := 0
 	oneMinute := time.Duration(1) * time.Minute
-	roundsToWait := config.GetConfig().Livestatus.MinutesToWait
+	roundsToWait := live.minutesToWait
 Loop:
 	for roundsToWait != 0 {
 		select {
 		case versionPrintable := <-printables:
-			version = versionPrintable.PrintForInfluxDB("0")
+			if p, ok := versionPrintable.(collector.SimplePrintable); ok {
+				version = p.Text
+			}
 			break Loop
 		case <-time.After(oneMinute):
 			if i < roundsToWait {
@@ -21,7 +21,7 @@ func TestNewLivestatusCollector(t *testing.T) {
 		LivestatusAddress: "localhost:6559",
 		ConnectionType:    "tcp",
 	}
-	collector := NewLivestatusCollector(make(collector.ResultQueues), connector, "")
+	collector := NewLivestatusCollector(make(collector.ResultQueues), connector, "", "", 0)
 	if collector == nil {
 		t.Error("Constructor returned null pointer")
 	}
@@ -2,12 +2,26 @@ package livestatus
 
 import (
 	"bufio"
-	"encoding/csv"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"github.com/kdar/factorlog"
 	"io"
+	"io/ioutil"
 	"net"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	//DefaultTimeout is used for connecting and for every query, if the Connector has no Timeout.
+	DefaultTimeout = time.Duration(30) * time.Second
+	//Size of the response header with ResponseHeader: fixed16
+	responseHeaderSize = 16
+	//Idle connections which are kept with KeepAlive
+	maxIdleConnections = 4
 )
 
 //Connector fetches data from livestatus.
@@ -15,53 +29,205 @@ type Connector struct {
 	Log               *factorlog.FactorLog
 	LivestatusAddress string
 	ConnectionType    string
+
+	//TLSConfig is used for the ConnectionType tls, nil verifies the server with the system CAs.
+	TLSConfig *tls.Config
+	//AuthUser is sent with every query, livestatus returns just the objects of this contact then.
+	AuthUser string
+	//KeepAlive keeps the connections open to reuse them for the next queries.
+	KeepAlive bool
+	Timeout   time.Duration
+
+	mutex sync.Mutex
+	idle  []net.Conn
 }
 
-//Queries livestatus and returns an list of list outer list are lines inner elements within the line.
-func (connector Connector) connectToLivestatus(query string, result chan []string, outerFinish chan bool) {
-	var conn net.Conn
-	switch connector.ConnectionType {
-	case "tcp":
-		conn, _ = net.Dial("tcp", connector.LivestatusAddress)
-	case "file":
-		conn, _ = net.Dial("unix", connector.LivestatusAddress)
-	default:
-		connector.Log.Critical("Connection type is unknown, options are: tcp, file. Input:" + connector.ConnectionType)
+//ResponseError is returned if livestatus answers with another status than 200.
+type ResponseError struct {
+	Status  int
+	Message string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("livestatus returned %d: %s", e.Status, e.Message)
+}
+
+//NewTLSConfig returns the TLS config for the ConnectionType tls. ca is a PEM file with the certificates to verify the
+//server, empty uses the ones of the system. certificate and key are the PEM files of the client certificate, if needed.
+func NewTLSConfig(ca, certificate, key string, skipVerify bool) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: skipVerify}
+	if ca != "" {
+		pem, err := ioutil.ReadFile(ca)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s contains no certificate", ca)
+		}
+	}
+	if certificate != "" || key != "" {
+		clientCertificate, err := tls.LoadX509KeyPair(certificate, key)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{clientCertificate}
+	}
+	return config, nil
+}
+
+//Queries livestatus and sends the rows of the answer to result, the query has to ask for OutputFormat: json with
+//ColumnHeaders: on.
+func (connector *Connector) connectToLivestatus(query string, result chan Row, outerFinish chan bool) {
+	body, err := connector.query(query)
+	if err != nil {
+		connector.Log.Warnf("Livestatus %s query failed: %s Query: %q", connector.LivestatusAddress, err, firstLine(query))
 		outerFinish <- false
 		return
 	}
-	if conn == nil {
+	rows, err := readRows(body)
+	if err != nil {
+		connector.Log.Warnf("Livestatus %s answer is no valid json: %s Query: %q", connector.LivestatusAddress, err, firstLine(query))
 		outerFinish <- false
 		return
 	}
+	for _, row := range rows {
+		result <- row
+	}
+	outerFinish <- true
+}
 
-	defer conn.Close()
-	fmt.Fprintf(conn, query)
-	reader := bufio.NewReader(conn)
+//Sends the query with the headers and returns the body of the response. A reused connection may have been closed by
+//livestatus in the meantime, so the query is sent once more on a new one if it fails.
+func (connector *Connector) query(query string) ([]byte, error) {
+	request := strings.TrimRight(query, "\n") + "\nResponseHeader: fixed16\n"
+	if connector.AuthUser != "" {
+		request += "AuthUser: " + connector.AuthUser + "\n"
+	}
+	if connector.KeepAlive {
+		request += "KeepAlive: on\n"
+	}
+	request += "\n"
 
-	length := 1
-	for length > 0 {
-		message, _, err := reader.ReadLine()
-		if err != nil {
-			if err == io.EOF {
-				break
-			} else {
-				connector.Log.Warn(err)
-			}
+	if conn := connector.idleConnection(); conn != nil {
+		body, err := connector.exchange(conn, request)
+		if _, ok := err.(*ResponseError); ok || err == nil {
+			return body, err
 		}
-		length = len(message)
-		if length > 0 {
-			csvReader := csv.NewReader(strings.NewReader(string(message)))
-			csvReader.Comma = ';'
-			csvReader.LazyQuotes = true
-			records, err := csvReader.Read()
-			if err != nil {
-				connector.Log.Warn("Query failed while csv parsing:" + query)
-				connector.Log.Warn(string(message))
-				connector.Log.Warn(err)
-			}
-			result <- records
+		connector.Log.Debug("Reused livestatus connection failed, trying a new one: ", err)
+	}
+	conn, err := connector.dial()
+	if err != nil {
+		return nil, err
+	}
+	return connector.exchange(conn, request)
+}
+
+//Writes the request and reads the response, the connection is kept for the next query if it's still usable.
+func (connector *Connector) exchange(conn net.Conn, request string) ([]byte, error) {
+	conn.SetDeadline(time.Now().Add(connector.timeout()))
+	if _, err := io.WriteString(conn, request); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	status, length, err := readResponseHeader(reader)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	//without KeepAlive livestatus closes the connection, leftovers mean the stream is out of step
+	if connector.KeepAlive && reader.Buffered() == 0 {
+		connector.keep(conn)
+	} else {
+		conn.Close()
+	}
+	if status != 200 {
+		return nil, &ResponseError{Status: status, Message: strings.TrimSpace(string(body))}
+	}
+	return body, nil
+}
+
+//Parses the header of fixed16: the status code, a space, the length of the body padded to 11 chars and a newline.
+func readResponseHeader(reader io.Reader) (int, int, error) {
+	header := make([]byte, responseHeaderSize)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return 0, 0, fmt.Errorf("reading the response header: %s", err)
+	}
+	if header[3] != ' ' || header[responseHeaderSize-1] != '\n' {
+		return 0, 0, fmt.Errorf("invalid response header: %q", header)
+	}
+	status, err := strconv.Atoi(string(header[:3]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid status in the response header: %q", header)
+	}
+	length, err := strconv.Atoi(strings.TrimSpace(string(header[4 : responseHeaderSize-1])))
+	if err != nil || length < 0 {
+		return 0, 0, fmt.Errorf("invalid length in the response header: %q", header)
+	}
+	return status, length, nil
+}
+
+func (connector *Connector) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: connector.timeout()}
+	switch connector.ConnectionType {
+	case "tcp":
+		return dialer.Dial("tcp", connector.LivestatusAddress)
+	case "tls":
+		config := connector.TLSConfig
+		if config == nil {
+			config = &tls.Config{}
 		}
+		return tls.DialWithDialer(dialer, "tcp", connector.LivestatusAddress, config)
+	case "file":
+		return dialer.Dial("unix", connector.LivestatusAddress)
 	}
-	outerFinish <- true
+	return nil, fmt.Errorf("connection type is unknown, options are: tcp, tls, file. Input: %s", connector.ConnectionType)
+}
+
+func (connector *Connector) timeout() time.Duration {
+	if connector.Timeout > 0 {
+		return connector.Timeout
+	}
+	return DefaultTimeout
+}
+
+func (connector *Connector) idleConnection() net.Conn {
+	connector.mutex.Lock()
+	defer connector.mutex.Unlock()
+	if len(connector.idle) == 0 {
+		return nil
+	}
+	conn := connector.idle[len(connector.idle)-1]
+	connector.idle = connector.idle[:len(connector.idle)-1]
+	return conn
+}
+
+func (connector *Connector) keep(conn net.Conn) {
+	connector.mutex.Lock()
+	defer connector.mutex.Unlock()
+	if len(connector.idle) >= maxIdleConnections {
+		conn.Close()
+		return
+	}
+	connector.idle = append(connector.idle, conn)
+}
+
+//Close closes the connections which are kept open by KeepAlive.
+func (connector *Connector) Close() {
+	connector.mutex.Lock()
+	defer connector.mutex.Unlock()
+	for _, conn := range connector.idle {
+		conn.Close()
+	}
+	connector.idle = nil
+}
+
+func firstLine(query string) string {
+	return strings.SplitN(query, "\n", 2)[0]
 }
@@ -2,21 +2,45 @@ package livestatus
 
 import (
 	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
 	"github.com/spitefulgrog/nagflux/helper"
 	"github.com/spitefulgrog/nagflux/logging"
+	"io/ioutil"
 	"log"
+	"math/big"
 	"net"
+	"path"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 )
 
+//MockLivestatus answers the Queries like livestatus, with ResponseHeader fixed16 and KeepAlive if they are requested.
+//The Failures are answered with the status 400 and the message.
 type MockLivestatus struct {
 	LivestatusAddress string
 	ConnectionType    string
 	Queries           map[string]string
 	isRunning         bool
+
+	Failures  map[string]string
+	TLSConfig *tls.Config
+
+	listener    net.Listener
+	connections []net.Conn
+	//connections which sent a query
+	used      int
+	authUsers []string
 }
 
 var mutex = &sync.Mutex{}
@@ -27,6 +51,8 @@ func (mockLive *MockLivestatus) StartMockLivestatus() {
 	switch mockLive.ConnectionType {
 	case "tcp":
 		listener, err = net.Listen("tcp", mockLive.LivestatusAddress)
+	case "tls":
+		listener, err = tls.Listen("tcp", mockLive.LivestatusAddress, mockLive.TLSConfig)
 	case "file":
 		listener, err = net.Listen("unix", mockLive.LivestatusAddress)
 	default:
@@ -37,14 +63,23 @@ func (mockLive *MockLivestatus) StartMockLivestatus() {
 	if err != nil {
 		log.Panic(err)
 	}
+	mutex.Lock()
+	mockLive.listener = listener
+	mutex.Unlock()
 
 	isRunning := true
 	for isRunning {
 		conn, err := listener.Accept()
 		if err != nil {
 			//log.Println(err)
+			mutex.Lock()
+			isRunning = mockLive.isRunning
+			mutex.Unlock()
 			continue
 		}
+		mutex.Lock()
+		mockLive.connections = append(mockLive.connections, conn)
+		mutex.Unlock()
 		go mockLive.handle(conn)
 
 		mutex.Lock()
@@ -54,49 +89,100 @@ func (mockLive *MockLivestatus) StartMockLivestatus() {
 }
 
 func (mockLive *MockLivestatus) handle(conn net.Conn) {
+	defer conn.Close()
 	connReader := bufio.NewReader(conn)
-	connWriter := bufio.NewWriter(conn)
-	query := ""
-	line, _ := connReader.ReadString('\n')
-	for line != "\n" {
-		query += line
-		line, _ = connReader.ReadString('\n')
-	}
-	query += "\n"
-	answer, found := mockLive.Queries[query]
-	if found == false {
-		answer = "\n\n"
-	}
-	connWriter.WriteString(answer)
-	connWriter.Flush()
-	conn.Close()
+	for first := true; ; first = false {
+		query := ""
+		fixed16, keepAlive := false, false
+		line, err := connReader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if first {
+			mutex.Lock()
+			mockLive.used++
+			mutex.Unlock()
+		}
+		for line != "\n" {
+			switch {
+			case line == "ResponseHeader: fixed16\n":
+				fixed16 = true
+			case line == "KeepAlive: on\n":
+				keepAlive = true
+			case strings.HasPrefix(line, "AuthUser: "):
+				mutex.Lock()
+				mockLive.authUsers = append(mockLive.authUsers, strings.TrimSpace(strings.TrimPrefix(line, "AuthUser: ")))
+				mutex.Unlock()
+			default:
+				query += line
+			}
+			if line, err = connReader.ReadString('\n'); err != nil {
+				return
+			}
+		}
+		query += "\n"
+		status := 200
+		answer, found := mockLive.Queries[query]
+		if message, failed := mockLive.Failures[query]; failed {
+			status, answer = 400, message
+		} else if found == false {
+			answer = "[]\n"
+		}
+		if fixed16 {
+			fmt.Fprintf(conn, "%03d %11d\n", status, len(answer))
+		}
+		conn.Write([]byte(answer))
+		if !keepAlive {
+			return
+		}
+	}
 }
 
 func (mockLive *MockLivestatus) StopMockLivestatus() {
+	mutex.Lock()
+	defer mutex.Unlock()
+	mockLive.isRunning = false
+	if mockLive.listener != nil {
+		mockLive.listener.Close()
+	}
+	mockLive.dropConnections()
+}
 
+//Closes the open connections, like livestatus does after an idle timeout.
+func (mockLive *MockLivestatus) dropConnections() {
+	for _, conn := range mockLive.connections {
+		conn.Close()
+	}
+}
+
+func startMockLivestatus(t *testing.T, mockLive *MockLivestatus) {
+	go mockLive.StartMockLivestatus()
+	if err := helper.WaitForPort("tcp", mockLive.LivestatusAddress, time.Duration(2)*time.Second); err != nil {
+		t.Fatal(err)
+	}
 }
 
 func TestConnectToLivestatus(t *testing.T) {
 	//Create Livestatus mock
-	livestatus := MockLivestatus{"localhost:6560", "tcp", map[string]string{"test\n\n": "foo;bar\n"}, true}
+	livestatus := MockLivestatus{LivestatusAddress: "localhost:6560", ConnectionType: "tcp", Queries: map[string]string{"test\n\n": `[["name","state"],["foo;bar",0]]`}, isRunning: true}
 
 	go livestatus.StartMockLivestatus()
-	connector := Connector{logging.GetLogger(), livestatus.LivestatusAddress, livestatus.ConnectionType}
+	connector := Connector{Log: logging.GetLogger(), LivestatusAddress: livestatus.LivestatusAddress, ConnectionType: livestatus.ConnectionType}
 	if err := helper.WaitForPort("tcp", "localhost:6560", time.Duration(2)*time.Second); err != nil {
 		panic(err)
 	}
-	csv := make(chan []string)
+	rows := make(chan Row)
 	finished := make(chan bool)
-	go connector.connectToLivestatus("test\n\n", csv, finished)
+	go connector.connectToLivestatus("test\n\n", rows, finished)
 
-	expected := []string{"foo", "bar"}
+	expected := Row{"name": json.RawMessage(`"foo;bar"`), "state": json.RawMessage(`0`)}
 
 	waitingForTheEnd := true
 	for waitingForTheEnd {
 		select {
-		case line := <-csv:
-			if !reflect.DeepEqual(line, expected) {
-				t.Errorf("Expected:%s result:%s", expected, line)
+		case row := <-rows:
+			if !reflect.DeepEqual(row, expected) {
+				t.Errorf("Expected:%s result:%s", expected, row)
 			}
 		case result := <-finished:
 			if !result {
@@ -109,11 +195,185 @@ func TestConnectToLivestatus(t *testing.T) {
 	}
 	livestatus.StopMockLivestatus()
 
-	connector2 := Connector{logging.GetLogger(), "/live", "file"}
-	csv2 := make(chan []string)
+	connector2 := Connector{Log: logging.GetLogger(), LivestatusAddress: "/live", ConnectionType: "file"}
+	rows2 := make(chan Row)
 	finished2 := make(chan bool)
-	go connector2.connectToLivestatus("test\n\n", csv2, finished2)
+	go connector2.connectToLivestatus("test\n\n", rows2, finished2)
 	if result := <-finished2; result {
 		t.Error("Expected an error with unknown connection type")
 	}
 }
+
+func TestConnectorResponseError(t *testing.T) {
+	logging.InitTestLogger()
+	livestatus := &MockLivestatus{LivestatusAddress: "localhost:6562", ConnectionType: "tcp", isRunning: true,
+		Failures: map[string]string{"GET foo\n\n": "Invalid GET request, no such table 'foo'\n"}}
+	startMockLivestatus(t, livestatus)
+	defer livestatus.StopMockLivestatus()
+	connector := &Connector{Log: logging.GetLogger(), LivestatusAddress: livestatus.LivestatusAddress, ConnectionType: "tcp"}
+
+	_, err := connector.query("GET foo\n\n")
+	if responseError, ok := err.(*ResponseError); !ok || responseError.Status != 400 || responseError.Message != "Invalid GET request, no such table 'foo'" {
+		t.Errorf("Expected the error of livestatus, got %v", err)
+	}
+	finished := make(chan bool, 1)
+	connector.connectToLivestatus("GET foo\n\n", make(chan Row), finished)
+	if <-finished {
+		t.Error("The query should fail")
+	}
+}
+
+func TestConnectorKeepAlive(t *testing.T) {
+	logging.InitTestLogger()
+	livestatus := &MockLivestatus{LivestatusAddress: "localhost:6563", ConnectionType: "tcp", isRunning: true,
+		Queries: map[string]string{"GET hosts\n\n": "host1\nhost2\n"}}
+	startMockLivestatus(t, livestatus)
+	defer livestatus.StopMockLivestatus()
+	connector := &Connector{Log: logging.GetLogger(), LivestatusAddress: livestatus.LivestatusAddress, ConnectionType: "tcp",
+		AuthUser: "alice", KeepAlive: true}
+	defer connector.Close()
+
+	for i := 0; i < 3; i++ {
+		if body, err := connector.query("GET hosts\n\n"); err != nil || string(body) != "host1\nhost2\n" {
+			t.Fatalf("Unexpected answer: %q %v", body, err)
+		}
+	}
+	mutex.Lock()
+	if livestatus.used != 1 {
+		t.Errorf("The connection should be reused, got %d connections", livestatus.used)
+	}
+	if !reflect.DeepEqual(livestatus.authUsers, []string{"alice", "alice", "alice"}) {
+		t.Errorf("Unexpected AuthUser headers: %v", livestatus.authUsers)
+	}
+	//livestatus closed the idle connection, the query is sent on a new one
+	livestatus.dropConnections()
+	mutex.Unlock()
+	if body, err := connector.query("GET hosts\n\n"); err != nil || string(body) != "host1\nhost2\n" {
+		t.Errorf("The query should be repeated on a new connection: %q %v", body, err)
+	}
+	mutex.Lock()
+	if livestatus.used != 2 {
+		t.Errorf("Expected a second connection, got %d connections", livestatus.used)
+	}
+	mutex.Unlock()
+}
+
+func TestConnectorTimeout(t *testing.T) {
+	logging.InitTestLogger()
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	//accepts but never answers
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	connector := &Connector{Log: logging.GetLogger(), LivestatusAddress: listener.Addr().String(), ConnectionType: "tcp",
+		Timeout: time.Duration(100) * time.Millisecond}
+	start := time.Now()
+	if _, err := connector.query("GET hosts\n\n"); err == nil {
+		t.Error("Expected a timeout")
+	}
+	if elapsed := time.Since(start); elapsed > time.Duration(2)*time.Second {
+		t.Errorf("The timeout was not used, took %s", elapsed)
+	}
+}
+
+func TestReadResponseHeader(t *testing.T) {
+	for header, expected := range map[string][]int{
+		"200          12\n": {200, 12},
+		"404           0\n": {404, 0},
+		"200 12\n":          nil,
+		"20x          12\n": nil,
+		"200          1x\n": nil,
+		"200          12 ":  nil,
+	} {
+		status, length, err := readResponseHeader(strings.NewReader(header))
+		if expected == nil {
+			if err == nil {
+				t.Errorf("%q: expected an error", header)
+			}
+		} else if err != nil || status != expected[0] || length != expected[1] {
+			t.Errorf("%q: expected %v, got %d %d %v", header, expected, status, length, err)
+		}
+	}
+}
+
+//Writes a certificate signed by the parent, or a self signed one, and returns it with its key.
+func writeCertificate(t *testing.T, folder, name string, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawKey, _ := x509.MarshalECPrivateKey(key)
+	ioutil.WriteFile(path.Join(folder, name+".pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw}), 0644)
+	ioutil.WriteFile(path.Join(folder, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: rawKey}), 0600)
+	certificate, _ := x509.ParseCertificate(raw)
+	return certificate, key
+}
+
+func TestConnectorTLS(t *testing.T) {
+	logging.InitTestLogger()
+	folder := t.TempDir()
+	validity := time.Now().Add(time.Duration(1) * time.Hour)
+	ca, caKey := writeCertificate(t, folder, "ca", &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "ca"},
+		NotAfter: validity, IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign}, nil, nil)
+	writeCertificate(t, folder, "server", &x509.Certificate{SerialNumber: big.NewInt(2), Subject: pkix.Name{CommonName: "localhost"},
+		NotAfter: validity, DNSNames: []string{"localhost"}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}, ca, caKey)
+	writeCertificate(t, folder, "client", &x509.Certificate{SerialNumber: big.NewInt(3), Subject: pkix.Name{CommonName: "nagflux"},
+		NotAfter: validity, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}, ca, caKey)
+
+	serverCertificate, err := tls.LoadX509KeyPair(path.Join(folder, "server.pem"), path.Join(folder, "server.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca)
+	livestatus := &MockLivestatus{LivestatusAddress: "localhost:6564", ConnectionType: "tls", isRunning: true,
+		Queries:   map[string]string{"GET status\nColumns: livestatus_version\n\n": "2.2.0p1\n"},
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{serverCertificate}, ClientCAs: clientCAs, ClientAuth: tls.RequireAndVerifyClientCert}}
+	startMockLivestatus(t, livestatus)
+	defer livestatus.StopMockLivestatus()
+
+	tlsConfig, err := NewTLSConfig(path.Join(folder, "ca.pem"), path.Join(folder, "client.pem"), path.Join(folder, "client.key"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	connector := &Connector{Log: logging.GetLogger(), LivestatusAddress: livestatus.LivestatusAddress, ConnectionType: "tls", TLSConfig: tlsConfig}
+	if body, err := connector.query("GET status\nColumns: livestatus_version\n\n"); err != nil || string(body) != "2.2.0p1\n" {
+		t.Errorf("Unexpected answer: %q %v", body, err)
+	}
+
+	//the server needs the client certificate
+	tlsConfig, _ = NewTLSConfig(path.Join(folder, "ca.pem"), "", "", false)
+	connector.TLSConfig = tlsConfig
+	if _, err := connector.query("GET status\nColumns: livestatus_version\n\n"); err == nil {
+		t.Error("Expected an error without client certificate")
+	}
+	//the CA of the system does not know the server
+	connector.TLSConfig = nil
+	if _, err := connector.query("GET status\nColumns: livestatus_version\n\n"); err == nil {
+		t.Error("Expected an error with an unknown server certificate")
+	}
+
+	if _, err := NewTLSConfig(path.Join(folder, "client.key"), "", "", false); err == nil {
+		t.Error("A key is no CA")
+	}
+	if _, err := NewTLSConfig("", path.Join(folder, "client.pem"), "", false); err == nil {
+		t.Error("The key of the client certificate is missing")
+	}
+}
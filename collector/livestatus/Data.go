@@ -1,9 +1,10 @@
 package livestatus
 
 import (
-	"fmt"
-	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/data"
 	"github.com/spitefulgrog/nagflux/helper"
+	"strconv"
 	"strings"
 )
 
@@ -14,43 +15,55 @@ type Data struct {
 	comment            string
 	entryTime          string
 	author             string
+	site               string
 }
 
-//Escape all bad chars.
-func (live *Data) sanitizeValues() {
-	live.hostName = helper.SanitizeInfluxInput(live.hostName)
-	live.serviceDisplayName = helper.SanitizeInfluxInput(live.serviceDisplayName)
-	live.entryTime = helper.SanitizeInfluxInput(live.entryTime)
-	live.author = helper.SanitizeInfluxInput(live.author)
-}
-
-//Generates the Influxdb tablename.
-func (live Data) getTablename() string {
-	if live.serviceDisplayName == "" {
-		live.serviceDisplayName = config.GetConfig().InfluxDBGlobal.HostcheckAlias
+//Generates a point of the measurement messages, the time is given in seconds. Unknown types and hostchecks have no tag,
+//the site just if it has a name.
+func (live Data) genPoint(typ, message, time string) collector.Point {
+	timestamp, _ := strconv.ParseInt(helper.CastStringTimeFromSToMs(time), 10, 64)
+	tags := map[string]string{"host": live.hostName, "author": live.author}
+	if live.serviceDisplayName != "" {
+		tags["service"] = live.serviceDisplayName
+	}
+	if live.site != "" {
+		tags["site"] = live.site
+	}
+	if typ != "" {
+		tags["type"] = typ
+	}
+	return collector.Point{
+		Kind:        collector.MessageKind,
+		Measurement: "messages",
+		Tags:        tags,
+		Fields:      data.Fields{"message": data.String(message)},
+		Timestamp:   timestamp,
 	}
-	return fmt.Sprintf("messages,host=%s,service=%s", live.hostName, live.serviceDisplayName)
-}
-
-//Generates the linedata which can be parsed from influxdb
-func (live Data) genInfluxLine(tags string) string {
-	return live.genInfluxLineWithValue(tags, live.comment)
-}
-
-//Generates the linedata which can be parsed from influxdb
-func (live Data) genInfluxLineWithValue(tags, text string) string {
-	tags += ",author=" + live.author
-	return fmt.Sprintf("%s%s message=\"%s\" %s", live.getTablename(), tags, text, helper.CastStringTimeFromSToMs(live.entryTime))
 }
 
-func (live Data) genElasticLineWithValue(index, typ, value, timestamp string) string {
-	value = strings.Replace(value, `"`, `\"`, -1)
-	if live.serviceDisplayName == "" {
-		live.serviceDisplayName = config.GetConfig().ElasticsearchGlobal.HostcheckAlias
-	}
-	head := fmt.Sprintf(`{"index":{"_index":"%s","_type":"messages"}}`, helper.GenIndex(index, timestamp)) + "\n"
-	data := fmt.Sprintf(`{"timestamp":%s,"message":"%s","author":"%s","host":"%s","service":"%s","type":"%s"}`+"\n",
-		helper.CastStringTimeFromSToMs(timestamp), value, live.author, live.hostName, live.serviceDisplayName, typ,
-	)
-	return head + data
+//Generates a point of the measurement states, the type tells the kind of log entry. The message is the only field
+//besides the given ones, the author and the state_type are tags if they are set.
+func (live Data) genStatePoint(typ, stateType string, fields data.Fields) collector.Point {
+	timestamp, _ := strconv.ParseInt(helper.CastStringTimeFromSToMs(live.entryTime), 10, 64)
+	tags := map[string]string{"host": live.hostName, "type": typ}
+	if live.serviceDisplayName != "" {
+		tags["service"] = live.serviceDisplayName
+	}
+	if stateType != "" {
+		tags["state_type"] = stateType
+	}
+	if live.author != "" {
+		tags["author"] = live.author
+	}
+	if live.site != "" {
+		tags["site"] = live.site
+	}
+	fields["message"] = data.String(strings.TrimSpace(live.comment))
+	return collector.Point{
+		Kind:        collector.MessageKind,
+		Measurement: "states",
+		Tags:        tags,
+		Fields:      fields,
+		Timestamp:   timestamp,
+	}
 }
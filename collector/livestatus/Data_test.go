@@ -1,52 +1,38 @@
 package livestatus
 
 import (
-	"fmt"
-	"github.com/spitefulgrog/nagflux/helper"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/data"
 	"reflect"
 	"testing"
 )
 
-func TestDataSanitizeValues(t *testing.T) {
+func TestGenPoint(t *testing.T) {
 	t.Parallel()
-	live1 := Data{"host", "service", "comm\\ent a", "0", "author"}
-	live2 := live1
-	live2.sanitizeValues()
-	if !reflect.DeepEqual(live1, live2) {
-		t.Errorf("Sanitize should not change the comment. \n1:%s\n2:%s", live1, live2)
+	live := Data{"host", "service", "comment", "0", "author", ""}
+	expected := collector.Point{
+		Kind:        collector.MessageKind,
+		Measurement: "messages",
+		Tags:        map[string]string{"host": "host", "service": "service", "author": "author", "type": "comment"},
+		Fields:      data.Fields{"message": data.String("special text")},
+		Timestamp:   12000,
 	}
-}
-
-func TestGetTablename(t *testing.T) {
-	t.Parallel()
-	live := Data{"host", "service", "comment", "0", "author"}
-	tablename := fmt.Sprintf("messages,host=%s,service=%s", live.hostName, live.serviceDisplayName)
-	if live.getTablename() != tablename {
-		t.Error("Tablename should match")
-	}
-	tablename2 := fmt.Sprintf("%s%s%s%smessages", live.hostName, "", live.serviceDisplayName, "")
-	if live.getTablename() == tablename2 {
-		t.Error("Tablname should not match")
+	if result := live.genPoint("comment", "special text", "12"); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected:%v\nResult:%v", expected, result)
 	}
 }
 
-func TestGenInfluxLineWithValue(t *testing.T) {
+func TestGenPointHostcheck(t *testing.T) {
 	t.Parallel()
-	live := Data{"host", "service", "comment", "0", "author"}
-
-	expected := fmt.Sprintf("%s%s message=\"%s\" %s", live.getTablename(), ",author="+live.author, "special text", helper.CastStringTimeFromSToMs(live.entryTime))
-	result := live.genInfluxLineWithValue("", "special text")
-	if expected != result {
-		t.Errorf("Expected:%s\nResult:%s", expected, result)
+	live := Data{"host", "", "comment", "0", "author", "site1"}
+	result := live.genPoint("", "text", "12")
+	if _, ok := result.Tags["service"]; ok {
+		t.Errorf("Hostchecks should not have a service, the targets add their alias: %v", result.Tags)
 	}
-}
-
-func TestGenInfluxLine(t *testing.T) {
-	t.Parallel()
-	live := Data{"host", "service", "comment", "0", "author"}
-	expected := fmt.Sprintf("%s%s message=\"%s\" %s", live.getTablename(), ",a=1,b=2,author="+live.author, "comment", helper.CastStringTimeFromSToMs(live.entryTime))
-	result := live.genInfluxLine(",a=1,b=2")
-	if expected != result {
-		t.Errorf("Expected:%s\nResult:%s", expected, result)
+	if _, ok := result.Tags["type"]; ok {
+		t.Errorf("Unknown types should be left out: %v", result.Tags)
+	}
+	if result.Tags["site"] != "site1" {
+		t.Errorf("The site is missing: %v", result.Tags)
 	}
 }
@@ -1,52 +1,21 @@
 package livestatus
 
 import (
-	"fmt"
-	"github.com/spitefulgrog/nagflux/config"
 	"github.com/spitefulgrog/nagflux/logging"
 	"testing"
 )
 
-func TestSanitizeValuesDowntime(t *testing.T) {
-	t.Parallel()
-	down := DowntimeData{Data: Data{hostName: "host 1", serviceDisplayName: "service 1", author: "philip"}, endTime: "123"}
-	down.sanitizeValues()
-	if down.Data.hostName != `host\ 1` {
-		t.Errorf("The notificationType should be escaped. Expected: %s Got: %s", `host\ 1`, down.Data.hostName)
-	}
-}
-
-func TestPrintInfluxdbDowntime(t *testing.T) {
+func TestPointsDowntime(t *testing.T) {
 	logging.InitTestLogger()
-	down := DowntimeData{Data: Data{hostName: "host 1", serviceDisplayName: "service 1", author: "philip"}, endTime: "123"}
-	if !didThisPanic(down.PrintForInfluxDB, "0.8") {
-		t.Errorf("This should panic, due to unsuported influxdb version")
-	}
-
-	result := down.PrintForInfluxDB("0.9")
-	expected := `messages,host=host\ 1,service=service\ 1,type=downtime,author=philip message="Downtime start: <br>" 000
-messages,host=host\ 1,service=service\ 1,type=downtime,author=philip message="Downtime end: <br>" 123000`
-	if result != expected {
-		t.Errorf("The result did not match the expected. Result:\n%s \nExpected:\n%s", result, expected)
+	down := DowntimeData{Data: Data{hostName: "host 1", serviceDisplayName: "service 1", author: "philip", entryTime: "100"}, endTime: "123"}
+	points := down.Points()
+	if len(points) != 2 {
+		t.Fatalf("Expected a start and an end, got: %v", points)
 	}
-}
-
-func TestPrintElasticsearchDowntime(t *testing.T) {
-	logging.InitTestLogger()
-	config.InitConfigFromString(fmt.Sprintf(Config, "monthly"))
-	down := DowntimeData{Data: Data{hostName: "host 1", serviceDisplayName: "service 1", author: "philip", entryTime: "1458988932000"}, endTime: "123"}
-	if !didThatPanic(down.PrintForElasticsearch, "1.0", "index") {
-		t.Errorf("This should panic, due to unsuported elasticsearch version")
+	if points[0].Fields["message"].Text != "Downtime start: <br>" || points[0].Timestamp != 100000 || points[0].Tags["type"] != "downtime" {
+		t.Errorf("Unexpected start: %v", points[0])
 	}
-
-	result := down.PrintForElasticsearch("2.0", "index")
-	expected := `{"index":{"_index":"index-2016.03","_type":"messages"}}
-{"timestamp":1458988932000000,"message":"Downtime start: <br>","author":"philip","host":"host 1","service":"service 1","type":"downtime"}
-
-{"index":{"_index":"index-1970.01","_type":"messages"}}
-{"timestamp":123000,"message":"Downtime end: <br>","author":"philip","host":"host 1","service":"service 1","type":"downtime"}
-`
-	if result != expected {
-		t.Errorf("The result did not match the expected. Result: %sExpected: %s", result, expected)
+	if points[1].Fields["message"].Text != "Downtime end: <br>" || points[1].Timestamp != 123000 || points[1].Tags["type"] != "downtime" {
+		t.Errorf("Unexpected end: %v", points[1])
 	}
 }
@@ -0,0 +1,18 @@
+package livestatus
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/data"
+)
+
+//FlappingData adds the start and stop of flapping to the livestatus data
+type FlappingData struct {
+	collector.Filterable
+	Data
+	stateType string
+}
+
+//Points returns the flapping as point of the measurement states with the type flapping.
+func (flapping FlappingData) Points() []collector.Point {
+	return []collector.Point{flapping.genStatePoint("flapping", flapping.stateType, data.Fields{})}
+}
@@ -0,0 +1,35 @@
+package livestatus
+
+import (
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/logging"
+	"reflect"
+	"testing"
+)
+
+func TestPointsFlapping(t *testing.T) {
+	logging.InitTestLogger()
+	live := Collector{log: logging.GetLogger()}
+	flapping := live.handleQueryForFlapping(logRow{Type: "HOST FLAPPING ALERT", Time: "1458988932", HostName: "host 1", StateType: "STARTED",
+		Comment: "Host appears to have started flapping (21.3% change >= 20.0% threshold)"})
+	if flapping == nil {
+		t.Fatal("Could not parse the flapping")
+	}
+	points := flapping.Points()
+	if len(points) != 1 || points[0].Measurement != "states" || points[0].Timestamp != 1458988932000 {
+		t.Fatalf("Unexpected points: %v", points)
+	}
+	if tags := map[string]string{"host": "host 1", "type": "flapping", "state_type": "STARTED"}; !reflect.DeepEqual(points[0].Tags, tags) {
+		t.Errorf("Unexpected tags: %v", points[0].Tags)
+	}
+	if fields := (data.Fields{"message": data.String("Host appears to have started flapping (21.3% change >= 20.0% threshold)")}); !reflect.DeepEqual(points[0].Fields, fields) {
+		t.Errorf("Unexpected fields: %v", points[0].Fields)
+	}
+
+	if live.handleQueryForFlapping(logRow{Type: "SERVICE FLAPPING ALERT", Time: "1458988932"}) != nil {
+		t.Error("An entry without host should be skipped")
+	}
+	if live.handleQueryForFlapping(logRow{Type: "SERVICE ALERT", Time: "1458988932", HostName: "host 1"}) != nil {
+		t.Error("Other types should be skipped")
+	}
+}
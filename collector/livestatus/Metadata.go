@@ -0,0 +1,124 @@
+package livestatus
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+//Names of the metadata which can be added as tags, custom variables are given with a leading underscore like _SITE.
+const (
+	MetadataHostgroups    = "hostgroups"
+	MetadataServicegroups = "servicegroups"
+	MetadataContactGroups = "contact_groups"
+	MetadataAddress       = "address"
+)
+
+//Metadata contains the groups, the address and the custom variables of a host or service.
+type Metadata struct {
+	Groups          []string
+	ContactGroups   []string
+	Address         string
+	CustomVariables map[string]string
+}
+
+//MetadataCache contains the metadata of every host and service.
+type MetadataCache struct {
+	hosts    map[string]Metadata
+	services map[string]map[string]Metadata
+}
+
+//NewMetadataCache returns an empty cache.
+func NewMetadataCache() MetadataCache {
+	return MetadataCache{hosts: map[string]Metadata{}, services: map[string]map[string]Metadata{}}
+}
+
+//IsValidMetadataTag returns true if the name is one of the Metadata constants or a custom variable.
+func IsValidMetadataTag(name string) bool {
+	switch name {
+	case MetadataHostgroups, MetadataServicegroups, MetadataContactGroups, MetadataAddress:
+		return true
+	}
+	return len(name) > 1 && strings.HasPrefix(name, "_")
+}
+
+//Adds a row of QueryForHostMetadata, it needs the name of the host.
+func (cache MetadataCache) addHost(row metadataRow) bool {
+	if row.Name == "" {
+		return false
+	}
+	cache.hosts[row.Name] = Metadata{
+		Groups:          row.Groups,
+		ContactGroups:   row.ContactGroups,
+		Address:         row.Address,
+		CustomVariables: customVariables(row.CustomVariableNames, row.CustomVariableValues),
+	}
+	return true
+}
+
+//Adds a row of QueryForServiceMetadata, it needs the host_name and the description.
+func (cache MetadataCache) addService(row metadataRow) bool {
+	if row.HostName == "" || row.Description == "" {
+		return false
+	}
+	if _, ok := cache.services[row.HostName]; !ok {
+		cache.services[row.HostName] = map[string]Metadata{}
+	}
+	cache.services[row.HostName][row.Description] = Metadata{
+		Groups:          row.Groups,
+		ContactGroups:   row.ContactGroups,
+		CustomVariables: customVariables(row.CustomVariableNames, row.CustomVariableValues),
+	}
+	return true
+}
+
+//Returns the tags of the allowed metadata, an empty service is the hostcheck. The values of the service win over the
+//ones of the host, lists are joined by commas. Custom variables are named without underscore in lower case: _SITE is site.
+func (cache MetadataCache) tags(host, service string, allowed []string) map[string]string {
+	hostData, hostFound := cache.hosts[host]
+	serviceData, serviceFound := cache.services[host][service]
+	if !hostFound && !serviceFound {
+		return nil
+	}
+	tags := map[string]string{}
+	for _, name := range allowed {
+		var value string
+		switch name {
+		case MetadataHostgroups:
+			value = strings.Join(hostData.Groups, ",")
+		case MetadataServicegroups:
+			if service != "" {
+				value = strings.Join(serviceData.Groups, ",")
+			}
+		case MetadataContactGroups:
+			if service != "" {
+				value = strings.Join(serviceData.ContactGroups, ",")
+			} else {
+				value = strings.Join(hostData.ContactGroups, ",")
+			}
+		case MetadataAddress:
+			value = hostData.Address
+		default:
+			variable := strings.TrimPrefix(name, "_")
+			if value = serviceData.CustomVariables[variable]; value == "" {
+				value = hostData.CustomVariables[variable]
+			}
+			name = strings.ToLower(variable)
+		}
+		if value != "" {
+			tags[name] = value
+		}
+	}
+	return tags
+}
+
+//Icinga2 may return lists or dictionaries as values, they are kept as json.
+func customVariables(names []string, values []json.RawMessage) map[string]string {
+	if len(names) == 0 || len(names) != len(values) {
+		return nil
+	}
+	result := make(map[string]string, len(names))
+	for i, name := range names {
+		result[name] = text(values[i])
+	}
+	return result
+}
@@ -0,0 +1,93 @@
+package livestatus
+
+import (
+	"encoding/json"
+	"github.com/spitefulgrog/nagflux/helper"
+	"github.com/spitefulgrog/nagflux/logging"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMetadataCacheTags(t *testing.T) {
+	cache := NewMetadataCache()
+	cache.addHost(metadataRow{Name: "host1", Groups: []string{"linux", "web"}, ContactGroups: []string{"admins"}, Address: "10.0.0.1",
+		CustomVariableNames: []string{"SITE", "OWNER"}, CustomVariableValues: []json.RawMessage{json.RawMessage(`"berlin"`), json.RawMessage(`"alice"`)}})
+	cache.addService(metadataRow{HostName: "host1", Description: "http", Groups: []string{"webchecks"}, ContactGroups: []string{"webadmins"},
+		CustomVariableNames: []string{"OWNER"}, CustomVariableValues: []json.RawMessage{json.RawMessage(`"bob"`)}})
+	cache.addService(metadataRow{HostName: "host1", Description: "disk"})
+	if cache.addHost(metadataRow{HostName: "host2", Groups: []string{"linux"}}) {
+		t.Error("A row without name should be skipped")
+	}
+	allowed := []string{MetadataHostgroups, MetadataServicegroups, MetadataContactGroups, MetadataAddress, "_SITE", "_OWNER"}
+
+	for _, test := range []struct {
+		host, service string
+		expected      map[string]string
+	}{
+		{"host1", "", map[string]string{"hostgroups": "linux,web", "contact_groups": "admins", "address": "10.0.0.1", "site": "berlin", "owner": "alice"}},
+		{"host1", "http", map[string]string{"hostgroups": "linux,web", "servicegroups": "webchecks", "contact_groups": "webadmins", "address": "10.0.0.1", "site": "berlin", "owner": "bob"}},
+		{"host1", "disk", map[string]string{"hostgroups": "linux,web", "address": "10.0.0.1", "site": "berlin", "owner": "alice"}},
+		{"host2", "", nil},
+	} {
+		if tags := cache.tags(test.host, test.service, allowed); !reflect.DeepEqual(tags, test.expected) {
+			t.Errorf("%s/%s: expected %v, got %v", test.host, test.service, test.expected, tags)
+		}
+	}
+	if tags := cache.tags("host1", "http", []string{"_OWNER"}); !reflect.DeepEqual(tags, map[string]string{"owner": "bob"}) {
+		t.Errorf("Just the allowed tags should be returned: %v", tags)
+	}
+}
+
+func TestCustomVariables(t *testing.T) {
+	values := []json.RawMessage{json.RawMessage(`"berlin"`), json.RawMessage(`"alice,bob"`), json.RawMessage(`["a","b"]`)}
+	expected := map[string]string{"SITE": "berlin", "OWNER": "alice,bob", "TEAMS": `["a","b"]`}
+	if variables := customVariables([]string{"SITE", "OWNER", "TEAMS"}, values); !reflect.DeepEqual(variables, expected) {
+		t.Errorf("Unexpected variables: %v", variables)
+	}
+	if variables := customVariables([]string{"SITE"}, values); variables != nil {
+		t.Errorf("The values don't fit the names: %v", variables)
+	}
+	if variables := customVariables(nil, nil); variables != nil {
+		t.Errorf("Expected no variables: %v", variables)
+	}
+}
+
+func TestIsValidMetadataTag(t *testing.T) {
+	for name, expected := range map[string]bool{"hostgroups": true, "address": true, "_SITE": true, "_": false, "site": false, "": false} {
+		if IsValidMetadataTag(name) != expected {
+			t.Errorf("%q: expected %t", name, expected)
+		}
+	}
+}
+
+func TestCacheBuilderMetadata(t *testing.T) {
+	logging.InitTestLogger()
+	queries := map[string]string{
+		QueryForHostMetadata: `[["name","groups","contact_groups","address","custom_variable_names","custom_variable_values"],
+["host1",["linux","web"],["admins"],"10.0.0.1",["SITE"],["berlin"]]]`,
+		QueryForServiceMetadata: `[["host_name","description","groups","contact_groups","custom_variable_names","custom_variable_values"],
+["host1","http",["webchecks"],["webadmins"],[],[]]]`,
+	}
+	livestatus := &MockLivestatus{LivestatusAddress: "localhost:6561", ConnectionType: "tcp", Queries: queries, isRunning: true}
+	go livestatus.StartMockLivestatus()
+	if err := helper.WaitForPort("tcp", livestatus.LivestatusAddress, time.Duration(2)*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	connector := &Connector{Log: logging.GetLogger(), LivestatusAddress: livestatus.LivestatusAddress, ConnectionType: livestatus.ConnectionType}
+	builder := NewLivestatusCacheBuilder(connector, []string{MetadataHostgroups, MetadataServicegroups, "_SITE", "unknown"})
+	defer builder.Stop()
+
+	expected := map[string]string{"hostgroups": "linux,web", "servicegroups": "webchecks", "site": "berlin"}
+	timeout := time.After(time.Duration(5) * time.Second)
+	for !reflect.DeepEqual(builder.MetadataTags("host1", "http"), expected) {
+		select {
+		case <-timeout:
+			t.Fatalf("Expected %v, got %v", expected, builder.MetadataTags("host1", "http"))
+		case <-time.After(time.Duration(10) * time.Millisecond):
+		}
+	}
+	if groups := builder.Hostgroups("host1"); !reflect.DeepEqual(groups, []string{"linux", "web"}) {
+		t.Errorf("Unexpected hostgroups: %v", groups)
+	}
+}
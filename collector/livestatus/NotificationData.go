@@ -3,7 +3,6 @@ package livestatus
 import (
 	"fmt"
 	"github.com/spitefulgrog/nagflux/collector"
-	"github.com/spitefulgrog/nagflux/helper"
 	"github.com/spitefulgrog/nagflux/logging"
 	"strings"
 )
@@ -16,36 +15,10 @@ type NotificationData struct {
 	notificationLevel string
 }
 
-func (notification *NotificationData) sanitizeValues() {
-	notification.Data.sanitizeValues()
-	notification.notificationType = helper.SanitizeInfluxInput(notification.notificationType)
-	notification.notificationLevel = helper.SanitizeInfluxInput(notification.notificationLevel)
-}
-
-//PrintForInfluxDB prints the data in influxdb lineformat
-func (notification NotificationData) PrintForInfluxDB(version string) string {
-	notification.sanitizeValues()
-	if helper.VersionOrdinal(version) >= helper.VersionOrdinal("0.9") {
-		var tags string
-		if text := notificationToText(notification.notificationType); text != "" {
-			tags = ",type=" + text
-		}
-		value := fmt.Sprintf("%s:<br> %s", strings.TrimSpace(notification.notificationLevel), notification.comment)
-		return notification.genInfluxLineWithValue(tags, value)
-	}
-	logging.GetLogger().Criticalf("This influxversion [%f] given in the config is not supported", version)
-	panic("")
-}
-
-//PrintForElasticsearch prints in the elasticsearch json format
-func (notification NotificationData) PrintForElasticsearch(version, index string) string {
-	if helper.VersionOrdinal(version) >= helper.VersionOrdinal("2.0") {
-		text := notificationToText(notification.notificationType)
-		value := fmt.Sprintf("%s:<br> %s", strings.TrimSpace(notification.notificationLevel), notification.comment)
-		return notification.genElasticLineWithValue(index, text, value, notification.entryTime)
-	}
-	logging.GetLogger().Criticalf("This elasticsearchversion [%f] given in the config is not supported", version)
-	panic("")
+//Points returns the notification as message, prefixed by its level.
+func (notification NotificationData) Points() []collector.Point {
+	value := fmt.Sprintf("%s:<br> %s", strings.TrimSpace(notification.notificationLevel), notification.comment)
+	return []collector.Point{notification.genPoint(notificationToText(notification.notificationType), value, notification.entryTime)}
 }
 
 func notificationToText(input string) string {
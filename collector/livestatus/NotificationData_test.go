@@ -1,153 +1,78 @@
 package livestatus
 
 import (
-	"fmt"
-	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/collector"
 	"github.com/spitefulgrog/nagflux/logging"
+	"reflect"
 	"testing"
 )
 
-func TestSanitizeValuesNotification(t *testing.T) {
-	t.Parallel()
-	notification := NotificationData{Data: Data{hostName: "host 1"}, notificationType: "HOST NOTIFICATION", notificationLevel: "WARN"}
-	notification.sanitizeValues()
-
-	if notification.notificationType != `HOST\ NOTIFICATION` {
-		t.Errorf("The notificationType should be escaped. Expected: %s Got: %s", `HOST\ NOTIFICATION`, notification.notificationType)
-	}
-}
-
-func TestPrintNotification(t *testing.T) {
+func TestPointsNotification(t *testing.T) {
 	logging.InitTestLogger()
-	notification := NotificationData{Data: Data{hostName: "host 1", author: "philip"}, notificationType: "HOST NOTIFICATION", notificationLevel: "WARN"}
-	if !didThisPanic(notification.PrintForInfluxDB, "0.8") {
-		t.Error("Printed for unsuported influxdb version but got a response")
-	}
-
-	result := notification.PrintForInfluxDB("0.9")
-	if result != `messages,host=host\ 1,service=hostcheck,type=host_notification,author=philip message="WARN:<br> " 000` {
-		t.Errorf("Result does not match the expected. Result: %s", result)
-	}
-
-	notification2 := NotificationData{Data: Data{hostName: "host 1", serviceDisplayName: "service 1", author: "philip"}, notificationType: "SERVICE NOTIFICATION", notificationLevel: "WARN"}
-	result2 := notification2.PrintForInfluxDB("0.9")
-	if result2 != `messages,host=host\ 1,service=service\ 1,type=service_notification,author=philip message="WARN:<br> " 000` {
-		t.Errorf("Result does not match the expected. Result: %s", result2)
-	}
-
-	notification3 := NotificationData{Data: Data{hostName: "host 1", serviceDisplayName: "service 1", author: "philip"}, notificationType: "NULL NOTIFICATION", notificationLevel: "WARN"}
-	result3 := notification3.PrintForInfluxDB("0.9")
-	if result3 != `messages,host=host\ 1,service=service\ 1,author=philip message="WARN:<br> " 000` {
-		t.Errorf("Result does not match the expected. Result: %s", result3)
+	for _, data := range []struct {
+		input NotificationData
+		typ   string
+	}{
+		{NotificationData{Data: Data{hostName: "host 1", author: "philip"}, notificationType: "HOST NOTIFICATION", notificationLevel: "WARN"}, "host_notification"},
+		{NotificationData{Data: Data{hostName: "host 1", serviceDisplayName: "service 1", author: "philip"}, notificationType: "SERVICE NOTIFICATION", notificationLevel: "WARN"}, "service_notification"},
+		{NotificationData{Data: Data{hostName: "host 1", serviceDisplayName: "service 1", author: "philip"}, notificationType: "NULL NOTIFICATION", notificationLevel: "WARN"}, ""},
+	} {
+		points := data.input.Points()
+		if len(points) != 1 || points[0].Tags["type"] != data.typ || points[0].Fields["message"].Text != "WARN:<br> " {
+			t.Errorf("Unexpected points for %s: %v", data.input.notificationType, points)
+		}
 	}
 }
 
-const Config = `[main]
-	NagiosSpoolfileFolder = "/var/spool/nagios"
-	NagiosSpoolfileWorker = 1
-	InfluxWorker = 2
-	MaxInfluxWorker = 5
-	DumpFile = "nagflux.dump"
-	NagfluxSpoolfileFolder = "/var/spool/nagflux"
-	FieldSeparator = "&"
-	BufferSize = 10000
-	FileBufferSize = 65536
-
-[Log]
-	# leave empty for stdout
-	LogFile = ""
-	# List of Severities https://godoc.org/github.com/kdar/factorlog#Severity
-	MinSeverity = "INFO"
-
-[Monitoring]
-	# leave empty to disable
-	# PrometheusAddress = ":7000"
-	PrometheusAddress = ""
-
-[Livestatus]
-	# tcp or file
-	Type = "tcp"
-	# tcp: 127.0.0.1:6557 or file /var/run/live
-	Address = "127.0.0.1:6557"
-	MinutesToWait = 2
-
-[InfluxDBGlobal]
-	CreateDatabaseIfNotExists = true
-	NastyString = ""
-	NastyStringToReplace = ""
-	HostcheckAlias = "hostcheck"
-
-[InfluxDB "nagflux"]
-	Enabled = true
-	Version = 1.0
-	Address = "http://127.0.0.1:8086"
-	Arguments = "precision=ms&u=root&p=root&db=nagflux"
-	StopPullingDataIfDown = true
-
-[ElasticsearchGlobal]
-	HostcheckAlias = "hostcheck"
-	NumberOfShards = 1
-	NumberOfReplicas = 1
-	# Sorts the indices "monthly" or "yearly"
-	IndexRotation = "monthly"
-
-[Elasticsearch "example"]
-	Enabled = false
-	Address = "http://localhost:9200"
-	Index = "nagflux"
-	Version = 2.1`
-
-func TestPrintForElasticsearchNotification(t *testing.T) {
+func TestHandleQueryForNotifications(t *testing.T) {
 	logging.InitTestLogger()
-	config.InitConfigFromString(fmt.Sprintf(Config, "monthly"))
-	notification := NotificationData{Data: Data{hostName: "host 1", author: "philip", entryTime: "1458988932000"}, notificationType: "HOST NOTIFICATION", notificationLevel: "WARN"}
-	if !didThatPanic(notification.PrintForElasticsearch, "1.0", "index") {
-		t.Error("Printed for unsuported elasticsearch version but got a response")
-	}
-
-	result := notification.PrintForElasticsearch("2.0", "index")
-	expected := `{"index":{"_index":"index-2016.03","_type":"messages"}}
-{"timestamp":1458988932000000,"message":"WARN:<br> ","author":"philip","host":"host 1","service":"hostcheck","type":"host_notification"}
-`
-	if result != expected {
-		t.Errorf("Result does not match the expected.\n%s%s", result, expected)
-	}
-
-	notification2 := NotificationData{Data: Data{hostName: "host 1", serviceDisplayName: "service 1", author: "philip", entryTime: "1458988932000"}, notificationType: "SERVICE NOTIFICATION", notificationLevel: "WARN"}
-	result2 := notification2.PrintForElasticsearch("2.0", "index")
-	expected2 := `{"index":{"_index":"index-2016.03","_type":"messages"}}
-{"timestamp":1458988932000000,"message":"WARN:<br> ","author":"philip","host":"host 1","service":"service 1","type":"service_notification"}
-`
-	if result2 != expected2 {
-		t.Errorf("Result does not match the expected.\n%s%s", result2, expected2)
-	}
-
-	notification3 := NotificationData{Data: Data{hostName: "host 1", serviceDisplayName: "service 1", author: "philip", entryTime: "1458988932000"}, notificationType: "NULL NOTIFICATION", notificationLevel: "WARN"}
-	result3 := notification3.PrintForElasticsearch("2.0", "index")
-	expected3 := `{"index":{"_index":"index-2016.03","_type":"messages"}}
-{"timestamp":1458988932000000,"message":"WARN:<br> ","author":"philip","host":"host 1","service":"service 1","type":""}
-`
-	if result3 != expected3 {
-		t.Errorf("Result does not match the expected.\n%s%s", result3, expected3)
-	}
-}
-
-func didThisPanic(f func(string) string, arg string) (result bool) {
-	defer func() {
-		if rec := recover(); rec != nil {
-			result = true
+	live := Collector{log: logging.GetLogger(), site: "berlin"}
+	for _, test := range []struct {
+		entry    logRow
+		expected *NotificationData
+	}{
+		{
+			logRow{Type: "HOST NOTIFICATION", Time: "1458988932", HostName: "host 1", StateType: "DOWN", ContactName: "admin", PluginOutput: "PING CRITICAL",
+				Options: "admin;host 1;DOWN;notify-host;PING CRITICAL"},
+			&NotificationData{Data: Data{"host 1", "", "PING CRITICAL", "1458988932", "admin", "berlin"}, notificationType: "HOST NOTIFICATION", notificationLevel: "DOWN"},
+		},
+		{
+			logRow{Type: "SERVICE NOTIFICATION", Time: "1458988932", HostName: "host 1", ServiceDescription: "load", StateType: "ACKNOWLEDGEMENT (CRITICAL)",
+				ContactName: "admin", CommandName: "notify-service", PluginOutput: "CRITICAL - load", Comment: "on it; really",
+				Options: "admin;host 1;load;ACKNOWLEDGEMENT (CRITICAL);notify-service;CRITICAL - load;philip;on it; really"},
+			&NotificationData{Data: Data{"host 1", "load", "on it; really", "1458988932", "philip", "berlin"}, notificationType: "SERVICE NOTIFICATION", notificationLevel: "ACKNOWLEDGEMENT (CRITICAL)"},
+		},
+		{
+			logRow{Type: "SERVICE NOTIFICATION", Time: "1458988932", HostName: "host 1", ServiceDescription: "load", StateType: "ACKNOWLEDGEMENT (CRITICAL)",
+				ContactName: "admin", CommandName: "notify-service", PluginOutput: "CRITICAL - load",
+				Options: "admin;host 1;load;ACKNOWLEDGEMENT (CRITICAL);notify-service;CRITICAL - load;5;4;3;philip;on it"},
+			&NotificationData{Data: Data{"host 1", "load", "on it", "1458988932", "philip", "berlin"}, notificationType: "SERVICE NOTIFICATION", notificationLevel: "ACKNOWLEDGEMENT (CRITICAL)"},
+		},
+		{
+			logRow{Type: "HOST NOTIFICATION", Time: "1458988932", HostName: "host 1", StateType: "CUSTOM (DOWN)", ContactName: "admin", CommandName: "notify-host",
+				PluginOutput: "PING CRITICAL", Comment: "reboot; then check",
+				Options: "admin;host 1;CUSTOM (DOWN);notify-host;PING CRITICAL;rta=600;pl=100;philip;reboot; then check"},
+			&NotificationData{Data: Data{"host 1", "", "reboot; then check", "1458988932", "philip", "berlin"}, notificationType: "HOST NOTIFICATION", notificationLevel: "CUSTOM (DOWN)"},
+		},
+		{
+			logRow{Type: "SERVICE NOTIFICATION", Time: "1458988932", HostName: "host 1", ServiceDescription: "load", StateType: "CRITICAL",
+				ContactName: "admin", CommandName: "notify-service", PluginOutput: "CRITICAL - load",
+				Options: "admin;host 1;load;CRITICAL;notify-service;CRITICAL - load;5;4;3"},
+			&NotificationData{Data: Data{"host 1", "load", "CRITICAL - load", "1458988932", "admin", "berlin"}, notificationType: "SERVICE NOTIFICATION", notificationLevel: "CRITICAL"},
+		},
+		{
+			logRow{Type: "SERVICE NOTIFICATION", Time: "1458988932", HostName: "host 1", ServiceDescription: "load", StateType: "ACKNOWLEDGEMENT (CRITICAL)",
+				ContactName: "admin", CommandName: "notify-service", PluginOutput: "CRITICAL - load", Options: "CRITICAL - load;philip;on it"},
+			&NotificationData{Data: Data{"host 1", "load", "CRITICAL - load", "1458988932", "admin", "berlin"}, notificationType: "SERVICE NOTIFICATION", notificationLevel: "ACKNOWLEDGEMENT (CRITICAL)"},
+		},
+		{logRow{Type: "SERVICE NOTIFICATION SUPPRESSED", HostName: "host 1"}, nil},
+		{logRow{Type: "CONTACT NOTIFICATION", HostName: "host 1"}, nil},
+	} {
+		if test.expected != nil {
+			test.expected.Filterable = collector.RoutableFilterable
 		}
-	}()
-	f(arg)
-	return false
-}
-
-func didThatPanic(f func(string, string) string, arg1, arg2 string) (result bool) {
-	defer func() {
-		if rec := recover(); rec != nil {
-			result = true
+		if result := live.handleQueryForNotifications(test.entry); !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.entry.Type, test.expected, result)
 		}
-	}()
-	f(arg1, arg2)
-	return false
+	}
 }
@@ -0,0 +1,116 @@
+package livestatus
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+//Row is a line of a livestatus answer, the values are mapped by the name of their column.
+type Row map[string]json.RawMessage
+
+//Decode fills the fields of target by the json tags which match the column names. Columns which are not part of the
+//query stay empty.
+func (row Row) Decode(target interface{}) error {
+	object, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(object, target)
+}
+
+//Parses the answer to a query with OutputFormat: json and ColumnHeaders: on, the first line contains the column names.
+func readRows(body []byte) ([]Row, error) {
+	var table [][]json.RawMessage
+	if err := json.Unmarshal(body, &table); err != nil {
+		return nil, err
+	}
+	if len(table) == 0 {
+		return nil, nil
+	}
+	columns := make([]string, len(table[0]))
+	for i, column := range table[0] {
+		if err := json.Unmarshal(column, &columns[i]); err != nil {
+			return nil, fmt.Errorf("invalid column header %s: %s", column, err)
+		}
+	}
+	rows := make([]Row, 0, len(table)-1)
+	for _, line := range table[1:] {
+		if len(line) != len(columns) {
+			return nil, fmt.Errorf("the line has %d values for %d columns", len(line), len(columns))
+		}
+		row := make(Row, len(columns))
+		for i, column := range columns {
+			row[column] = line[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+//Returns a string as it is and other values like numbers or the lists of Icinga2 custom variables as json.
+func text(value json.RawMessage) string {
+	var result string
+	if err := json.Unmarshal(value, &result); err != nil {
+		return string(value)
+	}
+	return result
+}
+
+//statusRow contains the columns of QueryLivestatusVersion.
+type statusRow struct {
+	Version string `json:"livestatus_version"`
+}
+
+//logRow contains the columns of the log table, which are used by the queries for notifications, alerts, flapping and
+//acknowledgements. Each query asks just for the columns of its type.
+//The notifications of Nagios, Naemon, Checkmk and Icinga2 are parsed into the same columns by livestatus, the author
+//of custom notifications and acknowledgements is left in options and the comment is in its column just for some cores.
+type logRow struct {
+	Type               string      `json:"type"`
+	Time               json.Number `json:"time"`
+	HostName           string      `json:"host_name"`
+	ServiceDescription string      `json:"service_description"`
+	State              json.Number `json:"state"`
+	StateType          string      `json:"state_type"`
+	Attempt            json.Number `json:"attempt"`
+	ContactName        string      `json:"contact_name"`
+	CommandName        string      `json:"command_name"`
+	Comment            string      `json:"comment"`
+	PluginOutput       string      `json:"plugin_output"`
+	Options            string      `json:"options"`
+}
+
+//commentRow contains the columns of the comments and downtimes table.
+type commentRow struct {
+	HostName           string      `json:"host_name"`
+	ServiceDisplayName string      `json:"service_display_name"`
+	Comment            string      `json:"comment"`
+	EntryTime          json.Number `json:"entry_time"`
+	Author             string      `json:"author"`
+	EntryType          json.Number `json:"entry_type"`
+	EndTime            json.Number `json:"end_time"`
+}
+
+//downtimeRow contains the columns of the downtimes queries of the CacheBuilder. Hosts have a name, services a
+//host_name and display_name.
+type downtimeRow struct {
+	ID          json.Number   `json:"id"`
+	StartTime   json.Number   `json:"start_time"`
+	EntryTime   json.Number   `json:"entry_time"`
+	Downtimes   []json.Number `json:"downtimes"`
+	Name        string        `json:"name"`
+	HostName    string        `json:"host_name"`
+	DisplayName string        `json:"display_name"`
+}
+
+//metadataRow contains the columns of QueryForHostMetadata and QueryForServiceMetadata.
+type metadataRow struct {
+	Name                 string            `json:"name"`
+	HostName             string            `json:"host_name"`
+	Description          string            `json:"description"`
+	Groups               []string          `json:"groups"`
+	ContactGroups        []string          `json:"contact_groups"`
+	Address              string            `json:"address"`
+	CustomVariableNames  []string          `json:"custom_variable_names"`
+	CustomVariableValues []json.RawMessage `json:"custom_variable_values"`
+}
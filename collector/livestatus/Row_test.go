@@ -0,0 +1,45 @@
+package livestatus
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestReadRows(t *testing.T) {
+	rows, err := readRows([]byte(`[["type","time","host_name"],["HOST ALERT",1458988932,"host;1"],["SERVICE ALERT",1458988933,"host 2"]]` + "\n"))
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("Expected two rows, got %v %v", rows, err)
+	}
+	var entry logRow
+	if err := rows[0].Decode(&entry); err != nil {
+		t.Fatal(err)
+	}
+	if expected := (logRow{Type: "HOST ALERT", Time: "1458988932", HostName: "host;1"}); !reflect.DeepEqual(entry, expected) {
+		t.Errorf("Expected %v, got %v", expected, entry)
+	}
+
+	for _, body := range []string{"", "foo;bar\n", `[["name"],["host1","host2"]]`, `[[1],["host1"]]`} {
+		if _, err := readRows([]byte(body)); err == nil {
+			t.Errorf("%q: expected an error", body)
+		}
+	}
+	if rows, err := readRows([]byte("[]\n")); err != nil || len(rows) != 0 {
+		t.Errorf("Expected no rows, got %v %v", rows, err)
+	}
+	if rows, err := readRows([]byte(`[["name"]]`)); err != nil || len(rows) != 0 {
+		t.Errorf("Expected just the header, got %v %v", rows, err)
+	}
+	//the column has a different type
+	if err := (Row{"groups": json.RawMessage(`"linux"`)}).Decode(&metadataRow{}); err == nil {
+		t.Error("A string is no list")
+	}
+}
+
+func TestText(t *testing.T) {
+	for value, expected := range map[string]string{`"berlin"`: "berlin", `42`: "42", `["a","b"]`: `["a","b"]`, `{"a":1}`: `{"a":1}`} {
+		if result := text(json.RawMessage(value)); result != expected {
+			t.Errorf("%s: expected %s, got %s", value, expected, result)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package livestatus
+
+import (
+	"github.com/spitefulgrog/nagflux/logging"
+	"sort"
+	"sync"
+)
+
+//Sites are the caches of the livestatus sites by their name. An unnamed Livestatus section has the empty name.
+type Sites map[string]*CacheBuilder
+
+//Hosts which are known by several sites, they are logged once.
+var ambiguousHosts sync.Map
+
+//Find returns the name and the cache of the site which knows the host, nil if there is none. If site is given just
+//this one is asked, otherwise all of them. A host which is known by several sites can't be assigned without the site,
+//it's logged and nil is returned. A single site is returned anyway, its hosts may not be loaded yet.
+func (sites Sites) Find(host, site string) (string, *CacheBuilder) {
+	if site != "" {
+		if cache, ok := sites[site]; ok {
+			return site, cache
+		}
+		return "", nil
+	}
+	if len(sites) == 1 {
+		for name, cache := range sites {
+			return name, cache
+		}
+	}
+	names := make([]string, 0, len(sites))
+	for name := range sites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var found []string
+	for _, name := range names {
+		if sites[name].Knows(host) {
+			found = append(found, name)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", nil
+	case 1:
+		return found[0], sites[found[0]]
+	}
+	if _, logged := ambiguousHosts.LoadOrStore(host, true); !logged {
+		logging.GetLogger().Warnf("The host %s is known by the livestatus sites %v, it's not tagged unless the site is given by NAGFLUX:TAG site=...", host, found)
+	}
+	return "", nil
+}
+
+//Hostgroups returns the hostgroups of the host of the site which knows it.
+func (sites Sites) Hostgroups(host string) []string {
+	if _, cache := sites.Find(host, ""); cache != nil {
+		return cache.Hostgroups(host)
+	}
+	return nil
+}
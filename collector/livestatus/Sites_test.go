@@ -0,0 +1,61 @@
+package livestatus
+
+import (
+	"github.com/spitefulgrog/nagflux/logging"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func cacheWithHosts(downtimes map[string]map[string]string, hosts ...string) *CacheBuilder {
+	metadata := NewMetadataCache()
+	for _, host := range hosts {
+		metadata.addHost(metadataRow{Name: host, Groups: []string{"group-" + host}})
+	}
+	return &CacheBuilder{downtimeCache: Cache{downtimes}, metadataCache: metadata, mutex: &sync.Mutex{}}
+}
+
+func TestSitesFind(t *testing.T) {
+	logging.InitTestLogger()
+	berlin := cacheWithHosts(map[string]map[string]string{"web1": {"": "100"}}, "web1", "db1")
+	munich := cacheWithHosts(map[string]map[string]string{}, "web1", "mail1")
+	sites := Sites{"berlin": berlin, "munich": munich}
+
+	for _, test := range []struct {
+		host, site string
+		name       string
+		cache      *CacheBuilder
+	}{
+		{"db1", "", "berlin", berlin},
+		{"mail1", "", "munich", munich},
+		//the same host on both sites can't be assigned without a given site
+		{"web1", "", "", nil},
+		{"web1", "berlin", "berlin", berlin},
+		{"web1", "munich", "munich", munich},
+		{"unknown", "", "", nil},
+		{"db1", "hamburg", "", nil},
+	} {
+		if name, cache := sites.Find(test.host, test.site); name != test.name || cache != test.cache {
+			t.Errorf("%s/%s: expected %s, got %s", test.host, test.site, test.name, name)
+		}
+	}
+	//the downtime cache is per site
+	if _, cache := sites.Find("web1", "berlin"); !cache.IsServiceInDowntime("web1", "", "200") {
+		t.Error("web1 is in downtime on berlin")
+	}
+	if _, cache := sites.Find("web1", "munich"); cache.IsServiceInDowntime("web1", "", "200") {
+		t.Error("web1 is not in downtime on munich")
+	}
+	if groups := sites.Hostgroups("mail1"); !reflect.DeepEqual(groups, []string{"group-mail1"}) {
+		t.Errorf("Unexpected hostgroups: %v", groups)
+	}
+
+	//a single site is used even if the hosts are not loaded yet
+	single := Sites{"": cacheWithHosts(map[string]map[string]string{})}
+	if name, cache := single.Find("web1", ""); name != "" || cache == nil {
+		t.Errorf("The single site should be returned: %q %v", name, cache)
+	}
+	if _, cache := (Sites{}).Find("web1", ""); cache != nil {
+		t.Error("Without sites there is no cache")
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/spitefulgrog/nagflux/helper"
 	"github.com/spitefulgrog/nagflux/helper/crypto"
 	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/wal"
 	"github.com/kdar/factorlog"
 	"github.com/mikespook/gearman-go/worker"
 )
@@ -17,6 +18,7 @@ import (
 //GearmanWorker queries the gearmanserver and adds the extraced perfdata to the queue.
 type GearmanWorker struct {
 	quit                  chan bool
+	stopped               chan bool
 	results               collector.ResultQueues
 	nagiosSpoolfileWorker *spoolfile.NagiosSpoolfileWorker
 	aesECBDecrypter       *crypto.AESECBDecrypter
@@ -27,7 +29,7 @@ type GearmanWorker struct {
 
 //NewGearmanWorker generates a new GearmanWorker.
 //leave the key empty to disable encryption, otherwise the gearmanpacketes are expected to be encrpyten with AES-ECB 128Bit and a 32 Byte Key.
-func NewGearmanWorker(address, queue, key string, results collector.ResultQueues, livestatusCacheBuilder *livestatus.CacheBuilder) *GearmanWorker {
+func NewGearmanWorker(address, queue, key string, results collector.ResultQueues, livestatusSites livestatus.Sites) *GearmanWorker {
 	var decrypter *crypto.AESECBDecrypter
 	if key != "" {
 		byteKey := ShapeKey(key, DefaultModGearmanKeyLength)
@@ -39,9 +41,10 @@ func NewGearmanWorker(address, queue, key string, results collector.ResultQueues
 	}
 	worker := &GearmanWorker{
 		quit:    make(chan bool),
+		stopped: make(chan bool),
 		results: results,
 		nagiosSpoolfileWorker: spoolfile.NewNagiosSpoolfileWorker(
-			-1, make(chan string), make(collector.ResultQueues), livestatusCacheBuilder, 4096, collector.AllFilterable,
+			-1, make(chan string), make(collector.ResultQueues), livestatusSites, 4096, collector.RoutableFilterable,
 		),
 		aesECBDecrypter: decrypter,
 		worker:          createGearmanWorker(address),
@@ -80,6 +83,7 @@ func (g GearmanWorker) startGearmanWorker() error {
 
 //Stop stops the worker
 func (g GearmanWorker) Stop() {
+	close(g.stopped)
 	g.worker.Close()
 	g.quit <- true
 	<-g.quit
@@ -88,9 +92,18 @@ func (g GearmanWorker) Stop() {
 
 func (g GearmanWorker) run() {
 	for {
+		select {
+		case <-g.stopped:
+			return
+		default:
+		}
 		if err := g.startGearmanWorker(); err != nil {
 			g.log.Warn(err)
-			time.Sleep(time.Duration(30) * time.Second)
+			select {
+			case <-g.stopped:
+				return
+			case <-time.After(time.Duration(30) * time.Second):
+			}
 		} else {
 			return
 		}
@@ -100,7 +113,7 @@ func (g GearmanWorker) run() {
 func (g GearmanWorker) handleLoad() {
 	bufferLimit := int(float32(config.GetConfig().Main.BufferSize) * 0.90)
 	for {
-		for _, r := range g.results {
+		for _, r := range g.results.Snapshot() {
 			if len(r) > bufferLimit && g.worker != nil {
 				g.worker.Lock()
 				for len(r) > bufferLimit {
@@ -122,8 +135,7 @@ func (g GearmanWorker) handlePause() {
 	pause := false
 	for {
 		select {
-		case <-g.quit:
-			g.quit <- true
+		case <-g.stopped:
 			return
 		case <-time.After(time.Duration(1) * time.Second):
 			globalPause := config.IsAnyTargetOnPause()
@@ -152,13 +164,9 @@ func (g GearmanWorker) handelJob(job worker.Job) ([]byte, error) {
 	splittedPerformanceData := helper.StringToMap(string(secret), "\t", "::")
 	g.log.Debug("[ModGearman] ", string(job.Data()))
 	g.log.Debug("[ModGearman] ", splittedPerformanceData)
-	for singlePerfdata := range g.nagiosSpoolfileWorker.PerformanceDataIterator(splittedPerformanceData) {
-		for _, r := range g.results {
-			select {
-			case r <- singlePerfdata:
-			case <-time.After(time.Duration(1) * time.Minute):
-				logging.GetLogger().Warn("GearmanWorker: Could not write to buffer")
-			}
+	for singlePerfdata := range g.nagiosSpoolfileWorker.PerformanceDataIterator(splittedPerformanceData, nil) {
+		for target, r := range g.results.Snapshot() {
+			wal.Deliver(target, r, singlePerfdata, nil, time.Duration(1)*time.Minute)
 		}
 	}
 	return job.Data(), nil
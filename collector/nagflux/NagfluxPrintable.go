@@ -1,9 +1,10 @@
 package nagflux
 
 import (
-	"fmt"
 	"github.com/spitefulgrog/nagflux/collector"
-	"github.com/spitefulgrog/nagflux/helper"
+	"github.com/spitefulgrog/nagflux/data"
+	"strconv"
+	"strings"
 )
 
 //Printable converts from nagfluxfile format to X
@@ -12,34 +13,27 @@ type Printable struct {
 	Table     string
 	Timestamp string
 	tags      map[string]string
-	fields    map[string]string
+	fields    data.Fields
 }
 
-//PrintForInfluxDB prints the data in influxdb lineformat
-func (p Printable) PrintForInfluxDB(version string) string {
-	if helper.VersionOrdinal(version) >= helper.VersionOrdinal("0.9") {
-		line := p.Table
-		if len(p.tags) > 0 {
-			line += fmt.Sprintf(`,%s`, helper.PrintMapAsString(p.tags, ",", "="))
-		}
-		line += " "
-		if len(p.fields) > 0 {
-			line += fmt.Sprintf(`%s`, helper.PrintMapAsString(p.fields, ",", "="))
-		}
-		return fmt.Sprintf("%s %s", line, p.Timestamp)
-	}
-	return ""
-}
+//The tags in the files are escaped like in the line protocol, the points contain the plain values.
+var tagUnescaper = strings.NewReplacer(`\ `, ` `, `\,`, `,`, `\=`, `=`)
 
-//PrintForElasticsearch prints in the elasticsearch json format
-func (p Printable) PrintForElasticsearch(version, index string) string {
-	if helper.VersionOrdinal(version) >= helper.VersionOrdinal("2.0") {
-		head := fmt.Sprintf(`{"index":{"_index":"%s","_type":"%s"}}`, helper.GenIndex(index, p.Timestamp), p.Table) + "\n"
-		data := fmt.Sprintf(`{"timestamp":%s`, p.Timestamp)
-		data += helper.CreateJSONFromStringMap(p.tags)
-		data += helper.CreateJSONFromStringMap(p.fields)
-		data += "}\n"
-		return head + data
+//Points returns the line as point of the given table, the tags are taken as they are.
+func (p Printable) Points() []collector.Point {
+	timestamp, err := strconv.ParseInt(p.Timestamp, 10, 64)
+	if err != nil {
+		return nil
+	}
+	tags := make(map[string]string, len(p.tags))
+	for key, value := range p.tags {
+		tags[tagUnescaper.Replace(key)] = tagUnescaper.Replace(value)
 	}
-	return ""
+	return []collector.Point{{
+		Kind:        collector.CustomKind,
+		Measurement: p.Table,
+		Tags:        tags,
+		Fields:      p.fields,
+		Timestamp:   timestamp,
+	}}
 }
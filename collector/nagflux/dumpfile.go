@@ -0,0 +1,11 @@
+package nagflux
+
+import (
+	"fmt"
+	"github.com/spitefulgrog/nagflux/data"
+)
+
+//GenDumpfileName returns the name of an dumpfile
+func GenDumpfileName(filename string, ending data.Target) string {
+	return fmt.Sprintf("%s-%s.%s", filename, ending.Name, ending.Datatype)
+}
@@ -2,11 +2,15 @@ package nagflux
 
 import (
 	"encoding/csv"
+	"fmt"
 	"github.com/spitefulgrog/nagflux/collector"
 	"github.com/spitefulgrog/nagflux/collector/spoolfile"
 	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
 	"github.com/spitefulgrog/nagflux/helper"
 	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/quarantine"
+	"github.com/spitefulgrog/nagflux/wal"
 	"github.com/kdar/factorlog"
 	"os"
 	"time"
@@ -17,6 +21,7 @@ type FileCollector struct {
 	quit           chan bool
 	results        collector.ResultQueues
 	folder         string
+	watcher        *spoolfile.Watcher
 	log            *factorlog.FactorLog
 	fieldSeparator rune
 }
@@ -29,12 +34,13 @@ test&all&1489474756000&1.0&"""bar"""
 var requiredFields = []string{"table", "time"}
 var optionalFields = []string{"target"}
 
-//NewNagfluxFileCollector constructor, which also starts the collector.
-func NewNagfluxFileCollector(results collector.ResultQueues, folder string, fieldSeparator rune) *FileCollector {
+//NewNagfluxFileCollector constructor, which also starts the collector. watchMode is spoolfile.WatchPoll or spoolfile.WatchInotify.
+func NewNagfluxFileCollector(results collector.ResultQueues, folder, watchMode string, fieldSeparator rune) *FileCollector {
 	s := &FileCollector{
-		quit:           make(chan bool, 1),
+		quit:           make(chan bool),
 		results:        results,
 		folder:         folder,
+		watcher:        spoolfile.NewWatcher(folder, watchMode, spoolfile.MinFileAge),
 		log:            logging.GetLogger(),
 		fieldSeparator: fieldSeparator,
 	}
@@ -46,6 +52,7 @@ func NewNagfluxFileCollector(results collector.ResultQueues, folder string, fiel
 func (nfc *FileCollector) Stop() {
 	nfc.quit <- true
 	<-nfc.quit
+	nfc.watcher.Stop()
 	nfc.log.Debug("NagfluxFileCollector stoped")
 }
 
@@ -56,28 +63,27 @@ func (nfc FileCollector) run() {
 		case <-nfc.quit:
 			nfc.quit <- true
 			return
-		case <-time.After(spoolfile.IntervalToCheckDirectory):
+		case batch := <-nfc.watcher.Batches:
 			pause := config.IsAnyTargetOnPause()
 			if pause {
 				logging.GetLogger().Debugln("NagfluxFileCollector in pause")
 				continue
 			}
-			for _, currentFile := range spoolfile.FilesInDirectoryOlderThanX(nfc.folder, spoolfile.MinFileAge) {
+			for _, currentFile := range batch.Files() {
 				logging.GetLogger().Debug("Reading file: ", currentFile)
-				for _, p := range nfc.parseFile(currentFile) {
-					for _, r := range nfc.results {
-						select {
-						case <-nfc.quit:
+				printables, err := nfc.parseFile(currentFile)
+				if err != nil && quarantine.File("nagflux", currentFile, currentFile, err.Error()) {
+					continue
+				}
+				for _, p := range printables {
+					for target, r := range nfc.results.Snapshot() {
+						if !wal.Deliver(target, r, p, nfc.quit, time.Duration(1)*time.Minute) {
 							nfc.quit <- true
 							return
-						case r <- p:
-						case <-time.After(time.Duration(1) * time.Minute):
-							nfc.log.Warn("NagfluxFileCollector: Could not write to buffer")
 						}
 					}
 				}
-				err := os.Remove(currentFile)
-				if err != nil {
+				if err := os.Remove(currentFile); err != nil {
 					logging.GetLogger().Warn(err)
 				}
 			}
@@ -85,24 +91,28 @@ func (nfc FileCollector) run() {
 	}
 }
 
-func (nfc FileCollector) parseFile(filename string) []Printable {
+//Returns an error if the file is broken, it's quarantined then.
+func (nfc FileCollector) parseFile(filename string) ([]Printable, error) {
 	result := []Printable{}
-	csvfile, err := os.Open(filename)
-	if err != nil {
+	csvfile, err := spoolfile.OpenSpoolfile(filename)
+	if _, ok := err.(*os.PathError); ok {
 		nfc.log.Warn(err)
-		return result
+		return result, nil
+	} else if err != nil {
+		return result, err
 	}
 	defer csvfile.Close()
 	reader := csv.NewReader(csvfile)
 	reader.Comma = nfc.fieldSeparator
 	records, err := reader.ReadAll()
 	if err != nil {
-		nfc.log.Warn(err)
-		return result
+		return result, err
+	}
+	if len(records) == 0 {
+		return result, fmt.Errorf("the file is empty")
 	}
 	if !helper.Contains(records[0], requiredFields) {
-		nfc.log.Warnf("The file %s doesn't contain all of these fields: %s", filename, requiredFields)
-		return result
+		return result, fmt.Errorf("the header doesn't contain all of these fields: %s", requiredFields)
 	}
 
 	tagIndices := map[int]string{}
@@ -126,7 +136,7 @@ func (nfc FileCollector) parseFile(filename string) []Printable {
 		if i == 0 {
 			continue
 		}
-		currentPrintable := Printable{tags: map[string]string{}, fields: map[string]string{}}
+		currentPrintable := Printable{tags: map[string]string{}, fields: data.Fields{}}
 		for i, v := range r {
 			if v != "" {
 				if records[0][i] == requiredFields[0] {
@@ -138,7 +148,11 @@ func (nfc FileCollector) parseFile(filename string) []Printable {
 				} else if val, ok := tagIndices[i]; ok {
 					currentPrintable.tags[val] = v
 				} else if val, ok := fieldIndices[i]; ok {
-					currentPrintable.fields[val] = v
+					if field, ok := data.ParseFieldValue(v); ok {
+						currentPrintable.fields[val] = field
+					} else {
+						nfc.log.Warnf("Skipping the field %s, %s is not a finite number", val, v)
+					}
 				} else {
 					nfc.log.Warnf("This should not happen: %s->%s", records[0][i], v)
 				}
@@ -146,10 +160,10 @@ func (nfc FileCollector) parseFile(filename string) []Printable {
 		}
 
 		if currentPrintable.Filterable == collector.EmptyFilterable {
-			currentPrintable.Filterable = collector.AllFilterable
+			currentPrintable.Filterable = collector.RoutableFilterable
 		}
 
 		result = append(result, currentPrintable)
 	}
-	return result
+	return result, nil
 }
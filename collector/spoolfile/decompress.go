@@ -0,0 +1,81 @@
+package spoolfile
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"github.com/spitefulgrog/nagflux/statistics"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"os"
+	"time"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+//OpenSpoolfile opens the file and decompresses it while reading, if it's compressed by gzip or zstd.
+//The compression is detected by the magic number, so .gz and .zst files keep working after being claimed.
+func OpenSpoolfile(file string) (io.ReadCloser, error) {
+	handle, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	buffered := bufio.NewReader(handle)
+	magic, _ := buffered.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		reader, err := gzip.NewReader(buffered)
+		if err != nil {
+			handle.Close()
+			return nil, err
+		}
+		return &decompressor{format: "gzip", reader: reader, closers: []io.Closer{reader, handle}}, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		decoder, err := zstd.NewReader(buffered, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			handle.Close()
+			return nil, err
+		}
+		reader := decoder.IOReadCloser()
+		return &decompressor{format: "zstd", reader: reader, closers: []io.Closer{reader, handle}}, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{buffered, handle}, nil
+}
+
+//decompressor measures the decompressed bytes and the time it took, the metrics are updated on close.
+type decompressor struct {
+	format   string
+	reader   io.Reader
+	closers  []io.Closer
+	bytes    int64
+	duration time.Duration
+}
+
+func (d *decompressor) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := d.reader.Read(p)
+	d.duration += time.Since(start)
+	d.bytes += int64(n)
+	return n, err
+}
+
+func (d *decompressor) Close() error {
+	var result error
+	for _, closer := range d.closers {
+		if err := closer.Close(); err != nil && result == nil {
+			result = err
+		}
+	}
+	promServer := statistics.GetPrometheusServer()
+	if promServer.SpoolFilesDecompressedBytes != nil {
+		promServer.SpoolFilesDecompressedBytes.WithLabelValues(d.format).Add(float64(d.bytes))
+		promServer.SpoolFilesDecompressionSeconds.WithLabelValues(d.format).Add(d.duration.Seconds())
+	}
+	return result
+}
@@ -0,0 +1,86 @@
+package spoolfile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func gzipped(content string) []byte {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	writer.Write([]byte(content))
+	writer.Close()
+	return buffer.Bytes()
+}
+
+func zstded(content string) []byte {
+	encoder, _ := zstd.NewWriter(nil)
+	defer encoder.Close()
+	return encoder.EncodeAll([]byte(content), nil)
+}
+
+func TestOpenSpoolfile(t *testing.T) {
+	content := "DATATYPE::HOSTPERFDATA\tHOSTNAME::a\nDATATYPE::HOSTPERFDATA\tHOSTNAME::b\n"
+	folder := t.TempDir()
+	for name, raw := range map[string][]byte{"plain": []byte(content), "file.gz": gzipped(content), "file.zst": zstded(content),
+		//the extension does not matter
+		"gzip.processing": gzipped(content), "empty": nil} {
+		file := path.Join(folder, name)
+		if err := ioutil.WriteFile(file, raw, 0644); err != nil {
+			t.Fatal(err)
+		}
+		reader, err := OpenSpoolfile(file)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		read, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+		expected := content
+		if name == "empty" {
+			expected = ""
+		}
+		if string(read) != expected {
+			t.Errorf("%s: expected %q, got %q", name, expected, read)
+		}
+		if err := reader.Close(); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+	}
+}
+
+func TestOpenSpoolfileErrors(t *testing.T) {
+	folder := t.TempDir()
+	if _, err := OpenSpoolfile(path.Join(folder, "missing")); err == nil {
+		t.Error("Expected an error")
+	} else if _, ok := err.(*os.PathError); !ok {
+		t.Errorf("Expected a PathError, got %T", err)
+	}
+
+	broken := path.Join(folder, "broken.gz")
+	ioutil.WriteFile(broken, []byte{0x1f, 0x8b, 0x01}, 0644)
+	if _, err := OpenSpoolfile(broken); err == nil {
+		t.Error("Expected an error for a truncated gzip header")
+	} else if _, ok := err.(*os.PathError); ok {
+		t.Error("A broken file must not look like a missing one")
+	}
+
+	corrupt := zstded("DATATYPE::HOSTPERFDATA\tHOSTNAME::a\n")
+	corrupt[len(corrupt)-2] ^= 0xff
+	ioutil.WriteFile(path.Join(folder, "corrupt.zst"), corrupt, 0644)
+	reader, err := OpenSpoolfile(path.Join(folder, "corrupt.zst"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if _, err := ioutil.ReadAll(reader); err == nil {
+		t.Error("Expected an error for corrupt data")
+	}
+}
@@ -0,0 +1,67 @@
+package spoolfile
+
+import (
+	"github.com/spitefulgrog/nagflux/logging"
+	"os"
+	"strings"
+	"sync"
+)
+
+//ProcessingSuffix is appended to the spoolfiles which are handed to a worker. Files with it which are not in flight
+//are left from a crash or a stopped worker, they are read again.
+const ProcessingSuffix = ".processing"
+
+//InFlight tracks the spoolfiles which are handed to the workers, so no file is passed twice.
+type InFlight struct {
+	mutex *sync.Mutex
+	files map[string]bool
+}
+
+//NewInFlight creates an empty tracker.
+func NewInFlight() *InFlight {
+	return &InFlight{mutex: &sync.Mutex{}, files: map[string]bool{}}
+}
+
+//Claim renames the file to file.processing and returns the new name. Returns false if the file is in flight already
+//or it's gone meanwhile. Files with the suffix are taken as they are, if they are not in flight.
+func (f *InFlight) Claim(file string) (string, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if strings.HasSuffix(file, ProcessingSuffix) {
+		if f.files[file] {
+			return "", false
+		}
+		if _, err := os.Stat(file); err != nil {
+			return "", false
+		}
+		logging.GetLogger().Info("Reading unfinished spoolfile again: ", file)
+		f.files[file] = true
+		return file, true
+	}
+	processing := file + ProcessingSuffix
+	if err := os.Rename(file, processing); err != nil {
+		if !os.IsNotExist(err) {
+			logging.GetLogger().Warn("Could not claim spoolfile: ", err)
+		}
+		return "", false
+	}
+	f.files[processing] = true
+	return processing, true
+}
+
+//Done removes the claimed file, it's not in flight anymore.
+func (f *InFlight) Done(processing string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := os.Remove(processing); err != nil {
+		logging.GetLogger().Warn(err)
+	}
+	delete(f.files, processing)
+}
+
+//Release forgets the claimed file without removing it, so it's claimed again by the next scan.
+func (f *InFlight) Release(processing string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.files, processing)
+}
@@ -0,0 +1,112 @@
+package spoolfile
+
+import (
+	"fmt"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/statistics"
+	"io/ioutil"
+	"os"
+	"path"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInFlight(t *testing.T) {
+	logging.InitTestLogger()
+	folder := t.TempDir()
+	file := path.Join(folder, "a")
+	writeSpoolfile(t, file, 0)
+	f := NewInFlight()
+
+	processing, ok := f.Claim(file)
+	if !ok || processing != file+ProcessingSuffix {
+		t.Fatalf("Could not claim the file: %s %t", processing, ok)
+	}
+	if _, err := os.Stat(processing); err != nil {
+		t.Error("The file was not renamed: ", err)
+	}
+	if _, ok := f.Claim(file); ok {
+		t.Error("The file is gone, it can't be claimed again")
+	}
+	if _, ok := f.Claim(processing); ok {
+		t.Error("A file in flight must not be claimed twice")
+	}
+
+	//a stopped worker leaves the file, it's claimed by the next scan
+	f.Release(processing)
+	if again, ok := f.Claim(processing); !ok || again != processing {
+		t.Errorf("The released file should be claimed again: %s %t", again, ok)
+	}
+	f.Done(processing)
+	if _, err := os.Stat(processing); !os.IsNotExist(err) {
+		t.Error("The file should be removed: ", err)
+	}
+	if _, ok := f.Claim(processing); ok {
+		t.Error("A removed file can't be claimed")
+	}
+}
+
+var prometheusOnce sync.Once
+
+//Every file is read once, the unfinished one of the last run too.
+func TestNagiosSpoolfileCollectorReadsEveryFileOnce(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("The test uses inotify to start right away")
+	}
+	logging.InitTestLogger()
+	prometheusOnce.Do(func() { statistics.NewPrometheusServer("") })
+	config.InitConfigFromString("[main]")
+	folder := t.TempDir()
+	line := "DATATYPE::HOSTPERFDATA\tTIMET::1000\tHOSTNAME::host%d\tHOSTPERFDATA::rta=1\tHOSTCHECKCOMMAND::check-host-alive\n"
+	for i := 0; i < 20; i++ {
+		name := path.Join(folder, fmt.Sprint("file", i))
+		if i == 0 {
+			name += ProcessingSuffix
+		}
+		raw := []byte(fmt.Sprintf(line, i))
+		//compressed files are read like the others
+		if i == 1 {
+			raw = gzipped(string(raw))
+		} else if i == 2 {
+			raw = zstded(string(raw))
+		}
+		if err := ioutil.WriteFile(name, raw, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	queue := make(chan collector.Printable, 100)
+	results := collector.ResultQueues{}
+	results.Set(data.Target{Name: "test", Datatype: data.InfluxDB}, queue)
+
+	s := NagiosSpoolfileCollectorFactory(folder, WatchInotify, 4, results, nil, 4096, 0, collector.RoutableFilterable)
+	hosts := map[string]int{}
+	timeout := time.After(time.Duration(5) * time.Second)
+	for len(hosts) < 20 {
+		select {
+		case printable := <-queue:
+			hosts[printable.Points()[0].Tags["host"]]++
+		case <-timeout:
+			t.Fatalf("Got just %d hosts", len(hosts))
+		}
+	}
+	//a rescan must not pass the files again
+	s.watcher.Batches <- Batch{watcher: s.watcher, Rescan: true}
+	time.Sleep(time.Duration(200) * time.Millisecond)
+	s.Stop()
+	for len(queue) > 0 {
+		hosts[(<-queue).Points()[0].Tags["host"]]++
+	}
+	for host, count := range hosts {
+		if count != 1 {
+			t.Errorf("%s was read %d times", host, count)
+		}
+	}
+	if files, _ := ioutil.ReadDir(folder); len(files) != 0 {
+		t.Errorf("%d files are left", len(files))
+	}
+}
@@ -0,0 +1,58 @@
+//go:build linux
+// +build linux
+
+package spoolfile
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+//inotify reports the files which got closed after writing or moved into the folder.
+type inotify struct {
+	file   *os.File
+	buffer []byte
+}
+
+func newInotify(folder string) (*inotify, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := syscall.InotifyAddWatch(fd, folder, syscall.IN_CLOSE_WRITE|syscall.IN_MOVED_TO); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	//the fd is non blocking, so the runtime poller is used and close interrupts a pending read
+	return &inotify{file: os.NewFile(uintptr(fd), "inotify:"+folder), buffer: make([]byte, 64*1024)}, nil
+}
+
+//read waits for events and returns the names of the files. overflow is set if the kernel dropped events.
+func (i *inotify) read() (names []string, overflow bool, err error) {
+	n, err := i.file.Read(i.buffer)
+	if err != nil {
+		return nil, false, err
+	}
+	for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+		event := (*syscall.InotifyEvent)(unsafe.Pointer(&i.buffer[offset]))
+		nameStart := offset + syscall.SizeofInotifyEvent
+		offset = nameStart + int(event.Len)
+		switch {
+		case event.Mask&syscall.IN_Q_OVERFLOW != 0:
+			overflow = true
+		case event.Mask&syscall.IN_IGNORED != 0:
+			return nil, false, fmt.Errorf("the folder is not watched anymore")
+		case event.Mask&syscall.IN_ISDIR != 0 || event.Len == 0 || offset > n:
+		default:
+			names = append(names, string(bytes.TrimRight(i.buffer[nameStart:offset], "\x00")))
+		}
+	}
+	return names, overflow, nil
+}
+
+func (i *inotify) close() error {
+	return i.file.Close()
+}
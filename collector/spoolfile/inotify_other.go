@@ -0,0 +1,24 @@
+//go:build !linux
+// +build !linux
+
+package spoolfile
+
+import (
+	"errors"
+	"runtime"
+)
+
+//inotify is just available on linux, the watcher polls on the other platforms.
+type inotify struct{}
+
+func newInotify(folder string) (*inotify, error) {
+	return nil, errors.New("inotify is not supported on " + runtime.GOOS)
+}
+
+func (i *inotify) read() ([]string, bool, error) {
+	return nil, false, errors.New("inotify is not supported on " + runtime.GOOS)
+}
+
+func (i *inotify) close() error {
+	return nil
+}
@@ -0,0 +1,73 @@
+package spoolfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+//DefaultMaxLineSize is used if MaxLineSize is not set, in bytes.
+const DefaultMaxLineSize = 1048576
+
+//LineTooLongError is returned for a line which is longer than the max line size.
+type LineTooLongError struct {
+	Length  int
+	MaxSize int
+}
+
+func (e *LineTooLongError) Error() string {
+	return fmt.Sprintf("the line has %d bytes, more than MaxLineSize %d, it is cut off", e.Length, e.MaxSize)
+}
+
+//LineReader reads lines of any length, the size of the buffer does not limit them.
+type LineReader struct {
+	reader      *bufio.Reader
+	maxLineSize int
+	number      int
+}
+
+//NewLineReader returns a LineReader which reads with a buffer of bufferSize, maxLineSize <= 0 is DefaultMaxLineSize.
+func NewLineReader(reader io.Reader, bufferSize, maxLineSize int) *LineReader {
+	if maxLineSize <= 0 {
+		maxLineSize = DefaultMaxLineSize
+	}
+	return &LineReader{reader: bufio.NewReaderSize(reader, bufferSize), maxLineSize: maxLineSize}
+}
+
+//Next returns the next line without the line ending and its number, starting with 1, and io.EOF after the last one.
+//A line longer than the max line size is returned cut off with a *LineTooLongError, the reader goes on with the next line.
+func (r *LineReader) Next() ([]byte, int, error) {
+	var line []byte
+	length := 0
+	for {
+		chunk, err := r.reader.ReadSlice('\n')
+		length += len(chunk)
+		//the rest of an oversize line is just skipped
+		if free := r.maxLineSize + 1 - len(line); free > 0 {
+			if len(chunk) > free {
+				line = append(line, chunk[:free]...)
+			} else {
+				line = append(line, chunk...)
+			}
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil && (err != io.EOF || length == 0) {
+			return nil, r.number, err
+		}
+		r.number++
+		line = trimLineEnding(line)
+		//the line ending does not count
+		length -= len(chunk) - len(trimLineEnding(chunk))
+		if length > r.maxLineSize {
+			return line[:r.maxLineSize], r.number, &LineTooLongError{Length: length, MaxSize: r.maxLineSize}
+		}
+		return line, r.number, nil
+	}
+}
+
+func trimLineEnding(line []byte) []byte {
+	return bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r"))
+}
@@ -0,0 +1,118 @@
+package spoolfile
+
+import (
+	"fmt"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/quarantine"
+	"github.com/spitefulgrog/nagflux/statistics"
+	"github.com/prometheus/client_model/go"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLineReader(t *testing.T) {
+	long := strings.Repeat("x", 40)
+	input := "a\r\n" + long + "\n" + strings.Repeat("y", 20) + "\r\n\nlast"
+	//the buffer is smaller than most lines
+	reader := NewLineReader(strings.NewReader(input), 16, 20)
+	expected := []struct {
+		line   string
+		number int
+		length int
+	}{
+		{"a", 1, 0},
+		{long[:20], 2, 40},
+		{strings.Repeat("y", 20), 3, 0},
+		{"", 4, 0},
+		{"last", 5, 0},
+	}
+	for _, e := range expected {
+		line, number, err := reader.Next()
+		if string(line) != e.line || number != e.number {
+			t.Errorf("Expected line %d %q, got %d %q", e.number, e.line, number, line)
+		}
+		if e.length == 0 && err != nil {
+			t.Errorf("Line %d: %s", e.number, err)
+		} else if e.length != 0 {
+			if tooLong, ok := err.(*LineTooLongError); !ok || tooLong.Length != e.length {
+				t.Errorf("Line %d: expected a LineTooLongError of %d bytes, got %v", e.number, e.length, err)
+			}
+		}
+	}
+	if _, _, err := reader.Next(); err != io.EOF {
+		t.Errorf("Expected EOF, got %v", err)
+	}
+
+	if line, _, err := NewLineReader(strings.NewReader(strings.Repeat("z", DefaultMaxLineSize)), 16, 0).Next(); err != nil || len(line) != DefaultMaxLineSize {
+		t.Errorf("The default max size should fit: %d %v", len(line), err)
+	}
+}
+
+//A line which is too long is skipped, the others are processed.
+func TestNagiosSpoolfileWorkerSkipsLongLines(t *testing.T) {
+	logging.InitTestLogger()
+	prometheusOnce.Do(func() { statistics.NewPrometheusServer("") })
+	config.InitConfigFromString("[main]")
+	folder := t.TempDir()
+	quarantineFolder := t.TempDir()
+	cfg := config.Config{}
+	cfg.Main.QuarantineFolder = quarantineFolder
+	quarantine.Configure(cfg)
+	defer quarantine.Configure(config.Config{})
+
+	line := "DATATYPE::HOSTPERFDATA\tTIMET::1000\tHOSTNAME::host%d\tHOSTPERFDATA::rta=1\tHOSTCHECKCOMMAND::check-host-alive\n"
+	content := fmt.Sprintf(line, 1) + fmt.Sprintf(line, 2)[:50] + strings.Repeat("x", 200) + "\n" + fmt.Sprintf(line, 3)
+	file := path.Join(folder, "file")
+	ioutil.WriteFile(file, []byte(content), 0644)
+
+	metric := &io_prometheus_client.Metric{}
+	statistics.GetPrometheusServer().SpoolFilesSkippedLines.Write(metric)
+	skippedBefore := metric.GetCounter().GetValue()
+
+	queue := make(chan collector.Printable, 10)
+	results := collector.ResultQueues{}
+	results.Set(data.Target{Name: "test", Datatype: data.InfluxDB}, queue)
+	jobs := make(chan string, 1)
+	w := NagiosSpoolfileWorkerGenerator(jobs, results, nil, 64, 150, collector.RoutableFilterable, nil)()
+	jobs <- file
+
+	hosts := []string{}
+	for len(hosts) < 2 {
+		select {
+		case printable := <-queue:
+			hosts = append(hosts, printable.Points()[0].Tags["host"])
+		case <-time.After(time.Duration(5) * time.Second):
+			t.Fatalf("Got just %v", hosts)
+		}
+	}
+	w.Stop()
+	if hosts[0] != "host1" || hosts[1] != "host3" || len(queue) != 0 {
+		t.Errorf("Unexpected hosts: %v, %d left", hosts, len(queue))
+	}
+
+	statistics.GetPrometheusServer().SpoolFilesSkippedLines.Write(metric)
+	if skipped := metric.GetCounter().GetValue() - skippedBefore; skipped != 1 {
+		t.Errorf("Expected one skipped line, got %f", skipped)
+	}
+	files, _ := ioutil.ReadDir(quarantineFolder)
+	for _, f := range files {
+		raw, _ := ioutil.ReadFile(path.Join(quarantineFolder, f.Name()))
+		if strings.HasSuffix(f.Name(), quarantine.ReasonSuffix) {
+			if !strings.HasPrefix(string(raw), "line 2: the line has 250 bytes") {
+				t.Errorf("Unexpected reason: %q", raw)
+			}
+		} else if len(raw) != 151 {
+			t.Errorf("The line should be cut off at 150 bytes, got %d", len(raw)-1)
+		}
+	}
+	if len(files) != 2 {
+		t.Errorf("Expected the line and its reason, got %d files", len(files))
+	}
+}
@@ -23,20 +23,25 @@ type NagiosSpoolfileCollector struct {
 	quit           chan bool
 	jobs           chan string
 	spoolDirectory string
+	watcher        *Watcher
+	inFlight       *InFlight
 	workers        []*NagiosSpoolfileWorker
 }
 
 //NagiosSpoolfileCollectorFactory creates the give amount of Woker and starts them.
-func NagiosSpoolfileCollectorFactory(spoolDirectory string, workerAmount int, results collector.ResultQueues,
-	livestatusCacheBuilder *livestatus.CacheBuilder, fileBufferSize int, defaultTarget collector.Filterable) *NagiosSpoolfileCollector {
+//watchMode is WatchPoll or WatchInotify.
+func NagiosSpoolfileCollectorFactory(spoolDirectory, watchMode string, workerAmount int, results collector.ResultQueues,
+	livestatusSites livestatus.Sites, fileBufferSize, maxLineSize int, defaultTarget collector.Filterable) *NagiosSpoolfileCollector {
 	s := &NagiosSpoolfileCollector{
 		quit:           make(chan bool),
 		jobs:           make(chan string, 100),
 		spoolDirectory: spoolDirectory,
+		watcher:        NewWatcher(spoolDirectory, watchMode, 0),
+		inFlight:       NewInFlight(),
 		workers:        make([]*NagiosSpoolfileWorker, workerAmount),
 	}
 
-	gen := NagiosSpoolfileWorkerGenerator(s.jobs, results, livestatusCacheBuilder, fileBufferSize, defaultTarget)
+	gen := NagiosSpoolfileWorkerGenerator(s.jobs, results, livestatusSites, fileBufferSize, maxLineSize, defaultTarget, s.inFlight)
 
 	for w := 0; w < workerAmount; w++ {
 		s.workers[w] = gen()
@@ -50,6 +55,7 @@ func NagiosSpoolfileCollectorFactory(spoolDirectory string, workerAmount int, re
 func (s *NagiosSpoolfileCollector) Stop() {
 	s.quit <- true
 	<-s.quit
+	s.watcher.Stop()
 	for _, worker := range s.workers {
 		worker.Stop()
 	}
@@ -64,24 +70,32 @@ func (s *NagiosSpoolfileCollector) run() {
 		case <-s.quit:
 			s.quit <- true
 			return
-		case <-time.After(IntervalToCheckDirectory):
+		case batch := <-s.watcher.Batches:
 			pause := config.IsAnyTargetOnPause()
 			if pause {
+				//the files are passed again by the next rescan
 				logging.GetLogger().Debugln("NagiosSpoolfileCollector in pause")
 				continue
 			}
 
-			logging.GetLogger().Debug("Reading Directory: ", s.spoolDirectory)
-			files, _ := ioutil.ReadDir(s.spoolDirectory)
-			promServer.SpoolFilesOnDisk.Set(float64(len(files)))
+			files := batch.Files()
+			if batch.Rescan {
+				promServer.SpoolFilesOnDisk.Set(float64(len(files)))
+			}
 			for _, currentFile := range files {
+				claimed, ok := s.inFlight.Claim(currentFile)
+				if !ok {
+					continue
+				}
 				select {
 				case <-s.quit:
+					//the claimed file is read again after the restart
 					s.quit <- true
 					return
-				case s.jobs <- path.Join(s.spoolDirectory, currentFile.Name()):
+				case s.jobs <- claimed:
 				case <-time.After(time.Duration(1) * time.Minute):
 					logging.GetLogger().Warn("NagiosSpoolfileCollector: Could not write to buffer")
+					s.inFlight.Release(claimed)
 				}
 			}
 		}
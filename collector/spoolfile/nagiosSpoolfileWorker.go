@@ -1,13 +1,14 @@
 package spoolfile
 
 import (
-	"bufio"
-	"errors"
 	"fmt"
 	"github.com/spitefulgrog/nagflux/collector"
 	"github.com/spitefulgrog/nagflux/collector/livestatus"
+	"github.com/spitefulgrog/nagflux/data"
 	"github.com/spitefulgrog/nagflux/helper"
 	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/quarantine"
+	"github.com/spitefulgrog/nagflux/wal"
 	"github.com/spitefulgrog/nagflux/statistics"
 	"io"
 	"os"
@@ -36,10 +37,8 @@ const (
 )
 
 var (
-	checkMulitRegex       = regexp.MustCompile(`^(.*::)(.*)`)
-	rangeRegex            = regexp.MustCompile(`[\d\.\-]+`)
-	regexPerformancelable = regexp.MustCompile(`([^=]+)=(U|[\d\.,\-]+)([\pL\/%]*);?([\d\.,\-:~@]+)?;?([\d\.,\-:~@]+)?;?([\d\.,\-]+)?;?([\d\.,\-]+)?;?\s*`)
-	regexAltCommand       = regexp.MustCompile(`.*\[(.*)\]\s?$`)
+	checkMulitRegex = regexp.MustCompile(`^(.*::)(.*)`)
+	regexAltCommand = regexp.MustCompile(`.*\[(.*)\]\s?$`)
 )
 
 //NagiosSpoolfileWorker parses the given spoolfiles and adds the extraced perfdata to the queue.
@@ -48,31 +47,36 @@ type NagiosSpoolfileWorker struct {
 	quit                   chan bool
 	jobs                   chan string
 	results                collector.ResultQueues
-	livestatusCacheBuilder *livestatus.CacheBuilder
+	livestatusCachesBySite livestatus.Sites
 	fileBufferSize         int
 	defaultTarget          collector.Filterable
+	inFlight               *InFlight
+	maxLineSize            int
 }
 
 //NewNagiosSpoolfileWorker returns a new NagiosSpoolfileWorker.
 func NewNagiosSpoolfileWorker(workerID int, jobs chan string, results collector.ResultQueues,
-	livestatusCacheBuilder *livestatus.CacheBuilder, fileBufferSize int, defaultTarget collector.Filterable) *NagiosSpoolfileWorker {
+	livestatusSites livestatus.Sites, fileBufferSize int, defaultTarget collector.Filterable) *NagiosSpoolfileWorker {
 	return &NagiosSpoolfileWorker{
 		workerID:               workerID,
 		quit:                   make(chan bool),
 		jobs:                   jobs,
 		results:                results,
-		livestatusCacheBuilder: livestatusCacheBuilder,
+		livestatusCachesBySite: livestatusSites,
 		fileBufferSize:         fileBufferSize,
 		defaultTarget:          defaultTarget,
 	}
 }
 
 //NagiosSpoolfileWorkerGenerator generates a worker and starts it.
+//The workers report the finished files to inFlight, longer lines than maxLineSize are skipped.
 func NagiosSpoolfileWorkerGenerator(jobs chan string, results collector.ResultQueues,
-	livestatusCacheBuilder *livestatus.CacheBuilder, fileBufferSize int, defaultTarget collector.Filterable) func() *NagiosSpoolfileWorker {
+	livestatusSites livestatus.Sites, fileBufferSize, maxLineSize int, defaultTarget collector.Filterable, inFlight *InFlight) func() *NagiosSpoolfileWorker {
 	workerID := 0
 	return func() *NagiosSpoolfileWorker {
-		s := NewNagiosSpoolfileWorker(workerID, jobs, results, livestatusCacheBuilder, fileBufferSize, defaultTarget)
+		s := NewNagiosSpoolfileWorker(workerID, jobs, results, livestatusSites, fileBufferSize, defaultTarget)
+		s.inFlight = inFlight
+		s.maxLineSize = maxLineSize
 		workerID++
 		go s.run()
 		return s
@@ -99,41 +103,52 @@ func (w *NagiosSpoolfileWorker) run() {
 			promServer.SpoolFilesInQueue.Set(float64(len(w.jobs)))
 			startTime := time.Now()
 			logging.GetLogger().Debug("Reading file: ", file)
-			filehandle, err := os.OpenFile(file, os.O_RDONLY, os.ModePerm)
-			if err != nil {
+			filehandle, err := OpenSpoolfile(file)
+			if _, ok := err.(*os.PathError); ok {
 				logging.GetLogger().Warn("NagiosSpoolfileWorker: Opening file error: ", err)
+				w.release(file)
+				break
+			} else if err != nil {
+				//the header of the compression is broken
+				w.quarantine(file, err)
 				break
 			}
-			reader := bufio.NewReaderSize(filehandle, w.fileBufferSize)
+			reader := NewLineReader(filehandle, w.fileBufferSize, w.maxLineSize)
 			queries := 0
-			line, isPrefix, err := reader.ReadLine()
-			for err == nil && !isPrefix {
+			var broken []quarantine.Line
+			line, lineNumber, err := reader.Next()
+			for ; err == nil || isLineTooLong(err); line, lineNumber, err = reader.Next() {
+				if err != nil {
+					logging.GetLogger().Warnf("NagiosSpoolfileWorker: skipping line %d of %s: %s", lineNumber, file, err)
+					promServer.SpoolFilesSkippedLines.Inc()
+					broken = append(broken, quarantine.Line{Number: lineNumber, Text: string(line), Reason: err.Error()})
+					continue
+				}
 				splittedPerformanceData := helper.StringToMap(string(line), "\t", "::")
-				for singlePerfdata := range w.PerformanceDataIterator(splittedPerformanceData) {
-					for _, r := range w.results {
-						select {
-						case <-w.quit:
+				if len(splittedPerformanceData) > 1 && findType(splittedPerformanceData) == "" {
+					broken = append(broken, quarantine.Line{Number: lineNumber, Text: string(line), Reason: "the line does not match the scheme"})
+				}
+				results := w.results.Snapshot()
+				done := make(chan struct{})
+				for singlePerfdata := range w.PerformanceDataIterator(splittedPerformanceData, done) {
+					for target, r := range results {
+						if !wal.Deliver(target, r, singlePerfdata, w.quit, time.Duration(10)*time.Second) {
+							close(done)
+							filehandle.Close()
 							w.quit <- true
 							return
-						case r <- singlePerfdata:
-							queries++
-						case <-time.After(time.Duration(10) * time.Second):
-							logging.GetLogger().Warn("NagiosSpoolfileWorker: Could not write to buffer")
 						}
+						queries++
 					}
 				}
-				line, isPrefix, err = reader.ReadLine()
-			}
-			if err != nil && err != io.EOF {
-				logging.GetLogger().Warn(err)
-			}
-			if isPrefix {
-				logging.GetLogger().Warn("NagiosSpoolfileWorker: filebuffer is too small")
 			}
 			filehandle.Close()
-			err = os.Remove(file)
-			if err != nil {
-				logging.GetLogger().Warn(err)
+			quarantine.Lines("spoolfile", strings.TrimSuffix(file, ProcessingSuffix), broken)
+			if err != nil && err != io.EOF {
+				//e.g. the compressed data is corrupt
+				w.quarantine(file, err)
+			} else {
+				w.done(file)
 			}
 			timeDiff := float64(time.Since(startTime).Nanoseconds() / 1000000)
 			if timeDiff >= 0 {
@@ -149,8 +164,38 @@ func (w *NagiosSpoolfileWorker) run() {
 	}
 }
 
-//PerformanceDataIterator returns an iterator to loop over generated perf data.
-func (w *NagiosSpoolfileWorker) PerformanceDataIterator(input map[string]string) <-chan PerformanceData {
+func isLineTooLong(err error) bool {
+	_, ok := err.(*LineTooLongError)
+	return ok
+}
+
+//Removes the file, the workers without collector don't track their files.
+func (w *NagiosSpoolfileWorker) done(file string) {
+	if w.inFlight != nil {
+		w.inFlight.Done(file)
+	} else if err := os.Remove(file); err != nil {
+		logging.GetLogger().Warn(err)
+	}
+}
+
+//Moves the broken file into the quarantine, without one it's removed.
+func (w *NagiosSpoolfileWorker) quarantine(file string, reason error) {
+	if quarantine.File("spoolfile", file, strings.TrimSuffix(file, ProcessingSuffix), reason.Error()) {
+		w.release(file)
+	} else {
+		w.done(file)
+	}
+}
+
+func (w *NagiosSpoolfileWorker) release(file string) {
+	if w.inFlight != nil {
+		w.inFlight.Release(file)
+	}
+}
+
+//PerformanceDataIterator returns an iterator to loop over generated perf data. Closing done stops the iterator, if
+//not all of the data is read, nil keeps it running till the end.
+func (w *NagiosSpoolfileWorker) PerformanceDataIterator(input map[string]string, done <-chan struct{}) <-chan PerformanceData {
 	ch := make(chan PerformanceData)
 	typ := findType(input)
 	if typ == "" {
@@ -167,40 +212,68 @@ func (w *NagiosSpoolfileWorker) PerformanceDataIterator(input map[string]string)
 	if typ != hostType {
 		currentService = input[servicedesc]
 	}
+	//the site can be chosen by NAGFLUX:TAG, if hosts with the same name are on several sites
+	site, livestatusCache := w.livestatusCachesBySite.Find(input[hostname], helper.StringToMap(input[nagfluxTags], " ", "=")["site"])
+	var metadata map[string]string
+	if livestatusCache != nil {
+		metadata = livestatusCache.MetadataTags(input[hostname], currentService)
+		if site != "" {
+			if metadata == nil {
+				metadata = map[string]string{}
+			}
+			metadata["site"] = site
+		}
+	}
 
 	go func() {
-		perfSlice := regexPerformancelable.FindAllStringSubmatch(input[typ+"PERFDATA"], -1)
+		defer close(ch)
+		items, errors := ParsePerfdata(input[typ+"PERFDATA"])
+		for _, err := range errors {
+			logging.GetLogger().Warnf("Could not parse perfdata. Host: %v, Service: %v, %v", input[hostname], currentService, err)
+		}
 		currentCheckMultiLabel := ""
 		//try to find a check_multi prefix
-		if len(perfSlice) > 0 && len(perfSlice[0]) > 1 {
-			currentCheckMultiLabel = getCheckMultiRegexMatch(perfSlice[0][1])
+		if len(items) > 0 {
+			currentCheckMultiLabel = getCheckMultiRegexMatch(items[0].RawLabel)
 		}
 
-	item:
-		for _, value := range perfSlice {
+		for _, item := range items {
 			// Allows to add tags and fields to spoolfileentries
 			tag := map[string]string{}
 			if tagString, ok := input[nagfluxTags]; ok {
 				tag = helper.StringToMap(tagString, " ", "=")
 			}
-			field := map[string]string{}
+			//NAGFLUX:TAG wins over the metadata of livestatus
+			for key, value := range metadata {
+				if _, ok := tag[key]; !ok {
+					tag[key] = value
+				}
+			}
+			field := data.Fields{}
 			if fieldString, ok := input[nagfluxField]; ok {
-				field = helper.StringToMap(fieldString, " ", "=")
+				for key, value := range helper.StringToMap(fieldString, " ", "=") {
+					if parsed, ok := data.ParseFieldValue(value); ok {
+						field[key] = parsed
+					} else {
+						logging.GetLogger().Warnf("Skipping the field %s of host %s, %s is not a finite number", key, input[hostname], value)
+					}
+				}
 			}
 			var target collector.Filterable
 			if targetString, ok := input[nagfluxTarget]; ok {
 				target = collector.Filterable{Filter: targetString}
 			} else {
-				target = collector.AllFilterable
+				target = w.defaultTarget
 			}
 
+			//The label is kept as it was written, quotes included, so the existing series don't change
 			perf := PerformanceData{
 				Hostname:         input[hostname],
 				Service:          currentService,
 				Command:          currentCommand,
 				Time:             currentTime,
-				PerformanceLabel: value[1],
-				Unit:             value[3],
+				PerformanceLabel: item.RawLabel,
+				Unit:             item.Unit,
 				Tags:             tag,
 				Fields:           field,
 				Filterable:       target,
@@ -218,64 +291,66 @@ func (w *NagiosSpoolfileWorker) PerformanceDataIterator(input map[string]string)
 				}
 			}
 
-			for i, data := range value {
-				data = strings.Replace(data, ",", ".", -1)
-				if i > 1 && i != 3 && data != "" {
-					performanceType, err := indexToperformanceType(i)
-					if err != nil {
-						logging.GetLogger().Warn(err, value)
-						continue
-					}
-
-					//Add downtime tag if needed
-					if performanceType == "value" && w.livestatusCacheBuilder != nil && w.livestatusCacheBuilder.IsServiceInDowntime(perf.Hostname, perf.Service, input[timet]) {
-						perf.Tags["downtime"] = "true"
-					}
-
-					if performanceType == "warn" || performanceType == "crit" {
-						//Range handling
-						fillLabel := performanceType + "-fill"
-						rangeHits := rangeRegex.FindAllStringSubmatch(data, -1)
-						if len(rangeHits) == 1 {
-							perf.Tags[fillLabel] = "none"
-							perf.Fields[performanceType] = helper.StringIntToStringFloat(rangeHits[0][0])
-
-						} else if len(rangeHits) == 2 {
-							//If there is a range with no infinity as border, create two points
-							if strings.Contains(data, "@") {
-								perf.Tags[fillLabel] = "inner"
-							} else {
-								perf.Tags[fillLabel] = "outer"
-							}
-
-							for i, tag := range []string{"min", "max"} {
-								tagKey := fmt.Sprintf("%s-%s", performanceType, tag)
-								perf.Fields[tagKey] = helper.StringIntToStringFloat(rangeHits[i][0])
-							}
-						} else {
-							logging.GetLogger().Warnf("Could not parse warn/crit value. Host: %v, Service: %v, Element: %v, Wholedata: %v", perf.Hostname, perf.Service, data, value)
-						}
-
-					} else {
-						if data == "U" {
-							perf.Fields["unknown"] = "true"
-							continue
-						}
-						if !helper.IsStringANumber(data) {
-							continue item
-						}
-						perf.Fields[performanceType] = helper.StringIntToStringFloat(data)
-
-					}
+			//Add downtime tag if needed
+			if livestatusCache != nil && livestatusCache.IsServiceInDowntime(perf.Hostname, perf.Service, input[timet]) {
+				perf.Tags["downtime"] = "true"
+			}
+			if item.Unknown() {
+				perf.Fields["unknown"] = data.Bool(true)
+			} else {
+				perf.Fields["value"] = parseFloat(item.Value)
+			}
+			for performanceType, threshold := range map[string]*Range{"warn": item.Warn, "crit": item.Crit} {
+				if threshold != nil {
+					addThreshold(perf, performanceType, threshold)
 				}
 			}
-			ch <- perf
+			if item.Min != "" {
+				perf.Fields["min"] = parseFloat(item.Min)
+			}
+			if item.Max != "" {
+				perf.Fields["max"] = parseFloat(item.Max)
+			}
+			select {
+			case ch <- perf:
+			case <-done:
+				return
+			}
 		}
-		close(ch)
 	}()
 	return ch
 }
 
+//Adds a warn or crit range. A single border is stored as it is, a range with two borders as min and max.
+//A range without borders like ~: has no limit, so there is no threshold.
+func addThreshold(perf PerformanceData, performanceType string, threshold *Range) {
+	fillLabel := performanceType + "-fill"
+	borders := threshold.Borders()
+	if len(borders) == 0 {
+		return
+	} else if len(borders) == 1 {
+		perf.Tags[fillLabel] = "none"
+		perf.Fields[performanceType] = parseFloat(borders[0])
+	} else {
+		//If there is a range with no infinity as border, create two points
+		if threshold.Inside {
+			perf.Tags[fillLabel] = "inner"
+		} else {
+			perf.Tags[fillLabel] = "outer"
+		}
+		for i, tag := range []string{"min", "max"} {
+			tagKey := fmt.Sprintf("%s-%s", performanceType, tag)
+			perf.Fields[tagKey] = parseFloat(borders[i])
+		}
+	}
+}
+
+//The numbers are already checked by the tokenizer.
+func parseFloat(number string) data.FieldValue {
+	value, _ := strconv.ParseFloat(number, 64)
+	return data.Float(value)
+}
+
 func getCheckMultiRegexMatch(perfData string) string {
 	regexResult := checkMulitRegex.FindAllStringSubmatch(perfData, -1)
 	if len(regexResult) == 1 && len(regexResult[0]) == 3 {
@@ -318,21 +393,3 @@ func isHostPerformanceData(input map[string]string) bool {
 func isServicePerformanceData(input map[string]string) bool {
 	return input["DATATYPE"] == servicePerfdata
 }
-
-//Converts the index of the perftype to an string.
-func indexToperformanceType(index int) (string, error) {
-	switch index {
-	case 2:
-		return "value", nil
-	case 4:
-		return "warn", nil
-	case 5:
-		return "crit", nil
-	case 6:
-		return "min", nil
-	case 7:
-		return "max", nil
-	default:
-		return "", errors.New("Illegale index: " + strconv.Itoa(index))
-	}
-}
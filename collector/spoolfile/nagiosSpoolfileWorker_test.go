@@ -1,10 +1,16 @@
 package spoolfile
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/data"
 	"github.com/spitefulgrog/nagflux/helper"
+	"github.com/spitefulgrog/nagflux/logging"
+	"reflect"
+	"runtime"
 	"testing"
+	"time"
 )
 
 var TestPerformanceData = []struct {
@@ -21,7 +27,7 @@ var TestPerformanceData = []struct {
 			PerformanceLabel: "a used",
 			Unit:             "",
 			Tags:             map[string]string{},
-			Fields:           map[string]string{"value": "4.0"},
+			Fields:           data.Fields{"value": data.Float(4.0)},
 			Filterable:       collector.AllFilterable,
 		}},
 	}, {
@@ -34,7 +40,7 @@ var TestPerformanceData = []struct {
 			PerformanceLabel: "a used",
 			Unit:             "",
 			Tags:             map[string]string{},
-			Fields:           map[string]string{"value": "4.0"},
+			Fields:           data.Fields{"value": data.Float(4.0)},
 			Filterable:       collector.AllFilterable,
 		}, {
 			Hostname:         "xxx",
@@ -44,7 +50,7 @@ var TestPerformanceData = []struct {
 			PerformanceLabel: `'C:\ used %'`,
 			Unit:             "%",
 			Tags:             map[string]string{"warn-fill": "none", "crit-fill": "none"},
-			Fields:           map[string]string{"value": "44.0", "warn": "89.0", "crit": "94.0", "min": "0.0", "max": "100.0"},
+			Fields:           data.Fields{"value": data.Float(44.0), "warn": data.Float(89.0), "crit": data.Float(94.0), "min": data.Float(0.0), "max": data.Float(100.0)},
 			Filterable:       collector.AllFilterable,
 		}},
 	},
@@ -58,7 +64,7 @@ var TestPerformanceData = []struct {
 			PerformanceLabel: "a used",
 			Unit:             "",
 			Tags:             map[string]string{"warn-fill": "none", "crit-fill": "none"},
-			Fields:           map[string]string{"value": "4.0", "warn": "2.0", "crit": "10.0"},
+			Fields:           data.Fields{"value": data.Float(4.0), "warn": data.Float(2.0), "crit": data.Float(10.0)},
 			Filterable:       collector.AllFilterable,
 		}},
 	},
@@ -72,7 +78,7 @@ var TestPerformanceData = []struct {
 			PerformanceLabel: "a used",
 			Unit:             "",
 			Tags:             map[string]string{"warn-fill": "none", "crit-fill": "none"},
-			Fields:           map[string]string{"value": "4.0", "warn": "2.0", "crit": "10.0", "min": "1.0", "max": "4.0"},
+			Fields:           data.Fields{"value": data.Float(4.0), "warn": data.Float(2.0), "crit": data.Float(10.0), "min": data.Float(1.0), "max": data.Float(4.0)},
 			Filterable:       collector.AllFilterable,
 		}},
 	},
@@ -86,7 +92,7 @@ var TestPerformanceData = []struct {
 			PerformanceLabel: "a used",
 			Unit:             "",
 			Tags:             map[string]string{"warn-fill": "outer", "crit-fill": "outer"},
-			Fields:           map[string]string{"value": "4.0", "warn-min": "2.0", "warn-max": "4.0", "crit-min": "8.0", "crit-max": "10.0", "min": "1.0", "max": "4.0"},
+			Fields:           data.Fields{"value": data.Float(4.0), "warn-min": data.Float(2.0), "warn-max": data.Float(4.0), "crit-min": data.Float(8.0), "crit-max": data.Float(10.0), "min": data.Float(1.0), "max": data.Float(4.0)},
 			Filterable:       collector.AllFilterable,
 		}},
 	},
@@ -100,7 +106,7 @@ var TestPerformanceData = []struct {
 			PerformanceLabel: "a used",
 			Unit:             "",
 			Tags:             map[string]string{"warn-fill": "inner", "crit-fill": "inner"},
-			Fields:           map[string]string{"value": "4.0", "warn-min": "2.0", "warn-max": "4.0", "crit-min": "8.0", "crit-max": "10.0", "min": "1.0", "max": "4.0"},
+			Fields:           data.Fields{"value": data.Float(4.0), "warn-min": data.Float(2.0), "warn-max": data.Float(4.0), "crit-min": data.Float(8.0), "crit-max": data.Float(10.0), "min": data.Float(1.0), "max": data.Float(4.0)},
 			Filterable:       collector.AllFilterable,
 		}},
 	},
@@ -114,7 +120,7 @@ var TestPerformanceData = []struct {
 			PerformanceLabel: "a used",
 			Unit:             "",
 			Tags:             map[string]string{"warn-fill": "none", "crit-fill": "none"},
-			Fields:           map[string]string{"value": "4.0", "warn": "2.0", "crit": "10.0", "min": "1.0", "max": "4.0"},
+			Fields:           data.Fields{"value": data.Float(4.0), "warn": data.Float(2.0), "crit": data.Float(10.0), "min": data.Float(1.0), "max": data.Float(4.0)},
 			Filterable:       collector.AllFilterable,
 		}},
 	},
@@ -128,7 +134,7 @@ var TestPerformanceData = []struct {
 			PerformanceLabel: "a used",
 			Unit:             "",
 			Tags:             map[string]string{"warn-fill": "none", "crit-fill": "none"},
-			Fields:           map[string]string{"value": "4.0", "warn": "2.0", "crit": "10.0", "min": "1.0", "max": "4.0"},
+			Fields:           data.Fields{"value": data.Float(4.0), "warn": data.Float(2.0), "crit": data.Float(10.0), "min": data.Float(1.0), "max": data.Float(4.0)},
 			Filterable:       collector.AllFilterable,
 		}},
 	},
@@ -142,7 +148,7 @@ var TestPerformanceData = []struct {
 			PerformanceLabel: "a used",
 			Unit:             "",
 			Tags:             map[string]string{"warn-fill": "none", "crit-fill": "none"},
-			Fields:           map[string]string{"value": "4.0", "warn": "2.0", "crit": "10.0", "min": "1.0", "max": "4.0"},
+			Fields:           data.Fields{"value": data.Float(4.0), "warn": data.Float(2.0), "crit": data.Float(10.0), "min": data.Float(1.0), "max": data.Float(4.0)},
 			Filterable:       collector.AllFilterable,
 		}},
 	},
@@ -157,7 +163,7 @@ var TestPerformanceData = []struct {
 			PerformanceLabel: "a used",
 			Unit:             "",
 			Tags:             map[string]string{},
-			Fields:           map[string]string{"value": "4.5"},
+			Fields:           data.Fields{"value": data.Float(4.5)},
 			Filterable:       collector.AllFilterable,
 		}},
 	}, {
@@ -171,7 +177,7 @@ var TestPerformanceData = []struct {
 			PerformanceLabel: "comma",
 			Unit:             "",
 			Tags:             map[string]string{},
-			Fields:           map[string]string{"value": "4.5"},
+			Fields:           data.Fields{"value": data.Float(4.5)},
 			Filterable:       collector.AllFilterable,
 		}},
 	}, {
@@ -185,7 +191,7 @@ var TestPerformanceData = []struct {
 			PerformanceLabel: "a used",
 			Unit:             "",
 			Tags:             map[string]string{},
-			Fields:           map[string]string{"value": "4.6"},
+			Fields:           data.Fields{"value": data.Float(4.6)},
 			Filterable:       collector.AllFilterable,
 		}, {
 			Hostname:         "xxx",
@@ -195,7 +201,7 @@ var TestPerformanceData = []struct {
 			PerformanceLabel: `'C:\ used %'`,
 			Unit:             "%",
 			Tags:             map[string]string{"warn-fill": "none", "crit-fill": "none"},
-			Fields:           map[string]string{"value": "44.1", "warn": "89.2", "crit": "94.3", "min": "0.4", "max": "100.5"},
+			Fields:           data.Fields{"value": data.Float(44.1), "warn": data.Float(89.2), "crit": data.Float(94.3), "min": data.Float(0.4), "max": data.Float(100.5)},
 			Filterable:       collector.AllFilterable,
 		}},
 	}, {
@@ -209,7 +215,7 @@ var TestPerformanceData = []struct {
 			PerformanceLabel: "tag",
 			Unit:             "",
 			Tags:             map[string]string{"foo": "bar"},
-			Fields:           map[string]string{"value": "4.5"},
+			Fields:           data.Fields{"value": data.Float(4.5)},
 			Filterable:       collector.AllFilterable,
 		}},
 	}, {
@@ -223,7 +229,7 @@ var TestPerformanceData = []struct {
 			PerformanceLabel: "tag",
 			Unit:             "",
 			Tags:             map[string]string{},
-			Fields:           map[string]string{"value": "4.5"},
+			Fields:           data.Fields{"value": data.Float(4.5)},
 			Filterable:       collector.AllFilterable,
 		}},
 	}, {
@@ -237,7 +243,7 @@ var TestPerformanceData = []struct {
 			PerformanceLabel: "tag",
 			Unit:             "",
 			Tags:             map[string]string{},
-			Fields:           map[string]string{"value": "4.5"},
+			Fields:           data.Fields{"value": data.Float(4.5)},
 			Filterable:       collector.AllFilterable,
 		}},
 	}, {
@@ -251,7 +257,7 @@ var TestPerformanceData = []struct {
 			PerformanceLabel: "tag",
 			Unit:             "",
 			Tags:             map[string]string{},
-			Fields:           map[string]string{"value": "4.5"},
+			Fields:           data.Fields{"value": data.Float(4.5)},
 			Filterable:       collector.Filterable{Filter: "foo"},
 		}},
 	}, {
@@ -265,7 +271,7 @@ var TestPerformanceData = []struct {
 			PerformanceLabel: "size",
 			Unit:             "B",
 			Tags:             map[string]string{},
-			Fields:           map[string]string{"value": "128766.0", "min": "0.0"},
+			Fields:           data.Fields{"value": data.Float(128766.0), "min": data.Float(0.0)},
 			Filterable:       collector.AllFilterable,
 		}, {
 			Hostname:         "HOST_SERVER",
@@ -275,7 +281,36 @@ var TestPerformanceData = []struct {
 			PerformanceLabel: "time",
 			Unit:             "s",
 			Tags:             map[string]string{},
-			Fields:           map[string]string{"value": "0.004118", "min": "0.000000"},
+			Fields:           data.Fields{"value": data.Float(0.004118), "min": data.Float(0.000000)},
+			Filterable:       collector.AllFilterable,
+		}},
+	},
+	{
+		//scientific notation and a quoted label with an equal sign
+		"DATATYPE::SERVICEPERFDATA	TIMET::1441791000	HOSTNAME::xxx	SERVICEDESC::ntp	SERVICEPERFDATA::'off=set'=1.2e-05s;~:5E-1;@1:2	SERVICECHECKCOMMAND::check_ntp_time	SERVICESTATE::0	SERVICESTATETYPE::1",
+		[]PerformanceData{{
+			Hostname:         "xxx",
+			Service:          "ntp",
+			Command:          "check_ntp_time",
+			Time:             "1441791000000",
+			PerformanceLabel: "'off=set'",
+			Unit:             "s",
+			Tags:             map[string]string{"warn-fill": "none", "crit-fill": "inner"},
+			Fields:           data.Fields{"value": data.Float(1.2e-05), "warn": data.Float(5E-1), "crit-min": data.Float(1.0), "crit-max": data.Float(2.0)},
+			Filterable:       collector.AllFilterable,
+		}},
+	},
+	{
+		//ranges without borders are no thresholds
+		"DATATYPE::SERVICEPERFDATA	TIMET::1441791000	HOSTNAME::xxx	SERVICEDESC::load	SERVICEPERFDATA::load1=0.5;~:;@~:	SERVICECHECKCOMMAND::check_load	SERVICESTATE::0	SERVICESTATETYPE::1",
+		[]PerformanceData{{
+			Hostname:         "xxx",
+			Service:          "load",
+			Command:          "check_load",
+			Time:             "1441791000000",
+			PerformanceLabel: "load1",
+			Tags:             map[string]string{},
+			Fields:           data.Fields{"value": data.Float(0.5)},
 			Filterable:       collector.AllFilterable,
 		}},
 	},
@@ -290,7 +325,7 @@ var TestPerformanceData = []struct {
 			PerformanceLabel: "getItinerary_min",
 			Unit:             "µs",
 			Tags:             map[string]string{},
-			Fields:           map[string]string{"value": "34385.0"},
+			Fields:           data.Fields{"value": data.Float(34385.0)},
 			Filterable:       collector.AllFilterable,
 		}, {
 			Hostname:         "müü",
@@ -300,7 +335,7 @@ var TestPerformanceData = []struct {
 			PerformanceLabel: "getItinerary_avg",
 			Unit:             "µs",
 			Tags:             map[string]string{},
-			Fields:           map[string]string{"value": "130925.0"},
+			Fields:           data.Fields{"value": data.Float(130925.0)},
 			Filterable:       collector.AllFilterable,
 		}, {
 			Hostname:         "müü",
@@ -310,7 +345,7 @@ var TestPerformanceData = []struct {
 			PerformanceLabel: "getItinerary_max",
 			Unit:             "µs",
 			Tags:             map[string]string{},
-			Fields:           map[string]string{"value": "267719.0"},
+			Fields:           data.Fields{"value": data.Float(267719.0)},
 			Filterable:       collector.AllFilterable,
 		}},
 	},
@@ -350,7 +385,7 @@ func comparePerformanceData(p1, p2 PerformanceData) (bool, string) {
 	if !compareStringMap(p1.Tags, p2.Tags) {
 		return false, "tags:" + fmt.Sprint(p1.Tags) + "!=" + fmt.Sprint(p2.Tags)
 	}
-	if !compareStringMap(p1.Fields, p2.Fields) {
+	if !reflect.DeepEqual(p1.Fields, p2.Fields) {
 		return false, "fields:" + fmt.Sprint(p1.Fields) + "!=" + fmt.Sprint(p2.Fields)
 	}
 	if !p1.Filterable.TestTargetFilterObj(p2.Filterable) {
@@ -365,7 +400,7 @@ func TestNagiosSpoolfileWorker_PerformanceDataIterator(t *testing.T) {
 	w := NewNagiosSpoolfileWorker(0, nil, nil, nil, 4096, collector.AllFilterable)
 	for _, data := range TestPerformanceData {
 		splittedPerformanceData := helper.StringToMap(data.input, "\t", "::")
-		for singlePerfdata := range w.PerformanceDataIterator(splittedPerformanceData) {
+		for singlePerfdata := range w.PerformanceDataIterator(splittedPerformanceData, nil) {
 			found := false
 			for _, expectedPerfdata := range data.expected {
 				equal, _ := comparePerformanceData(singlePerfdata, expectedPerfdata)
@@ -391,3 +426,38 @@ func TestNagiosSpoolfileWorker_PerformanceDataIterator(t *testing.T) {
 		}
 	}
 }
+
+func TestAddThresholdWithoutBorders(t *testing.T) {
+	var output bytes.Buffer
+	logging.InitTestLogger()
+	logging.GetLogger().SetOutput(&output)
+	defer logging.InitTestLogger()
+	perf := PerformanceData{Tags: map[string]string{}, Fields: data.Fields{}}
+	addThreshold(perf, "warn", &Range{Start: "~"})
+	if len(perf.Tags) != 0 || len(perf.Fields) != 0 || output.Len() != 0 {
+		t.Errorf("~: is no threshold, got tags: %v fields: %v log: %s", perf.Tags, perf.Fields, output.String())
+	}
+}
+
+func TestNagiosSpoolfileWorker_PerformanceDataIteratorDone(t *testing.T) {
+	w := NewNagiosSpoolfileWorker(0, nil, nil, nil, 4096, collector.AllFilterable)
+	for _, data := range TestPerformanceData {
+		if len(data.expected) < 2 {
+			continue
+		}
+		done := make(chan struct{})
+		iterator := w.PerformanceDataIterator(helper.StringToMap(data.input, "\t", "::"), done)
+		<-iterator
+		//the iterator is blocked by the unread data
+		running := runtime.NumGoroutine()
+		close(done)
+		for timeout := time.Now().Add(time.Duration(1) * time.Second); runtime.NumGoroutine() >= running; {
+			if time.Now().After(timeout) {
+				t.Fatal("The iterator was not stopped by done:", data.input)
+			}
+			time.Sleep(time.Duration(10) * time.Millisecond)
+		}
+		return
+	}
+	t.Fatal("No perfdata with several items found")
+}
@@ -0,0 +1,320 @@
+package spoolfile
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//PerfdataItem is one 'label'=value[UOM];[warn];[crit];[min];[max] entry of the performance data.
+//Numbers are normalized, a decimal comma becomes a dot.
+type PerfdataItem struct {
+	//Label is unquoted, a doubled quote within a quoted label becomes a single one.
+	Label string
+	//RawLabel is the label as it was written, including the quotes.
+	RawLabel string
+	//Value is U if the plugin could not determine it.
+	Value string
+	Unit  string
+	Warn  *Range
+	Crit  *Range
+	Min   string
+	Max   string
+}
+
+//Range is a warn or crit threshold as defined by the plugin guidelines: [@][start:]end.
+type Range struct {
+	//Inside is set if the range starts with an @, the alert is raised within the range.
+	Inside bool
+	//Start is ~ for negative infinity and empty if it's omitted, which means 0. A leading : is dropped.
+	Start string
+	//End is empty or ~ for positive infinity.
+	End string
+}
+
+//PerfdataError describes an item which does not follow the plugin guidelines, the other items are parsed anyway.
+type PerfdataError struct {
+	//Offset is the position of the item within the performance data in bytes.
+	Offset int
+	Item   string
+	Reason string
+}
+
+func (e PerfdataError) Error() string {
+	return fmt.Sprintf("perfdata item %q at %d: %s", e.Item, e.Offset, e.Reason)
+}
+
+//Unknown returns true if the value is U.
+func (p PerfdataItem) Unknown() bool {
+	return p.Value == "U"
+}
+
+//String prints the item in the format of the plugin guidelines, the label is quoted if needed.
+func (p PerfdataItem) String() string {
+	result := quoteLabel(p.Label) + "=" + p.Value + p.Unit
+	fields := []string{p.Warn.String(), p.Crit.String(), p.Min, p.Max}
+	for len(fields) > 0 && fields[len(fields)-1] == "" {
+		fields = fields[:len(fields)-1]
+	}
+	for _, field := range fields {
+		result += ";" + field
+	}
+	return result
+}
+
+//Borders returns the start and end of the range which are numbers, infinity and omitted ones are left out.
+func (r *Range) Borders() []string {
+	var borders []string
+	for _, border := range []string{r.Start, r.End} {
+		if border != "" && border != "~" {
+			borders = append(borders, border)
+		}
+	}
+	return borders
+}
+
+func (r *Range) String() string {
+	if r == nil {
+		return ""
+	}
+	result := r.End
+	if r.Start != "" {
+		result = r.Start + ":" + r.End
+	}
+	if r.Inside {
+		result = "@" + result
+	}
+	return result
+}
+
+//ParsePerfdata splits the performance data of a check into its items. Labels without quotes may contain spaces,
+//they reach till the next =, like it's done by the most addons. An alternative command like [check_foo] is skipped.
+func ParsePerfdata(perfdata string) ([]PerfdataItem, []PerfdataError) {
+	var items []PerfdataItem
+	var errors []PerfdataError
+	pos := skipSpace(perfdata, 0)
+	for pos < len(perfdata) {
+		start := pos
+		item, next, err := parsePerfdataItem(perfdata, pos)
+		if err != "" {
+			errors = append(errors, PerfdataError{Offset: start, Item: perfdata[start:next], Reason: err})
+		} else if item != nil {
+			items = append(items, *item)
+		}
+		pos = skipSpace(perfdata, next)
+	}
+	return items, errors
+}
+
+//Parses the item at pos and returns the position after it. The item is nil if there was nothing to parse.
+func parsePerfdataItem(perfdata string, pos int) (*PerfdataItem, int, string) {
+	item := &PerfdataItem{}
+	if perfdata[pos] == '\'' {
+		label, end, ok := scanQuotedLabel(perfdata, pos)
+		if !ok {
+			return nil, len(perfdata), "the quote of the label is not closed"
+		}
+		item.Label, item.RawLabel, pos = label, perfdata[pos:end], end
+		if pos >= len(perfdata) || perfdata[pos] != '=' {
+			return nil, skipToken(perfdata, pos), "the label is not followed by a ="
+		}
+		if label == "" {
+			return nil, skipToken(perfdata, pos), "the label is empty"
+		}
+	} else {
+		token := perfdata[pos:skipToken(perfdata, pos)]
+		if strings.HasPrefix(token, "[") && strings.HasSuffix(token, "]") && !strings.Contains(token, "=") {
+			return nil, pos + len(token), ""
+		}
+		equal := strings.IndexByte(perfdata[pos:], '=')
+		if equal < 0 {
+			return nil, len(perfdata), "there is no ="
+		}
+		item.Label = strings.TrimRightFunc(perfdata[pos:pos+equal], unicode.IsSpace)
+		item.RawLabel = item.Label
+		pos += equal
+		if item.Label == "" {
+			return nil, skipToken(perfdata, pos), "the label is empty"
+		}
+	}
+
+	//pos is at the =, the data reaches till the next whitespace
+	end := skipToken(perfdata, pos)
+	fields := strings.Split(perfdata[pos+1:end], ";")
+	for len(fields) > 5 && fields[len(fields)-1] == "" {
+		fields = fields[:len(fields)-1]
+	}
+	if len(fields) > 5 {
+		return nil, end, "there are more than five fields"
+	}
+	for len(fields) < 5 {
+		fields = append(fields, "")
+	}
+
+	var err string
+	if item.Value, item.Unit, err = parseValue(fields[0]); err != "" {
+		return nil, end, err
+	}
+	if item.Warn, err = parseRange(fields[1]); err != "" {
+		return nil, end, "warn " + err
+	}
+	if item.Crit, err = parseRange(fields[2]); err != "" {
+		return nil, end, "crit " + err
+	}
+	for i, target := range []*string{&item.Min, &item.Max} {
+		if fields[3+i] == "" {
+			continue
+		}
+		if length := scanNumber(fields[3+i]); length != len(fields[3+i]) {
+			return nil, end, fmt.Sprintf("%s is not a number: %s", []string{"min", "max"}[i], fields[3+i])
+		}
+		*target = normalizeNumber(fields[3+i])
+	}
+	return item, end, ""
+}
+
+//Returns the unquoted label and the position after the closing quote, '' is an escaped quote.
+func scanQuotedLabel(perfdata string, pos int) (string, int, bool) {
+	var label strings.Builder
+	for i := pos + 1; i < len(perfdata); i++ {
+		if perfdata[i] != '\'' {
+			label.WriteByte(perfdata[i])
+		} else if i+1 < len(perfdata) && perfdata[i+1] == '\'' {
+			label.WriteByte('\'')
+			i++
+		} else {
+			return label.String(), i + 1, true
+		}
+	}
+	return "", len(perfdata), false
+}
+
+//Splits the value from the unit of measurement.
+func parseValue(field string) (string, string, string) {
+	var value, unit string
+	if strings.HasPrefix(field, "U") {
+		value, unit = "U", field[1:]
+	} else if length := scanNumber(field); length > 0 {
+		value, unit = normalizeNumber(field[:length]), field[length:]
+	} else if field == "" {
+		return "", "", "the value is missing"
+	} else {
+		return "", "", "the value is not a number: " + field
+	}
+	for _, r := range unit {
+		if !unicode.IsLetter(r) && r != '%' && r != '/' && r != '°' {
+			return "", "", "the unit is not valid: " + unit
+		}
+	}
+	return value, unit, ""
+}
+
+func parseRange(field string) (*Range, string) {
+	if field == "" {
+		return nil, ""
+	}
+	r := &Range{}
+	if strings.HasPrefix(field, "@") {
+		r.Inside = true
+		field = field[1:]
+	}
+	r.End = field
+	if colon := strings.IndexByte(field, ':'); colon >= 0 {
+		r.Start, r.End = field[:colon], field[colon+1:]
+	}
+	for _, border := range []*string{&r.Start, &r.End} {
+		if *border == "" || *border == "~" {
+			continue
+		}
+		if length := scanNumber(*border); length != len(*border) {
+			return nil, "is not a valid range: " + field
+		}
+		*border = normalizeNumber(*border)
+	}
+	if r.Start == "" && r.End == "" {
+		return nil, "is not a valid range: " + field
+	}
+	return r, ""
+}
+
+//Returns the length of the number at the beginning of s, a decimal comma and an exponent are accepted.
+func scanNumber(s string) int {
+	i := 0
+	if i < len(s) && (s[i] == '-' || s[i] == '+') {
+		i++
+	}
+	digits := 0
+	for ; i < len(s) && isDigit(s[i]); i++ {
+		digits++
+	}
+	if i < len(s) && (s[i] == '.' || s[i] == ',') {
+		i++
+		for ; i < len(s) && isDigit(s[i]); i++ {
+			digits++
+		}
+	}
+	if digits == 0 {
+		return 0
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		exponent := i + 1
+		if exponent < len(s) && (s[exponent] == '-' || s[exponent] == '+') {
+			exponent++
+		}
+		if exponent < len(s) && isDigit(s[exponent]) {
+			for i = exponent; i < len(s) && isDigit(s[i]); i++ {
+			}
+		}
+	}
+	return i
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+//Writes the number the way the targets expect it: 1,5 -> 1.5, +.5 -> 0.5, 5.e3 -> 5.0e3
+func normalizeNumber(number string) string {
+	number = strings.TrimPrefix(strings.Replace(number, ",", ".", 1), "+")
+	if dot := strings.IndexByte(number, '.'); dot >= 0 {
+		if dot+1 == len(number) || !isDigit(number[dot+1]) {
+			number = number[:dot+1] + "0" + number[dot+1:]
+		}
+		if dot == 0 || !isDigit(number[dot-1]) {
+			number = number[:dot] + "0" + number[dot:]
+		}
+	}
+	return number
+}
+
+//Returns the position of the next whitespace or the end.
+func skipToken(s string, pos int) int {
+	for pos < len(s) {
+		r, size := utf8.DecodeRuneInString(s[pos:])
+		if unicode.IsSpace(r) {
+			return pos
+		}
+		pos += size
+	}
+	return pos
+}
+
+func skipSpace(s string, pos int) int {
+	for pos < len(s) {
+		r, size := utf8.DecodeRuneInString(s[pos:])
+		if !unicode.IsSpace(r) {
+			return pos
+		}
+		pos += size
+	}
+	return pos
+}
+
+//Quotes the label if it contains a space, a quote or a =.
+func quoteLabel(label string) string {
+	if !strings.ContainsAny(label, "'=") && !strings.ContainsFunc(label, unicode.IsSpace) {
+		return label
+	}
+	return "'" + strings.Replace(label, "'", "''", -1) + "'"
+}
@@ -0,0 +1,138 @@
+package spoolfile
+
+import (
+	"bufio"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var ParsePerfdataData = []struct {
+	input    string
+	expected []PerfdataItem
+	errors   int
+}{
+	{"load1=0.150;15.000;30.000;0;", []PerfdataItem{
+		{Label: "load1", RawLabel: "load1", Value: "0.150", Warn: &Range{End: "15.000"}, Crit: &Range{End: "30.000"}, Min: "0"},
+	}, 0},
+	{"'label with spaces'=1 'a=b'=2c", []PerfdataItem{
+		{Label: "label with spaces", RawLabel: "'label with spaces'", Value: "1"},
+		{Label: "a=b", RawLabel: "'a=b'", Value: "2", Unit: "c"},
+	}, 0},
+	{"'it''s'=3", []PerfdataItem{
+		{Label: "it's", RawLabel: "'it''s'", Value: "3"},
+	}, 0},
+	{"offset=1.2e-05s;5E-1;1", []PerfdataItem{
+		{Label: "offset", RawLabel: "offset", Value: "1.2e-05", Unit: "s", Warn: &Range{End: "5E-1"}, Crit: &Range{End: "1"}},
+	}, 0},
+	{"a=10;~:10;@5:20;-1,5;1,5", []PerfdataItem{
+		{Label: "a", RawLabel: "a", Value: "10", Warn: &Range{Start: "~", End: "10"}, Crit: &Range{Inside: true, Start: "5", End: "20"}, Min: "-1.5", Max: "1.5"},
+	}, 0},
+	{"a used=U;10:", []PerfdataItem{
+		{Label: "a used", RawLabel: "a used", Value: "U", Warn: &Range{Start: "10"}},
+	}, 0},
+	{"time=0.1s [check_foo]", []PerfdataItem{
+		{Label: "time", RawLabel: "time", Value: "0.1", Unit: "s"},
+	}, 0},
+	{"fraction=.5 trailing=5.e3", []PerfdataItem{
+		{Label: "fraction", RawLabel: "fraction", Value: "0.5"},
+		{Label: "trailing", RawLabel: "trailing", Value: "5.0e3"},
+	}, 0},
+	//the broken items are reported, the others are parsed anyway
+	{"ok=1 bad=abc 'missing=2 ", []PerfdataItem{
+		{Label: "ok", RawLabel: "ok", Value: "1"},
+	}, 2},
+	{"a=1;2;3;4;5;6 b=1;x c=1;;;y d=1*2 e= f=2", []PerfdataItem{
+		{Label: "f", RawLabel: "f", Value: "2"},
+	}, 5},
+	{"=1 ''=2 'x'y=3 g=4", []PerfdataItem{
+		{Label: "g", RawLabel: "g", Value: "4"},
+	}, 3},
+	{"no equal sign", nil, 1},
+	{"   ", nil, 0},
+}
+
+func TestParsePerfdata(t *testing.T) {
+	t.Parallel()
+	for _, data := range ParsePerfdataData {
+		items, errors := ParsePerfdata(data.input)
+		if !reflect.DeepEqual(items, data.expected) {
+			t.Errorf("ParsePerfdata(%s): expected %+v, got %+v", data.input, data.expected, items)
+		}
+		if len(errors) != data.errors {
+			t.Errorf("ParsePerfdata(%s): expected %d errors, got %v", data.input, data.errors, errors)
+		}
+	}
+}
+
+func TestParsePerfdataErrorOffset(t *testing.T) {
+	t.Parallel()
+	_, errors := ParsePerfdata("ok=1 bad=1;x")
+	if len(errors) != 1 || errors[0].Offset != 5 || errors[0].Item != "bad=1;x" {
+		t.Errorf("Unexpected error: %v", errors)
+	}
+}
+
+func readPerfdataCorpus(t testing.TB) []string {
+	file, err := os.Open("testdata/perfdata_corpus.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var corpus []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" && !strings.HasPrefix(line, "#") {
+			corpus = append(corpus, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return corpus
+}
+
+func TestParsePerfdataCorpus(t *testing.T) {
+	t.Parallel()
+	for _, line := range readPerfdataCorpus(t) {
+		items, errors := ParsePerfdata(line)
+		if len(errors) > 0 {
+			t.Errorf("ParsePerfdata(%s): %v", line, errors)
+		}
+		if len(items) == 0 {
+			t.Errorf("ParsePerfdata(%s): no items", line)
+		}
+	}
+}
+
+//Every parsed item has to be printed in a way, which is parsed to the same item again.
+func FuzzParsePerfdata(f *testing.F) {
+	for _, line := range readPerfdataCorpus(f) {
+		f.Add(line)
+	}
+	for _, data := range ParsePerfdataData {
+		f.Add(data.input)
+	}
+	f.Fuzz(func(t *testing.T, perfdata string) {
+		items, errors := ParsePerfdata(perfdata)
+		for _, err := range errors {
+			if err.Offset < 0 || err.Offset+len(err.Item) > len(perfdata) || perfdata[err.Offset:err.Offset+len(err.Item)] != err.Item {
+				t.Fatalf("The error does not point to the item: %v", err)
+			}
+		}
+		for _, item := range items {
+			if item.Label == "" || item.Value == "" {
+				t.Fatalf("Incomplete item: %+v", item)
+			}
+			again, errors := ParsePerfdata(item.String())
+			if len(errors) > 0 || len(again) != 1 {
+				t.Fatalf("%+v printed as %s got %+v, %v", item, item.String(), again, errors)
+			}
+			again[0].RawLabel = item.RawLabel
+			if !reflect.DeepEqual(again[0], item) {
+				t.Fatalf("%+v printed as %s got %+v", item, item.String(), again[0])
+			}
+		}
+	})
+}
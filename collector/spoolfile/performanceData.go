@@ -1,10 +1,10 @@
 package spoolfile
 
 import (
-	"fmt"
 	"github.com/spitefulgrog/nagflux/collector"
-	"github.com/spitefulgrog/nagflux/config"
-	"github.com/spitefulgrog/nagflux/helper"
+	"github.com/spitefulgrog/nagflux/data"
+	"strconv"
+	"strings"
 )
 
 //PerformanceData represents the nagios perfdata
@@ -17,58 +17,33 @@ type PerformanceData struct {
 	Unit             string
 	Time             string
 	Tags             map[string]string
-	Fields           map[string]string
+	Fields           data.Fields
 }
 
-//PrintForInfluxDB prints the data in influxdb lineformat
-func (p PerformanceData) PrintForInfluxDB(version string) string {
-	if helper.VersionOrdinal(version) >= helper.VersionOrdinal("0.9") {
-		tableName := fmt.Sprintf(`metrics,host=%s`, helper.SanitizeInfluxInput(p.Hostname))
-		if p.Service == "" {
-			tableName += fmt.Sprintf(`,service=%s`, helper.SanitizeInfluxInput(config.GetConfig().InfluxDBGlobal.HostcheckAlias))
-		} else {
-			tableName += fmt.Sprintf(`,service=%s`, helper.SanitizeInfluxInput(p.Service))
-		}
-		tableName += fmt.Sprintf(`,command=%s,performanceLabel=%s`,
-			helper.SanitizeInfluxInput(p.Command),
-			helper.SanitizeInfluxInput(p.PerformanceLabel),
-		)
-		if len(p.Tags) > 0 {
-			tableName += fmt.Sprintf(`,%s`, helper.PrintMapAsString(helper.SanitizeMap(p.Tags), ",", "="))
-		}
-		if p.Unit != "" {
-			tableName += fmt.Sprintf(`,unit=%s`, p.Unit)
-		}
-
-		tableName += fmt.Sprintf(` %s`, helper.PrintMapAsString(helper.SanitizeMap(p.Fields), ",", "="))
-		tableName += fmt.Sprintf(" %s\n", p.Time)
-		return tableName
+//Points returns the perfdata as point of the measurement metrics, hostchecks have no service tag.
+func (p PerformanceData) Points() []collector.Point {
+	timestamp, err := strconv.ParseInt(p.Time, 10, 64)
+	if err != nil {
+		return nil
 	}
-	return ""
-}
-
-//PrintForElasticsearch prints in the elasticsearch json format
-func (p PerformanceData) PrintForElasticsearch(version, index string) string {
-	if helper.VersionOrdinal(version) >= helper.VersionOrdinal("2.0") {
-		if p.Service == "" {
-			p.Service = config.GetConfig().InfluxDBGlobal.HostcheckAlias
-		}
-		head := fmt.Sprintf(`{"index":{"_index":"%s","_type":"metrics"}}`, helper.GenIndex(index, p.Time)) + "\n"
-		data := fmt.Sprintf(
-			`{"timestamp":%s,"host":"%s","service":"%s","command":"%s","performanceLabel":"%s"`,
-			p.Time,
-			helper.SanitizeElasicInput(p.Hostname),
-			helper.SanitizeElasicInput(p.Service),
-			helper.SanitizeElasicInput(p.Command),
-			helper.SanitizeElasicInput(p.PerformanceLabel),
-		)
-		if p.Unit != "" {
-			data += fmt.Sprintf(`,"unit":"%s"`, helper.SanitizeElasicInput(p.Unit))
-		}
-		data += helper.CreateJSONFromStringMap(p.Tags)
-		data += helper.CreateJSONFromStringMap(p.Fields)
-		data += "}\n"
-		return head + data
+	tags := map[string]string{}
+	for key, value := range p.Tags {
+		tags[key] = value
+	}
+	tags["host"] = p.Hostname
+	if p.Service != "" {
+		tags["service"] = p.Service
+	}
+	tags["command"] = p.Command
+	tags["performanceLabel"] = strings.Trim(p.PerformanceLabel, `'`)
+	if p.Unit != "" {
+		tags["unit"] = p.Unit
 	}
-	return ""
+	return []collector.Point{{
+		Kind:        collector.PerfdataKind,
+		Measurement: "metrics",
+		Tags:        tags,
+		Fields:      p.Fields,
+		Timestamp:   timestamp,
+	}}
 }
@@ -0,0 +1,211 @@
+package spoolfile
+
+import (
+	"github.com/spitefulgrog/nagflux/logging"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	//WatchPoll lists the folder every IntervalToCheckDirectory.
+	WatchPoll = "poll"
+	//WatchInotify reacts to files which are closed after writing or moved into the folder.
+	WatchInotify = "inotify"
+	//RescanInterval is the interval in which a watched folder is listed anyway, in case events got lost.
+	RescanInterval = time.Duration(1) * time.Minute
+	//Time to wait for further events, so files written at once are passed at once.
+	eventDelay = time.Duration(100) * time.Millisecond
+)
+
+//Watcher tells the collectors which files of a folder can be read. It uses inotify if it's requested and available
+//and polls otherwise.
+type Watcher struct {
+	Batches    chan Batch
+	folder     string
+	minFileAge time.Duration
+	inotify    *inotify
+	//closed before inotify, so the failing read is expected
+	stopped chan bool
+	quit    chan bool
+}
+
+//Batch are files which are ready to be read.
+type Batch struct {
+	watcher *Watcher
+	files   []string
+	//Rescan is set if the whole folder has to be read, e.g. if polling or if events got lost.
+	Rescan bool
+}
+
+//Files returns the files of the batch which still exist, a rescan lists the folder at this point.
+func (b Batch) Files() []string {
+	if b.Rescan {
+		return b.watcher.Scan()
+	}
+	var files []string
+	for _, file := range b.files {
+		//the file could be read by a rescan, while the event waited
+		if _, err := os.Stat(file); err == nil {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+//NewWatcher starts watching the folder, mode is WatchPoll or WatchInotify. While polling just files older than
+//minFileAge are passed, events mark the files as complete.
+func NewWatcher(folder, mode string, minFileAge time.Duration) *Watcher {
+	w := &Watcher{Batches: make(chan Batch), folder: folder, minFileAge: minFileAge, quit: make(chan bool)}
+	switch strings.ToLower(mode) {
+	case "", WatchPoll:
+	case WatchInotify:
+		watch, err := newInotify(folder)
+		if err != nil {
+			logging.GetLogger().Warnf("Could not watch %s with inotify, polling instead: %s", folder, err)
+		} else {
+			w.inotify = watch
+		}
+	default:
+		logging.GetLogger().Warnf("Unknown SpoolfileWatchMode %s, polling %s", mode, folder)
+	}
+	go w.run()
+	return w
+}
+
+//Stop stops watching.
+func (w *Watcher) Stop() {
+	w.quit <- true
+	<-w.quit
+}
+
+//Scan lists the files of the folder which are older than the min file age.
+func (w *Watcher) Scan() []string {
+	logging.GetLogger().Debug("Reading Directory: ", w.folder)
+	if w.minFileAge <= 0 {
+		files, _ := ioutil.ReadDir(w.folder)
+		result := make([]string, 0, len(files))
+		for _, currentFile := range files {
+			result = append(result, path.Join(w.folder, currentFile.Name()))
+		}
+		return result
+	}
+	return FilesInDirectoryOlderThanX(w.folder, w.minFileAge)
+}
+
+func (w *Watcher) run() {
+	interval := IntervalToCheckDirectory
+	var events chan []string
+	var overflows chan bool
+	if w.inotify != nil {
+		logging.GetLogger().Infof("Watching %s with inotify", w.folder)
+		interval = RescanInterval
+		events, overflows, w.stopped = make(chan []string), make(chan bool), make(chan bool)
+		go readEvents(w.inotify, events, overflows, w.stopped)
+		//the files which were there before the watch started
+		if !w.send(Batch{watcher: w, Rescan: true}) {
+			return
+		}
+	}
+	for {
+		select {
+		case <-w.quit:
+			w.closeInotify()
+			w.quit <- true
+			return
+		case <-time.After(interval):
+			if !w.send(Batch{watcher: w, Rescan: true}) {
+				return
+			}
+		case <-overflows:
+			logging.GetLogger().Warnf("Lost inotify events of %s, reading the whole folder", w.folder)
+			if !w.send(Batch{watcher: w, Rescan: true}) {
+				return
+			}
+		case names, ok := <-events:
+			if !ok {
+				logging.GetLogger().Warnf("Stopped watching %s with inotify, polling instead", w.folder)
+				w.closeInotify()
+				events, overflows, interval = nil, nil, IntervalToCheckDirectory
+				continue
+			}
+			if !w.send(w.collectEvents(names, events)) {
+				return
+			}
+		}
+	}
+}
+
+//Waits shortly for further events and returns the files in sorted order, like a rescan.
+func (w *Watcher) collectEvents(names []string, events chan []string) Batch {
+	unique := map[string]bool{}
+	timeout := time.After(eventDelay)
+	for waiting := true; waiting; {
+		for _, name := range names {
+			unique[path.Join(w.folder, name)] = true
+		}
+		select {
+		case names, waiting = <-events:
+		case <-timeout:
+			waiting = false
+		}
+	}
+	batch := Batch{watcher: w, files: make([]string, 0, len(unique))}
+	for file := range unique {
+		batch.files = append(batch.files, file)
+	}
+	sort.Strings(batch.files)
+	return batch
+}
+
+//Passes the batch to the collector, false if the watcher got stopped meanwhile.
+func (w *Watcher) send(batch Batch) bool {
+	select {
+	case w.Batches <- batch:
+		return true
+	case <-w.quit:
+		w.closeInotify()
+		w.quit <- true
+		return false
+	}
+}
+
+func readEvents(watch *inotify, events chan []string, overflows chan bool, stopped chan bool) {
+	defer close(events)
+	for {
+		names, overflow, err := watch.read()
+		if err != nil {
+			select {
+			case <-stopped:
+			default:
+				logging.GetLogger().Warn("Reading inotify events failed: ", err)
+			}
+			return
+		}
+		if overflow {
+			select {
+			case overflows <- true:
+			case <-stopped:
+				return
+			}
+		}
+		if len(names) > 0 {
+			select {
+			case events <- names:
+			case <-stopped:
+				return
+			}
+		}
+	}
+}
+
+func (w *Watcher) closeInotify() {
+	if w.inotify != nil {
+		close(w.stopped)
+		w.inotify.close()
+		w.inotify = nil
+	}
+}
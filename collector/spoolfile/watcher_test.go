@@ -0,0 +1,107 @@
+package spoolfile
+
+import (
+	"github.com/spitefulgrog/nagflux/logging"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"runtime"
+	"sort"
+	"testing"
+	"time"
+)
+
+func writeSpoolfile(t *testing.T, file string, age time.Duration) {
+	if err := ioutil.WriteFile(file, []byte("DATATYPE::HOSTPERFDATA\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if age > 0 {
+		past := time.Now().Add(-age)
+		os.Chtimes(file, past, past)
+	}
+}
+
+func nextBatch(t *testing.T, w *Watcher, timeout time.Duration) Batch {
+	select {
+	case batch := <-w.Batches:
+		return batch
+	case <-time.After(timeout):
+		t.Fatal("Got no batch")
+	}
+	return Batch{}
+}
+
+func TestWatcherScan(t *testing.T) {
+	logging.InitTestLogger()
+	folder := t.TempDir()
+	writeSpoolfile(t, path.Join(folder, "old"), time.Duration(1)*time.Minute)
+	writeSpoolfile(t, path.Join(folder, "new"), 0)
+
+	w := &Watcher{folder: folder}
+	if files := w.Scan(); !reflect.DeepEqual(files, []string{path.Join(folder, "new"), path.Join(folder, "old")}) {
+		t.Errorf("Expected every file, got %v", files)
+	}
+	w.minFileAge = MinFileAge
+	if files := w.Scan(); !reflect.DeepEqual(files, []string{path.Join(folder, "old")}) {
+		t.Errorf("Expected the old file, got %v", files)
+	}
+}
+
+func TestBatchFilesSkipsVanished(t *testing.T) {
+	folder := t.TempDir()
+	writeSpoolfile(t, path.Join(folder, "a"), 0)
+	batch := Batch{files: []string{path.Join(folder, "a"), path.Join(folder, "gone")}}
+	if files := batch.Files(); !reflect.DeepEqual(files, []string{path.Join(folder, "a")}) {
+		t.Errorf("Unexpected files: %v", files)
+	}
+}
+
+func TestWatcherInotify(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("inotify is just available on linux")
+	}
+	logging.InitTestLogger()
+	folder := t.TempDir()
+	writeSpoolfile(t, path.Join(folder, "before"), 0)
+	w := NewWatcher(folder, WatchInotify, MinFileAge)
+	defer w.Stop()
+	if w.inotify == nil {
+		t.Fatal("inotify is not used")
+	}
+
+	//the files which are there already are listed once at the start
+	if batch := nextBatch(t, w, time.Second); !batch.Rescan {
+		t.Errorf("Expected a rescan first, got %v", batch.files)
+	}
+
+	//written files are passed without waiting for MinFileAge, moved ones too
+	writeSpoolfile(t, path.Join(folder, "written"), 0)
+	other := t.TempDir()
+	writeSpoolfile(t, path.Join(other, "moved"), 0)
+	if err := os.Rename(path.Join(other, "moved"), path.Join(folder, "moved")); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{path.Join(folder, "moved"), path.Join(folder, "written")}
+	var files []string
+	for len(files) < len(expected) {
+		batch := nextBatch(t, w, time.Duration(2)*time.Second)
+		if batch.Rescan {
+			t.Fatal("Expected events, got a rescan")
+		}
+		files = append(files, batch.Files()...)
+	}
+	sort.Strings(files)
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected %v, got %v", expected, files)
+	}
+}
+
+func TestWatcherFallsBackToPolling(t *testing.T) {
+	logging.InitTestLogger()
+	w := NewWatcher(path.Join(t.TempDir(), "missing"), WatchInotify, 0)
+	defer w.Stop()
+	if w.inotify != nil {
+		t.Error("A missing folder can't be watched")
+	}
+}
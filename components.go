@@ -0,0 +1,631 @@
+package main
+
+import (
+	"fmt"
+	"github.com/spitefulgrog/nagflux/admin"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/collector/livestatus"
+	"github.com/spitefulgrog/nagflux/collector/modGearman"
+	"github.com/spitefulgrog/nagflux/collector/nagflux"
+	"github.com/spitefulgrog/nagflux/collector/spoolfile"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/routing"
+	"github.com/spitefulgrog/nagflux/target"
+	"github.com/spitefulgrog/nagflux/target/elasticsearch"
+	"github.com/spitefulgrog/nagflux/target/file/json"
+	"github.com/spitefulgrog/nagflux/target/graphite"
+	"github.com/spitefulgrog/nagflux/target/influx"
+	"github.com/spitefulgrog/nagflux/target/kafka"
+	"github.com/spitefulgrog/nagflux/target/opentsdb"
+	"github.com/spitefulgrog/nagflux/target/prometheus"
+	"github.com/spitefulgrog/nagflux/wal"
+	"reflect"
+	"sync"
+	"time"
+)
+
+//targetSpec describes a configured target, the signature is compared on a reload to detect changes.
+type targetSpec struct {
+	signature []interface{}
+	start     func(jobs chan collector.Printable) (Stoppable, *wal.Drainer)
+}
+
+//runningTarget is a started target together with its write-ahead log and autoscaler.
+type runningTarget struct {
+	signature  []interface{}
+	connector  Stoppable
+	drainer    *wal.Drainer
+	autoscaler *target.Autoscaler
+}
+
+//Stop stops draining first, so the workers can spill their remaining data into the write-ahead log before it's closed.
+func (t runningTarget) Stop() {
+	if t.autoscaler != nil {
+		t.autoscaler.Stop()
+	}
+	if t.drainer != nil {
+		t.drainer.Stop()
+	}
+	t.connector.Stop()
+	if t.drainer != nil {
+		if err := t.drainer.Close(); err != nil {
+			log.Warn(err)
+		}
+	}
+}
+
+//startingTarget is a target whose connector is started in the background on a reload, the generation tells apart
+//several starts of the same target.
+type startingTarget struct {
+	signature  []interface{}
+	generation int
+}
+
+//livestatusSite is the running collector and cache of a livestatus site.
+type livestatusSite struct {
+	connector *livestatus.Connector
+	collector *livestatus.Collector
+	cache     *livestatus.CacheBuilder
+}
+
+//components keeps track of everything which is running, so a reload just has to replace the changed parts.
+type components struct {
+	mutex            sync.Mutex
+	cfg              config.Config
+	resultQueues     collector.ResultQueues
+	targets          map[data.Target]runningTarget
+	starting         map[data.Target]startingTarget
+	generation       int
+	stopped          bool
+	gearmanWorkers   map[string][]Stoppable
+	livestatusSites  map[string]livestatusSite
+	livestatusCaches livestatus.Sites
+	nagiosCollector  *spoolfile.NagiosSpoolfileCollector
+	nagfluxCollector *nagflux.FileCollector
+}
+
+func newComponents(resultQueues collector.ResultQueues) *components {
+	return &components{
+		resultQueues:   resultQueues,
+		targets:        map[data.Target]runningTarget{},
+		starting:       map[data.Target]startingTarget{},
+		gearmanWorkers: map[string][]Stoppable{},
+	}
+}
+
+//start starts everything defined by the config.
+func (c *components) start(cfg config.Config) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.cfg = cfg
+	for target, spec := range configuredTargets(cfg) {
+		c.startTarget(target, spec)
+	}
+	c.startLivestatus(cfg)
+	for name := range cfg.ModGearman {
+		c.startGearman(cfg, name)
+	}
+	c.startSpoolfileCollectors(cfg)
+}
+
+//reload compares the new config with the running one. Changed components are restarted, the others keep running.
+//New and changed targets are started in the background, their connectors may wait for the backend.
+func (c *components) reload(cfg config.Config) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.stopped {
+		return
+	}
+	old := c.cfg
+	c.cfg = cfg
+	if !reflect.DeepEqual(old.Log, cfg.Log) || !reflect.DeepEqual(old.Monitoring, cfg.Monitoring) {
+		log.Warn("Changes in the sections Log and Monitoring need a restart")
+	}
+	if old.Main.BufferSize != cfg.Main.BufferSize {
+		log.Warn("BufferSize is just used for new targets, the running ones need a restart")
+	}
+
+	targets := configuredTargets(cfg)
+	for target, running := range c.targets {
+		if spec, ok := targets[target]; !ok || !reflect.DeepEqual(spec.signature, running.signature) {
+			log.Infof("Stopping target %s(%s)", target.Name, target.Datatype)
+			running.Stop()
+			delete(c.targets, target)
+			if !ok {
+				c.resultQueues.Delete(target)
+				config.RemoveValue(target)
+			}
+		}
+	}
+	for target, starting := range c.starting {
+		if spec, ok := targets[target]; !ok || !reflect.DeepEqual(spec.signature, starting.signature) {
+			log.Infof("Dropping the start of target %s(%s)", target.Name, target.Datatype)
+			delete(c.starting, target)
+			if !ok {
+				c.resultQueues.Delete(target)
+				config.RemoveValue(target)
+			}
+		}
+	}
+	for target, spec := range targets {
+		_, isRunning := c.targets[target]
+		_, isStarting := c.starting[target]
+		if !isRunning && !isStarting {
+			log.Infof("Starting target %s(%s)", target.Name, target.Datatype)
+			c.startTargetInBackground(target, spec)
+		}
+	}
+
+	//The cache is used by the spoolfile collector and the gearman workers, so they have to follow
+	livestatusChanged := !reflect.DeepEqual(old.Livestatus, cfg.Livestatus)
+	spoolfileChanged := livestatusChanged || spoolfileSettings(old) != spoolfileSettings(cfg)
+	if spoolfileChanged {
+		c.stopSpoolfileCollectors()
+	}
+	for name, workers := range c.gearmanWorkers {
+		if livestatusChanged || !reflect.DeepEqual(old.ModGearman[name], cfg.ModGearman[name]) {
+			log.Infof("Stopping Mod_Gearman: %s", name)
+			stopAll(workers)
+			delete(c.gearmanWorkers, name)
+		}
+	}
+	if livestatusChanged {
+		log.Info("Restarting livestatus")
+		c.stopLivestatus()
+		c.startLivestatus(cfg)
+	}
+	for name := range cfg.ModGearman {
+		if _, ok := c.gearmanWorkers[name]; !ok {
+			c.startGearman(cfg, name)
+		}
+	}
+	if spoolfileChanged {
+		c.startSpoolfileCollectors(cfg)
+	}
+}
+
+//stoppables returns everything in the order it got started, cleanUp stops them in the reverse order.
+//Nothing is started afterwards, targets which are still starting are stopped as soon as they are up.
+func (c *components) stoppables() []Stoppable {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.stopped = true
+	var result []Stoppable
+	for _, running := range c.targets {
+		result = append(result, running)
+	}
+	for _, workers := range c.gearmanWorkers {
+		result = append(result, workers...)
+	}
+	for _, site := range c.livestatusSites {
+		result = append(result, site.collector, site.cache)
+	}
+	return append(result, c.nagiosCollector, c.nagfluxCollector)
+}
+
+//Targets returns the running targets for the admin API.
+func (c *components) Targets() []admin.Target {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var result []admin.Target
+	for target, running := range c.targets {
+		queue, _ := c.resultQueues.Get(target)
+		result = append(result, admin.Target{Target: target, Connector: running.connector, Queue: queue, Drainer: running.drainer})
+	}
+	return result
+}
+
+//Starts the target on a reload without holding the mutex, so neither the shutdown nor the admin API wait for a backend
+//which is down. The queue is created at once and collects the data meanwhile. A failing target must not take the others
+//down, a target which got changed, removed or stopped meanwhile is stopped again.
+func (c *components) startTargetInBackground(target data.Target, spec targetSpec) {
+	jobs := c.queue(target)
+	c.generation++
+	generation := c.generation
+	c.starting[target] = startingTarget{signature: spec.signature, generation: generation}
+	go func() {
+		var running runningTarget
+		failure := func() (failure interface{}) {
+			defer func() { failure = recover() }()
+			running = launchTarget(target, spec, jobs)
+			return nil
+		}()
+		c.mutex.Lock()
+		starting, ok := c.starting[target]
+		current := ok && starting.generation == generation
+		keep := current && failure == nil && !c.stopped
+		if current {
+			delete(c.starting, target)
+			if failure != nil {
+				c.resultQueues.Delete(target)
+			}
+		}
+		if keep {
+			c.targets[target] = running
+		}
+		c.mutex.Unlock()
+		if failure != nil {
+			log.Criticalf("Could not start %s(%s): %v", target.Name, target.Datatype, failure)
+		} else if !keep {
+			log.Infof("Stopping target %s(%s), it was changed or nagflux stopped while it was starting", target.Name, target.Datatype)
+			running.Stop()
+		}
+	}()
+}
+
+//Creates the queue if the target is new and starts it.
+func (c *components) startTarget(target data.Target, spec targetSpec) {
+	c.targets[target] = launchTarget(target, spec, c.queue(target))
+}
+
+//Returns the queue of the target, it's created if the target is new.
+func (c *components) queue(target data.Target) chan collector.Printable {
+	jobs, ok := c.resultQueues.Get(target)
+	if !ok {
+		jobs = make(chan collector.Printable, config.GetConfig().Main.BufferSize)
+		c.resultQueues.Set(target, jobs)
+	}
+	return jobs
+}
+
+//Starts the connector and the autoscaler of the target.
+func launchTarget(target data.Target, spec targetSpec, jobs chan collector.Printable) runningTarget {
+	connector, drainer := spec.start(jobs)
+	return runningTarget{
+		signature: spec.signature, connector: connector, drainer: drainer,
+		autoscaler: startAutoscaler(config.GetConfig(), target, connector, jobs),
+	}
+}
+
+//Scales the workers between InfluxWorker and MaxInfluxWorker, targets without workers are not scaled.
+func startAutoscaler(cfg config.Config, t data.Target, connector Stoppable, jobs chan collector.Printable) *target.Autoscaler {
+	scalable, ok := connector.(target.Scalable)
+	if !ok || cfg.Main.MaxInfluxWorker <= cfg.Main.InfluxWorker {
+		return nil
+	}
+	return target.NewAutoscaler(t, scalable, jobs, cfg.Main.InfluxWorker, cfg.Main.MaxInfluxWorker)
+}
+
+//Starts a collector and a cache per site. The unnamed [Livestatus] section of older configs has no Enabled, it's used anyway.
+//A site which can't be set up is not started, checkLivestatus rejects such configs before.
+func (c *components) startLivestatus(cfg config.Config) {
+	c.livestatusSites = map[string]livestatusSite{}
+	c.livestatusCaches = livestatus.Sites{}
+	for name, site := range cfg.Livestatus {
+		if site == nil || !(site.Enabled || name == "") {
+			continue
+		}
+		log.Infof("Livestatus: %s - %s [%s]", name, site.Address, site.Type)
+		connector, err := newLivestatusConnector(cfg, name)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+		running := livestatusSite{
+			connector: connector,
+			collector: livestatus.NewLivestatusCollector(c.resultQueues, connector, name, site.Version, site.MinutesToWait),
+			cache:     livestatus.NewLivestatusCacheBuilder(connector, site.MetadataTag),
+		}
+		c.livestatusSites[name] = running
+		c.livestatusCaches[name] = running.cache
+	}
+	routing.SetHostgroupSource(c.livestatusCaches)
+}
+
+//checkLivestatus returns an error if one of the enabled sites can't be set up, e.g. its TLS files are broken.
+func checkLivestatus(cfg config.Config) error {
+	for name, site := range cfg.Livestatus {
+		if site == nil || !(site.Enabled || name == "") {
+			continue
+		}
+		if _, err := newLivestatusConnector(cfg, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Returns the connector of the site, it does not connect yet.
+func newLivestatusConnector(cfg config.Config, name string) (*livestatus.Connector, error) {
+	site := cfg.Livestatus[name]
+	connector := &livestatus.Connector{Log: log, LivestatusAddress: site.Address, ConnectionType: site.Type,
+		AuthUser: site.AuthUser, KeepAlive: site.KeepAlive, Timeout: time.Duration(site.Timeout) * time.Second}
+	if site.Type == "tls" {
+		tlsConfig, err := livestatus.NewTLSConfig(site.TLSCA, site.TLSCertificate, site.TLSKey, site.TLSSkipVerify)
+		if err != nil {
+			return nil, fmt.Errorf("Livestatus %s TLS: %s", name, err)
+		}
+		connector.TLSConfig = tlsConfig
+	}
+	return connector, nil
+}
+
+func (c *components) stopLivestatus() {
+	log.Info("Stopping livestatus")
+	for _, site := range c.livestatusSites {
+		site.collector.Stop()
+		site.cache.Stop()
+		site.connector.Close()
+	}
+}
+
+func (c *components) startGearman(cfg config.Config, name string) {
+	data := cfg.ModGearman[name]
+	if data == nil || !(*data).Enabled {
+		return
+	}
+	log.Infof("Mod_Gearman: %s - %s [%s]", name, (*data).Address, (*data).Queue)
+	secret := modGearman.GetSecret((*data).Secret, (*data).SecretFile)
+	var workers []Stoppable
+	for i := 0; i < (*data).Worker; i++ {
+		gearmanWorker := modGearman.NewGearmanWorker((*data).Address,
+			(*data).Queue,
+			secret,
+			c.resultQueues,
+			c.livestatusCaches,
+		)
+		workers = append(workers, gearmanWorker)
+	}
+	c.gearmanWorkers[name] = workers
+}
+
+func (c *components) startSpoolfileCollectors(cfg config.Config) {
+	log.Info("Nagios Spoolfile Folder: ", cfg.Main.NagiosSpoolfileFolder)
+	defaultTarget := collector.RoutableFilterable
+	if cfg.Main.DefaultTarget != "" {
+		defaultTarget = collector.NewRoutableFilterable(cfg.Main.DefaultTarget)
+	}
+	c.nagiosCollector = spoolfile.NagiosSpoolfileCollectorFactory(
+		cfg.Main.NagiosSpoolfileFolder,
+		cfg.Main.SpoolfileWatchMode,
+		cfg.Main.NagiosSpoolfileWorker,
+		c.resultQueues,
+		c.livestatusCaches,
+		cfg.Main.FileBufferSize,
+		cfg.Main.MaxLineSize,
+		defaultTarget,
+	)
+
+	log.Info("Nagflux Spoolfile Folder: ", cfg.Main.NagfluxSpoolfileFolder)
+	fieldSeparator := []rune(cfg.Main.FieldSeparator)[0]
+	c.nagfluxCollector = nagflux.NewNagfluxFileCollector(c.resultQueues, cfg.Main.NagfluxSpoolfileFolder, cfg.Main.SpoolfileWatchMode, fieldSeparator)
+}
+
+func (c *components) stopSpoolfileCollectors() {
+	log.Info("Stopping the spoolfile collectors")
+	c.nagfluxCollector.Stop()
+	c.nagiosCollector.Stop()
+}
+
+func stopAll(stoppables []Stoppable) {
+	for i := len(stoppables) - 1; i >= 0; i-- {
+		stoppables[i].Stop()
+	}
+}
+
+//Returns the settings of the main section, which are used by the spoolfile collectors.
+func spoolfileSettings(cfg config.Config) string {
+	return fmt.Sprint(cfg.Main.NagiosSpoolfileFolder, cfg.Main.NagiosSpoolfileWorker, cfg.Main.FileBufferSize, cfg.Main.MaxLineSize,
+		cfg.Main.DefaultTarget, cfg.Main.NagfluxSpoolfileFolder, cfg.Main.FieldSeparator, cfg.Main.SpoolfileWatchMode)
+}
+
+//Returns the signature of a target, the settings of the other sections are used by every target.
+func targetSignature(cfg config.Config, section interface{}) []interface{} {
+	return []interface{}{
+		section, cfg.Main.DumpFile, cfg.Main.InfluxWorker, cfg.Main.MaxInfluxWorker,
+		cfg.Main.WriteAheadLogMaxSize, cfg.Main.WriteAheadLogSegmentSize, cfg.InfluxDBGlobal, cfg.ElasticsearchGlobal,
+	}
+}
+
+//configuredTargets returns every enabled target of the config.
+func configuredTargets(cfg config.Config) map[data.Target]targetSpec {
+	specs := map[data.Target]targetSpec{}
+
+	for name, value := range cfg.InfluxDB {
+		if value == nil || !(*value).Enabled {
+			continue
+		}
+		influxConfig := (*value)
+		target := data.Target{Name: name, Datatype: data.InfluxDB}
+		specs[target] = targetSpec{targetSignature(cfg, influxConfig), func(jobs chan collector.Printable) (Stoppable, *wal.Drainer) {
+			config.StoreValue(target, false)
+			influx := influx.ConnectorFactory(
+				jobs,
+				influxConfig.Address, influxConfig.Arguments, cfg.Main.DumpFile, influxConfig.Version,
+				cfg.Main.InfluxWorker, cfg.Main.MaxInfluxWorker, cfg.InfluxDBGlobal.CreateDatabaseIfNotExists,
+				influxConfig.StopPullingDataIfDown, target, cfg.InfluxDBGlobal.ClientTimeout,
+			)
+			return influx, startWriteAheadLog(cfg, target, jobs, influx.Encode, func() bool {
+				return influx.TestIfIsAlive(influxConfig.StopPullingDataIfDown)
+			})
+		}}
+	}
+
+	for name, value := range cfg.InfluxDB2 {
+		if value == nil || !(*value).Enabled {
+			continue
+		}
+		influxConfig := (*value)
+		target := data.Target{Name: name, Datatype: data.InfluxDB2}
+		specs[target] = targetSpec{targetSignature(cfg, influxConfig), func(jobs chan collector.Printable) (Stoppable, *wal.Drainer) {
+			config.StoreValue(target, false)
+			influx := influx.ConnectorV2Factory(
+				jobs,
+				influxConfig.Address, influxConfig.Organization, influxConfig.Bucket, influxConfig.Token, cfg.Main.DumpFile,
+				cfg.Main.InfluxWorker, cfg.Main.MaxInfluxWorker, influxConfig.CreateBucketIfNotExists,
+				influxConfig.StopPullingDataIfDown, target, cfg.InfluxDBGlobal.ClientTimeout,
+			)
+			return influx, startWriteAheadLog(cfg, target, jobs, influx.Encode, func() bool {
+				return influx.TestIfIsAlive(influxConfig.StopPullingDataIfDown)
+			})
+		}}
+	}
+
+	for name, value := range cfg.Elasticsearch {
+		if value == nil || !(*value).Enabled {
+			continue
+		}
+		elasticConfig := (*value)
+		target := data.Target{Name: name, Datatype: data.Elasticsearch}
+		specs[target] = targetSpec{targetSignature(cfg, elasticConfig), func(jobs chan collector.Printable) (Stoppable, *wal.Drainer) {
+			config.StoreValue(target, false)
+			elasticsearch := elasticsearch.ConnectorFactory(
+				jobs,
+				elasticConfig.Address, elasticConfig.Index, cfg.Main.DumpFile, elasticConfig.Version,
+				elasticConfig.Username, elasticConfig.Password, elasticConfig.APIKey,
+				cfg.Main.InfluxWorker, cfg.Main.MaxInfluxWorker, true, target,
+			)
+			return elasticsearch, startWriteAheadLog(cfg, target, jobs, elasticsearch.Encode, func() bool {
+				return elasticsearch.TestIfIsAlive()
+			})
+		}}
+	}
+
+	for name, value := range cfg.PrometheusRemoteWrite {
+		if value == nil || !(*value).Enabled {
+			continue
+		}
+		prometheusConfig := (*value)
+		if prometheusConfig.MetricPrefix == "" {
+			prometheusConfig.MetricPrefix = "nagflux"
+		}
+		target := data.Target{Name: name, Datatype: data.PrometheusRemoteWrite}
+		specs[target] = targetSpec{targetSignature(cfg, prometheusConfig), func(jobs chan collector.Printable) (Stoppable, *wal.Drainer) {
+			config.StoreValue(target, false)
+			remoteWrite := prometheus.ConnectorFactory(
+				jobs,
+				prometheusConfig.Address, prometheusConfig.MetricPrefix, cfg.Main.DumpFile,
+				cfg.Main.InfluxWorker, cfg.Main.MaxInfluxWorker,
+				prometheusConfig.StopPullingDataIfDown, target, cfg.InfluxDBGlobal.ClientTimeout,
+			)
+			return remoteWrite, startWriteAheadLog(cfg, target, jobs, remoteWrite.Encode, func() bool {
+				return remoteWrite.TestIfIsAlive(prometheusConfig.StopPullingDataIfDown)
+			})
+		}}
+	}
+
+	for name, value := range cfg.Graphite {
+		if value == nil || !(*value).Enabled {
+			continue
+		}
+		graphiteConfig := (*value)
+		if graphiteConfig.Template == "" {
+			graphiteConfig.Template = graphite.DefaultTemplate
+		}
+		target := data.Target{Name: name, Datatype: data.Graphite}
+		specs[target] = targetSpec{targetSignature(cfg, graphiteConfig), func(jobs chan collector.Printable) (Stoppable, *wal.Drainer) {
+			config.StoreValue(target, false)
+			carbon := graphite.ConnectorFactory(
+				jobs,
+				graphiteConfig.Address, graphiteConfig.Protocol, graphiteConfig.Template, graphiteConfig.Prefix, cfg.Main.DumpFile,
+				cfg.Main.InfluxWorker, cfg.Main.MaxInfluxWorker,
+				graphiteConfig.StopPullingDataIfDown, target, cfg.InfluxDBGlobal.ClientTimeout,
+			)
+			return carbon, startWriteAheadLog(cfg, target, jobs, carbon.Encode, func() bool {
+				return carbon.TestIfIsAlive(graphiteConfig.StopPullingDataIfDown)
+			})
+		}}
+	}
+
+	for name, value := range cfg.OpenTSDB {
+		if value == nil || !(*value).Enabled {
+			continue
+		}
+		openTSDBConfig := (*value)
+		if openTSDBConfig.MetricTemplate == "" {
+			openTSDBConfig.MetricTemplate = opentsdb.DefaultMetricTemplate
+		}
+		target := data.Target{Name: name, Datatype: data.OpenTSDB}
+		specs[target] = targetSpec{targetSignature(cfg, openTSDBConfig), func(jobs chan collector.Printable) (Stoppable, *wal.Drainer) {
+			config.StoreValue(target, false)
+			tsdb := opentsdb.ConnectorFactory(
+				jobs,
+				openTSDBConfig.Address, openTSDBConfig.MetricTemplate, cfg.Main.DumpFile,
+				cfg.Main.InfluxWorker, cfg.Main.MaxInfluxWorker,
+				openTSDBConfig.StopPullingDataIfDown, target, cfg.InfluxDBGlobal.ClientTimeout,
+			)
+			return tsdb, startWriteAheadLog(cfg, target, jobs, tsdb.Encode, func() bool {
+				return tsdb.TestIfIsAlive(openTSDBConfig.StopPullingDataIfDown)
+			})
+		}}
+	}
+
+	for name, value := range cfg.Kafka {
+		if value == nil || !(*value).Enabled {
+			continue
+		}
+		kafkaConfig := (*value)
+		topics := kafka.Topics{Perfdata: kafkaConfig.PerfdataTopic, Messages: kafkaConfig.MessagesTopic}
+		if topics.Perfdata == "" {
+			topics.Perfdata = kafka.DefaultPerfdataTopic
+		}
+		if topics.Messages == "" {
+			topics.Messages = kafka.DefaultMessagesTopic
+		}
+		if kafkaConfig.Format == "" {
+			kafkaConfig.Format = kafka.FormatInflux
+		}
+		if kafkaConfig.RequiredAcks == 0 {
+			kafkaConfig.RequiredAcks = 1
+		}
+		target := data.Target{Name: name, Datatype: data.Kafka}
+		specs[target] = targetSpec{targetSignature(cfg, kafkaConfig), func(jobs chan collector.Printable) (Stoppable, *wal.Drainer) {
+			config.StoreValue(target, false)
+			producer := kafka.ConnectorFactory(
+				jobs,
+				kafkaConfig.Brokers, topics, kafkaConfig.Format, kafkaConfig.RequiredAcks, cfg.Main.DumpFile,
+				cfg.Main.InfluxWorker, cfg.Main.MaxInfluxWorker,
+				kafkaConfig.StopPullingDataIfDown, target, cfg.InfluxDBGlobal.ClientTimeout,
+			)
+			return producer, startWriteAheadLog(cfg, target, jobs, producer.Encode, func() bool {
+				return producer.TestIfIsAlive(kafkaConfig.StopPullingDataIfDown)
+			})
+		}}
+	}
+
+	for name, value := range cfg.JSONFileExport {
+		if value == nil || !(*value).Enabled {
+			continue
+		}
+		jsonFileConfig := (*value)
+		target := data.Target{Name: name, Datatype: data.JSONFile}
+		specs[target] = targetSpec{targetSignature(cfg, jsonFileConfig), func(jobs chan collector.Printable) (Stoppable, *wal.Drainer) {
+			templateFile := json.NewJSONFileWorker(
+				log, jsonFileConfig.AutomaticFileRotation,
+				jobs, target, jsonFileConfig.Path,
+			)
+			return templateFile, nil
+		}}
+	}
+	return specs
+}
+
+//Opens the write-ahead log of the target, moves the dumpfile of older versions into it and starts draining it.
+//Returns nil if the log could not be opened, the workers fall back to the dumpfile in this case.
+func startWriteAheadLog(cfg config.Config, target data.Target, jobs chan collector.Printable, encode wal.Encoder, isAlive func() bool) *wal.Drainer {
+	maxSize := cfg.Main.WriteAheadLogMaxSize
+	if maxSize <= 0 {
+		maxSize = 1024
+	}
+	segmentSize := cfg.Main.WriteAheadLogSegmentSize
+	if segmentSize <= 0 {
+		segmentSize = 8
+	}
+	//Just complete segments are evicted, a larger one would exceed the max size
+	if segmentSize > maxSize {
+		log.Warnf("WriteAheadLogSegmentSize %d is larger than WriteAheadLogMaxSize, using %d", segmentSize, maxSize)
+		segmentSize = maxSize
+	}
+	dumpFile := nagflux.GenDumpfileName(cfg.Main.DumpFile, target)
+	queue, err := wal.Open(dumpFile+".wal", int64(segmentSize)<<20, int64(maxSize)<<20)
+	if err != nil {
+		log.Critical("Could not open the write-ahead log: ", err)
+		return nil
+	}
+	//Elasticsearch dumped whole bulk requests, every other target one entry per line
+	if err := wal.ImportDumpfile(queue, dumpFile, target.Datatype == data.Elasticsearch); err != nil {
+		log.Warn("Could not import the dumpfile: ", err)
+	}
+	return wal.NewDrainer(target, queue, jobs, encode, isAlive)
+}
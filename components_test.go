@@ -0,0 +1,96 @@
+package main
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/wal"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestCheckLivestatus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nagflux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, test := range []struct {
+		section string
+		valid   bool
+	}{
+		{"[Livestatus \"berlin\"]\nEnabled = true\nType = tls\nAddress = berlin:6557\nTLSSkipVerify = true\n", true},
+		{"[Livestatus \"berlin\"]\nEnabled = true\nType = tls\nAddress = berlin:6557\nTLSCA = " + path.Join(dir, "missing.pem") + "\n", false},
+		{"[Livestatus \"berlin\"]\nType = tls\nAddress = berlin:6557\nTLSCA = " + path.Join(dir, "missing.pem") + "\n", true},
+		{"[Livestatus]\nType = tls\nAddress = berlin:6557\nTLSCertificate = " + path.Join(dir, "missing.pem") + "\n", false},
+	} {
+		file := path.Join(dir, "config.gcfg")
+		if err := ioutil.WriteFile(file, []byte(test.section), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := config.LoadConfig(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := checkLivestatus(cfg); (err == nil) != test.valid {
+			t.Errorf("%s: expected valid %t, got %v", test.section, test.valid, err)
+		}
+	}
+}
+
+type fakeConnector struct {
+	stopped chan bool
+}
+
+func (f fakeConnector) Stop() {
+	f.stopped <- true
+}
+
+//The connector of the target waits for its backend, which must neither block the admin API nor the shutdown.
+func TestStartTargetInBackground(t *testing.T) {
+	logging.InitTestLogger()
+	log = logging.GetLogger()
+	c := newComponents(collector.ResultQueues{})
+	up := make(chan bool)
+	stopped := make(chan bool, 1)
+	spec := targetSpec{signature: []interface{}{"backend"}, start: func(jobs chan collector.Printable) (Stoppable, *wal.Drainer) {
+		<-up
+		return fakeConnector{stopped: stopped}, nil
+	}}
+
+	first := data.Target{Name: "first", Datatype: data.InfluxDB}
+	c.mutex.Lock()
+	c.startTargetInBackground(first, spec)
+	c.mutex.Unlock()
+	if _, ok := c.resultQueues.Get(first); !ok || len(c.Targets()) != 0 {
+		t.Fatal("The queue should exist while the target is starting, the target not")
+	}
+	up <- true
+	for timeout := time.Now().Add(time.Duration(1) * time.Second); len(c.Targets()) != 1; {
+		if time.Now().After(timeout) {
+			t.Fatal("The target was not added after it started")
+		}
+		time.Sleep(time.Duration(10) * time.Millisecond)
+	}
+
+	second := data.Target{Name: "second", Datatype: data.InfluxDB}
+	c.mutex.Lock()
+	c.startTargetInBackground(second, spec)
+	c.mutex.Unlock()
+	if len(c.stoppables()) != 3 {
+		t.Error("Just the running target should be stopped by the shutdown")
+	}
+	up <- true
+	select {
+	case <-stopped:
+	case <-time.After(time.Duration(1) * time.Second):
+		t.Error("A target which is up after the shutdown should be stopped")
+	}
+	if len(c.Targets()) != 1 {
+		t.Error("A target which is up after the shutdown should not be added")
+	}
+}
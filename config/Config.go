@@ -9,10 +9,16 @@ type Config struct {
 		MaxInfluxWorker        int
 		DumpFile               string
 		NagfluxSpoolfileFolder string
+		SpoolfileWatchMode     string
+		QuarantineFolder       string
 		FieldSeparator         string
 		BufferSize             int
 		FileBufferSize         int
+		MaxLineSize            int
 		DefaultTarget          string
+		//Sizes of the write-ahead logs in MB
+		WriteAheadLogMaxSize     int
+		WriteAheadLogSegmentSize int
 	}
 	ModGearman map[string]*struct {
 		Enabled    bool
@@ -28,13 +34,15 @@ type Config struct {
 	}
 	Monitoring struct {
 		PrometheusAddress string
+		AdminAddress      string
+		AdminToken        string
 	}
 	InfluxDBGlobal struct {
 		CreateDatabaseIfNotExists bool
 		NastyString               string
 		NastyStringToReplace      string
 		HostcheckAlias            string
-		ClientTimeout		      int
+		ClientTimeout             int
 	}
 	InfluxDB map[string]*struct {
 		Enabled               bool
@@ -43,11 +51,32 @@ type Config struct {
 		Version               string
 		StopPullingDataIfDown bool
 	}
-	Livestatus struct {
+	InfluxDB2 map[string]*struct {
+		Enabled                 bool
+		Address                 string
+		Organization            string
+		Bucket                  string
+		Token                   string
+		CreateBucketIfNotExists bool
+		StopPullingDataIfDown   bool
+	}
+	Livestatus map[string]*struct {
+		Enabled       bool
 		Type          string
 		Address       string
 		MinutesToWait int
 		Version       string
+		MetadataTag   []string
+
+		//PEM files for the Type tls
+		TLSCA          string
+		TLSCertificate string
+		TLSKey         string
+		TLSSkipVerify  bool
+		AuthUser       string
+		KeepAlive      bool
+		//Timeout in seconds for connecting and every query
+		Timeout int
 	}
 	ElasticsearchGlobal struct {
 		HostcheckAlias   string
@@ -56,10 +85,66 @@ type Config struct {
 		IndexRotation    string
 	}
 	Elasticsearch map[string]*struct {
-		Enabled bool
-		Address string
-		Index   string
-		Version string
+		Enabled  bool
+		Address  string
+		Index    string
+		Version  string
+		Username string
+		Password string
+		APIKey   string
+	}
+	PrometheusRemoteWrite map[string]*struct {
+		Enabled               bool
+		Address               string
+		MetricPrefix          string
+		StopPullingDataIfDown bool
+	}
+	Graphite map[string]*struct {
+		Enabled               bool
+		Address               string
+		Protocol              string
+		Prefix                string
+		Template              string
+		StopPullingDataIfDown bool
+	}
+	OpenTSDB map[string]*struct {
+		Enabled               bool
+		Address               string
+		MetricTemplate        string
+		StopPullingDataIfDown bool
+	}
+	Kafka map[string]*struct {
+		Enabled               bool
+		Brokers               string
+		PerfdataTopic         string
+		MessagesTopic         string
+		Format                string
+		RequiredAcks          int
+		StopPullingDataIfDown bool
+	}
+	Processor map[string]*struct {
+		Enabled     bool
+		Type        string
+		Order       int
+		Targets     string
+		Tag         string
+		Field       string
+		Pattern     string
+		Replacement string
+		To          string
+		Value       string
+	}
+	Route map[string]*struct {
+		Enabled   bool
+		Order     int
+		Targets   string
+		Host      string
+		Service   string
+		Command   string
+		Label     string
+		Hostgroup string
+		Tag       []string
+		Regex     bool
 	}
 	JSONFileExport map[string]*struct {
 		Enabled               bool
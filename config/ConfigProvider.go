@@ -21,9 +21,25 @@ func InitConfig(configPath string) {
 
 //GetConfig returns the static config object
 func GetConfig() Config {
+	mutex.Lock()
+	defer mutex.Unlock()
 	return config
 }
 
+//LoadConfig reads the config file without replacing the current config, use SetConfig to apply it.
+func LoadConfig(configPath string) (Config, error) {
+	var newConfig Config
+	err := gcfg.ReadFileInto(&newConfig, configPath)
+	return newConfig, err
+}
+
+//SetConfig replaces the current config, used on a reload
+func SetConfig(newConfig Config) {
+	mutex.Lock()
+	config = newConfig
+	mutex.Unlock()
+}
+
 //InitConfigFromString creates a config object from the give configstring primary for testing
 func InitConfigFromString(configString string) {
 	var err error
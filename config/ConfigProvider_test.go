@@ -2,8 +2,10 @@ package config
 
 import (
 	"bufio"
+	"gopkg.in/gcfg.v1"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -109,3 +111,53 @@ func TestInitConfigFromString(t *testing.T) {
 		t.Errorf("Content did not match %d != %d", cfg.Main.MaxInfluxWorker, 5)
 	}
 }
+
+func TestLoadConfig(t *testing.T) {
+	InitConfigFromString(configFileContent)
+	file, err := ioutil.TempFile(os.TempDir(), "prefix")
+	if err != nil {
+		panic(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString(strings.Replace(configFileContent, "MaxInfluxWorker = 5", "MaxInfluxWorker = 7", 1))
+	file.Close()
+
+	cfg, err := LoadConfig(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Main.MaxInfluxWorker != 7 {
+		t.Errorf("Content did not match %d != %d", cfg.Main.MaxInfluxWorker, 7)
+	}
+	if GetConfig().Main.MaxInfluxWorker != 5 {
+		t.Error("LoadConfig should not replace the current config")
+	}
+	SetConfig(cfg)
+	if GetConfig().Main.MaxInfluxWorker != 7 {
+		t.Error("SetConfig did not replace the current config")
+	}
+
+	if _, err := LoadConfig(file.Name() + "-missing"); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestLegacyLivestatusSection(t *testing.T) {
+	var cfg Config
+	legacy := configFileContent + `
+
+[Livestatus "munich"]
+	Enabled = true
+	Type = "tcp"
+	Address = "munich:6557"`
+	if err := gcfg.ReadStringInto(&cfg, legacy); err != nil {
+		t.Fatal(err)
+	}
+	site, ok := cfg.Livestatus[""]
+	if !ok || site.Address != "127.0.0.1:6557" || site.Type != "tcp" || site.MinutesToWait != 2 {
+		t.Errorf("The unnamed Livestatus section should be the site \"\": %v", cfg.Livestatus)
+	}
+	if site, ok := cfg.Livestatus["munich"]; !ok || site.Address != "munich:6557" {
+		t.Errorf("The named site is missing: %v", cfg.Livestatus)
+	}
+}
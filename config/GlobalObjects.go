@@ -11,6 +11,9 @@ type PauseMap map[data.Target]bool
 //pauseNagflux is used to sync the state of the influxdb
 var pauseNagflux = PauseMap{}
 
+//manualPause contains the targets which got paused by the admin API
+var manualPause = PauseMap{}
+
 var objMutex = &sync.Mutex{}
 
 //IsAnyTargetOnPause will return true if any target requested pause, false otherwise
@@ -23,6 +26,12 @@ func IsAnyTargetOnPause() bool {
 			break
 		}
 	}
+	for _, v := range manualPause {
+		if v {
+			result = true
+			break
+		}
+	}
 	objMutex.Unlock()
 	return result
 }
@@ -32,3 +41,25 @@ func StoreValue(target data.Target, value bool) {
 	pauseNagflux[target] = value
 	objMutex.Unlock()
 }
+
+//RemoveValue forgets the target, used if a target got removed from the config
+func RemoveValue(target data.Target) {
+	objMutex.Lock()
+	delete(pauseNagflux, target)
+	delete(manualPause, target)
+	objMutex.Unlock()
+}
+
+//StoreManualPause pauses or resumes the target on request, this is independent of its state.
+func StoreManualPause(target data.Target, value bool) {
+	objMutex.Lock()
+	manualPause[target] = value
+	objMutex.Unlock()
+}
+
+//GetPause returns if the target requested pause by itself and if it got paused manually
+func GetPause(target data.Target) (bool, bool) {
+	objMutex.Lock()
+	defer objMutex.Unlock()
+	return pauseNagflux[target], manualPause[target]
+}
@@ -24,3 +24,31 @@ func TestStoreValue(t *testing.T) {
 		t.Error("One target should be at pause")
 	}
 }
+
+func TestRemoveValue(t *testing.T) {
+	pauseNagflux = PauseMap{}
+	target := data.Target{Name: "foo", Datatype: data.InfluxDB}
+	StoreValue(target, true)
+	RemoveValue(target)
+	if IsAnyTargetOnPause() {
+		t.Error("A removed target should not pause nagflux")
+	}
+}
+
+func TestStoreManualPause(t *testing.T) {
+	pauseNagflux = PauseMap{}
+	manualPause = PauseMap{}
+	target := data.Target{Name: "foo", Datatype: data.InfluxDB}
+	StoreValue(target, false)
+	StoreManualPause(target, true)
+	if !IsAnyTargetOnPause() {
+		t.Error("The target got paused manually")
+	}
+	if paused, manual := GetPause(target); paused || !manual {
+		t.Errorf("Unexpected pause state: %t %t", paused, manual)
+	}
+	StoreManualPause(target, false)
+	if IsAnyTargetOnPause() {
+		t.Error("The target got resumed")
+	}
+}
@@ -6,8 +6,18 @@ type Datatype string
 const (
 	//InfluxDB enum
 	InfluxDB Datatype = "influx"
+	//InfluxDB2 enum
+	InfluxDB2 Datatype = "influx2"
 	//Elasticsearch enum
 	Elasticsearch Datatype = "elastic"
+	//PrometheusRemoteWrite enum
+	PrometheusRemoteWrite Datatype = "prometheus"
+	//Graphite enum
+	Graphite Datatype = "graphite"
+	//OpenTSDB enum
+	OpenTSDB Datatype = "opentsdb"
+	//Kafka enum
+	Kafka Datatype = "kafka"
 	//TemplateFile enum
 	JSONFile Datatype = "json"
 )
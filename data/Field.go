@@ -0,0 +1,222 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+//FieldType is the type of a field value, the targets encode the value according to it.
+type FieldType int
+
+const (
+	//FloatField is the default, like in the line protocol.
+	FloatField FieldType = iota
+	//IntField is a signed integer, 1i in the line protocol.
+	IntField
+	//UintField is an unsigned integer, 1u in the line protocol.
+	UintField
+	//BoolField is true or false.
+	BoolField
+	//StringField is a quoted text.
+	StringField
+)
+
+//FieldValue is a typed value of a measurement. Just the member matching the type is set.
+type FieldValue struct {
+	Type  FieldType
+	Float float64
+	Int   int64
+	Uint  uint64
+	Bool  bool
+	Text  string
+}
+
+//Fields maps the field names to their values.
+type Fields map[string]FieldValue
+
+//Float creates a float field.
+func Float(value float64) FieldValue {
+	return FieldValue{Type: FloatField, Float: value}
+}
+
+//Int creates a signed integer field.
+func Int(value int64) FieldValue {
+	return FieldValue{Type: IntField, Int: value}
+}
+
+//Uint creates an unsigned integer field.
+func Uint(value uint64) FieldValue {
+	return FieldValue{Type: UintField, Uint: value}
+}
+
+//Bool creates a boolean field.
+func Bool(value bool) FieldValue {
+	return FieldValue{Type: BoolField, Bool: value}
+}
+
+//String creates a string field.
+func String(value string) FieldValue {
+	return FieldValue{Type: StringField, Text: value}
+}
+
+//ParseFieldValue reads a field value written like in the line protocol: 1.5 or 1 is a float, 1i an integer,
+//1u an unsigned integer, true or f a boolean and "text" a string. Anything else is taken as string as it is.
+//NaN and infinite numbers are rejected with false, the databases can't store them and would refuse the whole batch.
+func ParseFieldValue(input string) (FieldValue, bool) {
+	if len(input) >= 2 && strings.HasPrefix(input, `"`) && strings.HasSuffix(input, `"`) {
+		return String(strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(input[1 : len(input)-1])), true
+	}
+	switch input {
+	case "t", "T", "true", "True", "TRUE":
+		return Bool(true), true
+	case "f", "F", "false", "False", "FALSE":
+		return Bool(false), true
+	}
+	if strings.HasSuffix(input, "i") {
+		if value, err := strconv.ParseInt(input[:len(input)-1], 10, 64); err == nil {
+			return Int(value), true
+		}
+	}
+	if strings.HasSuffix(input, "u") {
+		if value, err := strconv.ParseUint(input[:len(input)-1], 10, 64); err == nil {
+			return Uint(value), true
+		}
+	}
+	value, err := strconv.ParseFloat(input, 64)
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		//also 1e999, which is out of range
+		return FieldValue{}, false
+	}
+	if err == nil {
+		return Float(value), true
+	}
+	return String(input), true
+}
+
+//Float64 returns the value as float for targets which just know numbers, booleans and strings return false.
+func (v FieldValue) Float64() (float64, bool) {
+	switch v.Type {
+	case FloatField:
+		return v.Float, true
+	case IntField:
+		return float64(v.Int), true
+	case UintField:
+		return float64(v.Uint), true
+	}
+	return 0, false
+}
+
+//LineProtocol encodes the value for InfluxDB. Unsigned integers are written as integers
+//if the database does not support them, InfluxDB 1.x doesn't by default.
+func (v FieldValue) LineProtocol(unsignedSupported bool) string {
+	switch v.Type {
+	case IntField:
+		return strconv.FormatInt(v.Int, 10) + "i"
+	case UintField:
+		if unsignedSupported {
+			return strconv.FormatUint(v.Uint, 10) + "u"
+		}
+		if v.Uint <= math.MaxInt64 {
+			return strconv.FormatUint(v.Uint, 10) + "i"
+		}
+		return formatFloat(float64(v.Uint))
+	case BoolField:
+		return strconv.FormatBool(v.Bool)
+	case StringField:
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v.Text) + `"`
+	}
+	return formatFloat(v.Float)
+}
+
+//MarshalJSON encodes the value as native JSON type, floats keep a decimal point.
+func (v FieldValue) MarshalJSON() ([]byte, error) {
+	switch v.Type {
+	case IntField:
+		return []byte(strconv.FormatInt(v.Int, 10)), nil
+	case UintField:
+		return []byte(strconv.FormatUint(v.Uint, 10)), nil
+	case BoolField:
+		return []byte(strconv.FormatBool(v.Bool)), nil
+	case StringField:
+		return json.Marshal(v.Text)
+	}
+	if math.IsNaN(v.Float) || math.IsInf(v.Float, 0) {
+		return []byte("null"), nil
+	}
+	return []byte(formatFloat(v.Float)), nil
+}
+
+//UnmarshalJSON reads what MarshalJSON wrote, numbers without decimal point or exponent become integers.
+func (v *FieldValue) UnmarshalJSON(raw []byte) error {
+	var decoded interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded); err != nil {
+		return err
+	}
+	switch value := decoded.(type) {
+	case bool:
+		*v = Bool(value)
+	case string:
+		*v = String(value)
+	case json.Number:
+		if !strings.ContainsAny(value.String(), ".eE") {
+			if i, err := strconv.ParseInt(value.String(), 10, 64); err == nil {
+				*v = Int(i)
+				return nil
+			}
+			if u, err := strconv.ParseUint(value.String(), 10, 64); err == nil {
+				*v = Uint(u)
+				return nil
+			}
+		}
+		f, err := value.Float64()
+		if err != nil {
+			return err
+		}
+		*v = Float(f)
+	case nil:
+		*v = Float(math.NaN())
+	default:
+		return fmt.Errorf("Not a field value: %s", raw)
+	}
+	return nil
+}
+
+//String returns the value without any type suffix or quotes.
+func (v FieldValue) String() string {
+	switch v.Type {
+	case IntField:
+		return strconv.FormatInt(v.Int, 10)
+	case UintField:
+		return strconv.FormatUint(v.Uint, 10)
+	case BoolField:
+		return strconv.FormatBool(v.Bool)
+	case StringField:
+		return v.Text
+	}
+	return formatFloat(v.Float)
+}
+
+//Formats like encoding/json but with a decimal point, so it's not mistaken for an integer by the databases.
+func formatFloat(value float64) string {
+	abs := math.Abs(value)
+	format := byte('f')
+	if abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	result := strconv.FormatFloat(value, format, -1, 64)
+	if format == 'e' {
+		//1e-07 -> 1e-7
+		if n := len(result); n >= 4 && result[n-4] == 'e' && result[n-3] == '-' && result[n-2] == '0' {
+			result = result[:n-2] + result[n-1:]
+		}
+	} else if !strings.ContainsAny(result, ".NI") {
+		result += ".0"
+	}
+	return result
+}
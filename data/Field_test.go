@@ -0,0 +1,78 @@
+package data
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+var ParseFieldValueData = []struct {
+	input    string
+	expected FieldValue
+}{
+	{"1.5", Float(1.5)},
+	{"1", Float(1)},
+	{"-2e-3", Float(-0.002)},
+	{"12i", Int(12)},
+	{"-12i", Int(-12)},
+	{"12u", Uint(12)},
+	{"-12u", String("-12u")},
+	{"true", Bool(true)},
+	{"F", Bool(false)},
+	{`"a \"quoted\" text"`, String(`a "quoted" text`)},
+	{"text", String("text")},
+	{"NaN1", String("NaN1")},
+	{"", String("")},
+}
+
+func TestParseFieldValue(t *testing.T) {
+	t.Parallel()
+	for _, data := range ParseFieldValueData {
+		if actual, ok := ParseFieldValue(data.input); !ok || actual != data.expected {
+			t.Errorf("ParseFieldValue(%s): expected %v, got %v", data.input, data.expected, actual)
+		}
+	}
+	for _, input := range []string{"NaN", "nan", "Inf", "+Inf", "-inf", "Infinity", "1e999", "-1e999"} {
+		if actual, ok := ParseFieldValue(input); ok {
+			t.Errorf("ParseFieldValue(%s): should be rejected, got %v", input, actual)
+		}
+	}
+}
+
+var EncodeFieldValueData = []struct {
+	input        FieldValue
+	lineProtocol string
+	json         string
+}{
+	{Float(4), "4.0", "4.0"},
+	{Float(0.004118), "0.004118", "0.004118"},
+	{Float(-1.2e-7), "-1.2e-7", "-1.2e-7"},
+	{Float(1e21), "1e+21", "1e+21"},
+	{Float(128766), "128766.0", "128766.0"},
+	{Int(-3), "-3i", "-3"},
+	{Uint(math.MaxUint64), "18446744073709552000.0", "18446744073709551615"},
+	{Uint(3), "3i", "3"},
+	{Bool(true), "true", "true"},
+	{String(`C:\ "x"`), `"C:\\ \"x\""`, `"C:\\ \"x\""`},
+}
+
+func TestEncodeFieldValue(t *testing.T) {
+	t.Parallel()
+	for _, data := range EncodeFieldValueData {
+		if actual := data.input.LineProtocol(false); actual != data.lineProtocol {
+			t.Errorf("LineProtocol(%v): expected %s, got %s", data.input, data.lineProtocol, actual)
+		}
+		raw, err := json.Marshal(data.input)
+		if err != nil || string(raw) != data.json {
+			t.Errorf("MarshalJSON(%v): expected %s, got %s %v", data.input, data.json, raw, err)
+		}
+		//JSON does not know unsigned integers, so just the value is compared
+		var decoded FieldValue
+		if err := json.Unmarshal(raw, &decoded); err != nil || decoded.String() != data.input.String() {
+			t.Errorf("UnmarshalJSON(%s): expected %v, got %v %v", raw, data.input, decoded, err)
+		}
+	}
+	if actual := Uint(3).LineProtocol(true); actual != "3u" {
+		t.Errorf("expected 3u, got %s", actual)
+	}
+}
@@ -44,3 +44,21 @@ func GenIndex(index, timeString string) string {
 		panic(fmt.Sprintf("The given IndexRotation[%s] is not supported", rotation))
 	}
 }
+
+//IsOpenSearch checks if the version belongs to an OpenSearch cluster, the version has to start with "opensearch".
+func IsOpenSearch(version string) bool {
+	return strings.HasPrefix(strings.ToLower(version), "opensearch")
+}
+
+//ElasticUsesMappingTypes is true for Elasticsearch below 7, mapping types are gone in 7 and in OpenSearch.
+func ElasticUsesMappingTypes(version string) bool {
+	return !IsOpenSearch(version) && VersionOrdinal(version) < VersionOrdinal("7.0")
+}
+
+//GenElasticBulkHeader generates the action line of a bulk request, the type is just added if the version supports it.
+func GenElasticBulkHeader(version, index, typ string) string {
+	if ElasticUsesMappingTypes(version) {
+		return fmt.Sprintf(`{"index":{"_index":"%s","_type":"%s"}}`, index, typ)
+	}
+	return fmt.Sprintf(`{"index":{"_index":"%s"}}`, index)
+}
@@ -152,3 +152,25 @@ func didThisPanic(f func(string, string) string, arg1, arg2 string) (result bool
 	f(arg1, arg2)
 	return false
 }
+
+var GenElasticBulkHeaderData = []struct {
+	version  string
+	expected string
+}{
+	{"2.1", `{"index":{"_index":"index","_type":"metrics"}}`},
+	{"6.8", `{"index":{"_index":"index","_type":"metrics"}}`},
+	{"7.0", `{"index":{"_index":"index"}}`},
+	{"8.11", `{"index":{"_index":"index"}}`},
+	{"opensearch-1.3", `{"index":{"_index":"index"}}`},
+	{"OpenSearch", `{"index":{"_index":"index"}}`},
+}
+
+func TestGenElasticBulkHeader(t *testing.T) {
+	t.Parallel()
+	for _, data := range GenElasticBulkHeaderData {
+		actual := GenElasticBulkHeader(data.version, "index", "metrics")
+		if actual != data.expected {
+			t.Errorf("GenElasticBulkHeader(%s): expected:%s, actual:%s", data.version, data.expected, actual)
+		}
+	}
+}
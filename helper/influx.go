@@ -2,6 +2,8 @@ package helper
 
 import (
 	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"sort"
 	"strings"
 )
 
@@ -36,3 +38,18 @@ func SanitizeMap(input map[string]string) map[string]string {
 	}
 	return result
 }
+
+//PrintFieldsForInfluxDB prints the fields in the line protocol, sorted by key. Unsigned integers need InfluxDB 2.0+.
+func PrintFieldsForInfluxDB(fields data.Fields, version string) string {
+	unsignedSupported := VersionOrdinal(version) >= VersionOrdinal("2.0")
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, SanitizeInfluxInput(key)+"="+fields[key].LineProtocol(unsignedSupported))
+	}
+	return strings.Join(result, ",")
+}
@@ -2,6 +2,7 @@ package helper
 
 import (
 	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
 	"reflect"
 	"testing"
 )
@@ -56,3 +57,18 @@ func TestSanitizeMap(t *testing.T) {
 		}
 	}
 }
+
+func TestPrintFieldsForInfluxDB(t *testing.T) {
+	fields := data.Fields{
+		"value": data.Float(4), "count": data.Int(-3), "bytes": data.Uint(7),
+		"unknown": data.Bool(true), "message": data.String(`say "hi"`),
+	}
+	expected := `bytes=7i,count=-3i,message="say \"hi\"",unknown=true,value=4.0`
+	if actual := PrintFieldsForInfluxDB(fields, "1.0"); actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+	expected = `bytes=7u,count=-3i,message="say \"hi\"",unknown=true,value=4.0`
+	if actual := PrintFieldsForInfluxDB(fields, "2.0"); actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
@@ -37,18 +37,6 @@ func StringToMap(input, entrySplitter, keyValueSplitter string) map[string]strin
 	return result
 }
 
-//StringIntToStringFloat adds a '.0' to a string if it does not contain a dot.
-func StringIntToStringFloat(inputInt string) string {
-	if inputInt == "" {
-		return inputInt
-	}
-
-	if !strings.Contains(inputInt, ".") {
-		inputInt += ".0"
-	}
-	return inputInt
-}
-
 //CastStringTimeFromSToMs adds three zeros to the timestring to cast from Seconds to Milliseconds.
 func CastStringTimeFromSToMs(time string) string {
 	return time + "000"
@@ -28,25 +28,6 @@ func TestStringToMap(t *testing.T) {
 	}
 }
 
-var StringIntToStringFloatData = []struct {
-	input    string
-	expected string
-}{
-	{"1.0", "1.0"},
-	{"1", "1.0"},
-	{"", ""},
-}
-
-func TestStringIntToStringFloat(t *testing.T) {
-	t.Parallel()
-	for _, data := range StringIntToStringFloatData {
-		actual := StringIntToStringFloat(data.input)
-		if actual != data.expected {
-			t.Errorf("StringIntToStringFloat(%s): expected:%s, actual:%s", data.input, data.expected, actual)
-		}
-	}
-}
-
 var CastStringTimeFromSToMsData = []struct {
 	input    string
 	expected string
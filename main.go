@@ -3,18 +3,14 @@ package main
 import (
 	"flag"
 	"fmt"
+	"github.com/spitefulgrog/nagflux/admin"
 	"github.com/spitefulgrog/nagflux/collector"
-	"github.com/spitefulgrog/nagflux/collector/livestatus"
-	"github.com/spitefulgrog/nagflux/collector/modGearman"
-	"github.com/spitefulgrog/nagflux/collector/nagflux"
-	"github.com/spitefulgrog/nagflux/collector/spoolfile"
 	"github.com/spitefulgrog/nagflux/config"
-	"github.com/spitefulgrog/nagflux/data"
 	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/processor"
+	"github.com/spitefulgrog/nagflux/quarantine"
+	"github.com/spitefulgrog/nagflux/routing"
 	"github.com/spitefulgrog/nagflux/statistics"
-	"github.com/spitefulgrog/nagflux/target/elasticsearch"
-	"github.com/spitefulgrog/nagflux/target/file/json"
-	"github.com/spitefulgrog/nagflux/target/influx"
 	"github.com/kdar/factorlog"
 	"os"
 	"os/signal"
@@ -27,9 +23,6 @@ type Stoppable interface {
 	Stop()
 }
 
-//Interval of the main loop, in which the amount of workers are calculated.
-const updateRate = 1
-
 //nagfluxVersion contains the current Github-Release
 const nagfluxVersion string = "v0.4.1"
 
@@ -46,8 +39,7 @@ Commandline Parameter:
 -configPath Path to the config file. If no file path is given the default is ./config.gcfg.
 -V Print version and exit
 
-For further informations / bugs reportes: https://github.com/Griesbacher/nagflux
-`)
+For further informations / bugs reportes: https://github.com/Griesbacher/nagflux`)
 	}
 	flag.StringVar(&configPath, "configPath", "config.gcfg", "path to the config file")
 	flag.BoolVar(&printver, "V", false, "print version and exit")
@@ -74,145 +66,90 @@ For further informations / bugs reportes: https://github.com/Griesbacher/nagflux
 	log.Info(`Started Nagflux `, nagfluxVersion)
 	log.Debugf("Using Config: %s", configPath)
 	resultQueues := collector.ResultQueues{}
-	stoppables := []Stoppable{}
 	if len(cfg.Main.FieldSeparator) < 1 {
 		panic("FieldSeparator is too short!")
 	}
-	pro := statistics.NewPrometheusServer(cfg.Monitoring.PrometheusAddress)
-	pro.WatchResultQueueLength(resultQueues)
-	fieldSeparator := []rune(cfg.Main.FieldSeparator)[0]
-
-	for name, value := range cfg.InfluxDB {
-		if value == nil || !(*value).Enabled {
-			continue
-		}
-		influxConfig := (*value)
-		target := data.Target{Name: name, Datatype: data.InfluxDB}
-		config.StoreValue(target, false)
-		resultQueues[target] = make(chan collector.Printable, cfg.Main.BufferSize)
-		influx := influx.ConnectorFactory(
-			resultQueues[target],
-			influxConfig.Address, influxConfig.Arguments, cfg.Main.DumpFile, influxConfig.Version,
-			cfg.Main.InfluxWorker, cfg.Main.MaxInfluxWorker, cfg.InfluxDBGlobal.CreateDatabaseIfNotExists,
-			influxConfig.StopPullingDataIfDown, target, cfg.InfluxDBGlobal.ClientTimeout,
-		)
-		stoppables = append(stoppables, influx)
-		influxDumpFileCollector := nagflux.NewDumpfileCollector(resultQueues[target], cfg.Main.DumpFile, target, cfg.Main.FileBufferSize)
-		waitForDumpfileCollector(influxDumpFileCollector)
-		stoppables = append(stoppables, influxDumpFileCollector)
+	if err := processor.Configure(cfg); err != nil {
+		panic(err)
 	}
-
-	for name, value := range cfg.Elasticsearch {
-		if value == nil || !(*value).Enabled {
-			continue
-		}
-		elasticConfig := (*value)
-		target := data.Target{Name: name, Datatype: data.Elasticsearch}
-		resultQueues[target] = make(chan collector.Printable, cfg.Main.BufferSize)
-		config.StoreValue(target, false)
-		elasticsearch := elasticsearch.ConnectorFactory(
-			resultQueues[target],
-			elasticConfig.Address, elasticConfig.Index, cfg.Main.DumpFile, elasticConfig.Version,
-			cfg.Main.InfluxWorker, cfg.Main.MaxInfluxWorker, true,
-		)
-		stoppables = append(stoppables, elasticsearch)
-		elasticDumpFileCollector := nagflux.NewDumpfileCollector(resultQueues[target], cfg.Main.DumpFile, target, cfg.Main.FileBufferSize)
-		waitForDumpfileCollector(elasticDumpFileCollector)
-		stoppables = append(stoppables, elasticDumpFileCollector)
+	if err := routing.Configure(cfg); err != nil {
+		panic(err)
 	}
-
-	for name, value := range cfg.JSONFileExport {
-		if value == nil || !(*value).Enabled {
-			continue
-		}
-		jsonFileConfig := (*value)
-		target := data.Target{Name: name, Datatype: data.JSONFile}
-		resultQueues[target] = make(chan collector.Printable, cfg.Main.BufferSize)
-		templateFile := json.NewJSONFileWorker(
-			log, jsonFileConfig.AutomaticFileRotation,
-			resultQueues[target], target, jsonFileConfig.Path,
-		)
-		stoppables = append(stoppables, templateFile)
+	if err := quarantine.Configure(cfg); err != nil {
+		panic(err)
 	}
-
-	//Some time for the dumpfile to fill the queue
-	time.Sleep(time.Duration(100) * time.Millisecond)
-
-	liveconnector := &livestatus.Connector{log, cfg.Livestatus.Address, cfg.Livestatus.Type}
-	livestatusCollector := livestatus.NewLivestatusCollector(resultQueues, liveconnector, cfg.Livestatus.Version)
-	livestatusCache := livestatus.NewLivestatusCacheBuilder(liveconnector)
-
-	for name, data := range cfg.ModGearman {
-		if data == nil || !(*data).Enabled {
-			continue
-		}
-		log.Infof("Mod_Gearman: %s - %s [%s]", name, (*data).Address, (*data).Queue)
-		secret := modGearman.GetSecret((*data).Secret, (*data).SecretFile)
-		for i := 0; i < (*data).Worker; i++ {
-			gearmanWorker := modGearman.NewGearmanWorker((*data).Address,
-				(*data).Queue,
-				secret,
-				resultQueues,
-				livestatusCache,
-			)
-			stoppables = append(stoppables, gearmanWorker)
-		}
+	if err := checkLivestatus(cfg); err != nil {
+		panic(err)
 	}
+	pro := statistics.NewPrometheusServer(cfg.Monitoring.PrometheusAddress)
+	pro.WatchResultQueueLength(resultQueues)
 
-	log.Info("Nagios Spoolfile Folder: ", cfg.Main.NagiosSpoolfileFolder)
-	nagiosCollector := spoolfile.NagiosSpoolfileCollectorFactory(
-		cfg.Main.NagiosSpoolfileFolder,
-		cfg.Main.NagiosSpoolfileWorker,
-		resultQueues,
-		livestatusCache,
-		cfg.Main.FileBufferSize,
-		collector.Filterable{Filter: cfg.Main.DefaultTarget},
-	)
-
-	log.Info("Nagflux Spoolfile Folder: ", cfg.Main.NagfluxSpoolfileFolder)
-	nagfluxCollector := nagflux.NewNagfluxFileCollector(resultQueues, cfg.Main.NagfluxSpoolfileFolder, fieldSeparator)
+	running := newComponents(resultQueues)
+	running.start(cfg)
+	if cfg.Monitoring.AdminAddress != "" {
+		admin.NewServer(running, cfg.Monitoring.AdminToken).Start(cfg.Monitoring.AdminAddress, cfg.Monitoring.PrometheusAddress)
+	}
 
-	//Listen for Interrupts
+	//Listen for Interrupts and reloads
 	interruptChannel := make(chan os.Signal, 1)
 	signal.Notify(interruptChannel, syscall.SIGINT)
 	signal.Notify(interruptChannel, syscall.SIGTERM)
+	reloadChannel := make(chan os.Signal, 1)
+	signal.Notify(reloadChannel, syscall.SIGHUP)
+	//A reload must not delay the shutdown, so both have their own goroutine
+	go func() {
+		for range reloadChannel {
+			reloadConfig(configPath, running)
+		}
+	}()
 	go func() {
 		<-interruptChannel
 		log.Warn("Got Interrupted")
-		stoppables = append(stoppables, []Stoppable{livestatusCollector, livestatusCache, nagiosCollector, nagfluxCollector}...)
-		cleanUp(stoppables, resultQueues)
+		cleanUp(running.stoppables(), resultQueues)
 		quit <- true
 	}()
-	loop:
-	//Main loop
-	for {
-		select {
-		case <-time.After(time.Duration(updateRate) * time.Second):
-		/*queriesSend, measureTime, err := statisticUser.GetData("send")
-			if err != nil {
-				continue
-			}
-			idleTime := (measureTime.Seconds() - queriesSend.Time.Seconds() / float64(influx.AmountWorkers())) / updateRate
-			log.Debugf("Buffer len: %d - Idletime in percent: %0.2f ", len(resultQueues[0]), idleTime * 100)
-
-		//TODO: fix worker spawn by type
-			if idleTime > 0.25 {
-				influx.RemoveWorker()
-			} else if idleTime < 0.1 && float64(len(resultQueues[0])) > resultQueueLength * 0.8 {
-				influx.AddWorker()
-			}*/
-		case <-quit:
-			break loop
-		}
-	}
+	//The targets are scaled by their autoscaler, so there is nothing left to do till the end
+	<-quit
 }
 
-func waitForDumpfileCollector(dump *nagflux.DumpfileCollector) {
-	if dump != nil {
-		for i := 0; i < 30 && dump.IsRunning; i++ {
-			time.Sleep(time.Duration(2) * time.Second)
-		}
+//Reads the config again and applies the changes, an invalid config is ignored.
+func reloadConfig(configPath string, running *components) {
+	log.Info("Reloading config: ", configPath)
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		log.Error("Could not reload the config: ", err)
+		return
+	}
+	if len(cfg.Main.FieldSeparator) < 1 {
+		log.Error("Could not reload the config: FieldSeparator is too short!")
+		return
+	}
+	//Nothing is changed till the whole config is valid
+	chains, err := processor.Load(cfg)
+	if err != nil {
+		log.Error("Could not reload the config: ", err)
+		return
+	}
+	rules, err := routing.Load(cfg)
+	if err != nil {
+		log.Error("Could not reload the config: ", err)
+		return
+	}
+	folder, err := quarantine.Load(cfg)
+	if err != nil {
+		log.Error("Could not reload the config: ", err)
+		return
+	}
+	if err := checkLivestatus(cfg); err != nil {
+		log.Error("Could not reload the config: ", err)
+		return
 	}
+	config.SetConfig(cfg)
+	chains.Apply()
+	rules.Apply()
+	folder.Apply()
+	running.reload(cfg)
+	log.Info("Config reloaded")
 }
 
 //Wait till the Performance Data is sent.
@@ -0,0 +1,35 @@
+package processor
+
+import (
+	"errors"
+	"github.com/spitefulgrog/nagflux/collector"
+	"regexp"
+)
+
+//Drop removes the points whose tag matches the pattern, e.g. the check_multi internals, or removes a field of every point.
+type Drop struct {
+	tag     string
+	pattern *regexp.Regexp
+	field   string
+}
+
+//NewDrop drops the points if the tag matches the pattern, or removes the field if no tag is given.
+func NewDrop(tag, field string, pattern *regexp.Regexp) (*Drop, error) {
+	if tag != "" && pattern == nil {
+		return nil, errors.New("drop needs a Pattern for the Tag")
+	}
+	if (tag == "") == (field == "") {
+		return nil, errors.New("drop needs either a Tag and a Pattern or a Field")
+	}
+	return &Drop{tag: tag, pattern: pattern, field: field}, nil
+}
+
+//Process returns false if the point should be dropped. A point without fields is dropped as well, it can't be written.
+func (d Drop) Process(point *collector.Point) bool {
+	if d.tag != "" {
+		value, ok := point.Tags[d.tag]
+		return !ok || !d.pattern.MatchString(value)
+	}
+	delete(point.Fields, d.field)
+	return len(point.Fields) > 0
+}
@@ -0,0 +1,52 @@
+package processor
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/data"
+	"regexp"
+	"testing"
+)
+
+func TestDropByTag(t *testing.T) {
+	t.Parallel()
+	drop, err := NewDrop("performanceLabel", "", regexp.MustCompile(`^check_multi::`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for label, expected := range map[string]bool{"check_multi::plugins": false, "rta": true} {
+		point := collector.Point{Tags: map[string]string{"performanceLabel": label}, Fields: data.Fields{"value": data.Float(1)}}
+		if actual := drop.Process(&point); actual != expected {
+			t.Errorf("%s: expected %t, got %t", label, expected, actual)
+		}
+	}
+	messages := collector.Point{Tags: map[string]string{"host": "h"}, Fields: data.Fields{"message": data.String("x")}}
+	if !drop.Process(&messages) {
+		t.Error("Points without the tag should be kept")
+	}
+}
+
+func TestDropField(t *testing.T) {
+	t.Parallel()
+	drop, err := NewDrop("", "warn", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	point := collector.Point{Fields: data.Fields{"value": data.Float(1), "warn": data.Float(2)}}
+	if !drop.Process(&point) || len(point.Fields) != 1 {
+		t.Errorf("Expected the field to be removed: %v", point)
+	}
+	onlyWarn := collector.Point{Fields: data.Fields{"warn": data.Float(2)}}
+	if drop.Process(&onlyWarn) {
+		t.Error("A point without fields should be dropped")
+	}
+}
+
+func TestNewDropInvalid(t *testing.T) {
+	t.Parallel()
+	if _, err := NewDrop("host", "", nil); err == nil {
+		t.Error("A tag without pattern should fail")
+	}
+	if _, err := NewDrop("", "", regexp.MustCompile("x")); err == nil {
+		t.Error("Neither tag nor field should fail")
+	}
+}
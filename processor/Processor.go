@@ -0,0 +1,201 @@
+package processor
+
+import (
+	"fmt"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//Processor changes a point before it's queued for a target, false drops the point.
+type Processor interface {
+	Process(point *collector.Point) bool
+}
+
+//Chain runs the processors in order, till one drops the point.
+type Chain []Processor
+
+//Process runs the chain on a copy of every point, the original printable is shared by all targets.
+//Returns nil if every point got dropped.
+func (c Chain) Process(printable collector.Printable) collector.Printable {
+	if len(c) == 0 {
+		return printable
+	}
+	var points []collector.Point
+	for _, point := range printable.Points() {
+		point = point.Copy()
+		if c.processPoint(&point) {
+			points = append(points, point)
+		}
+	}
+	if len(points) == 0 {
+		return nil
+	}
+	return Processed{Printable: printable, points: points}
+}
+
+func (c Chain) processPoint(point *collector.Point) bool {
+	for _, p := range c {
+		if !p.Process(point) {
+			return false
+		}
+	}
+	return true
+}
+
+//Processed contains the points after the chain, it replaces the printable of the collector and keeps its filter.
+type Processed struct {
+	collector.Printable
+	points []collector.Point
+}
+
+//Points returns the processed points.
+func (p Processed) Points() []collector.Point {
+	return p.points
+}
+
+//entry is a configured processor with the targets it applies to, an empty list means all.
+type entry struct {
+	name      string
+	order     int
+	targets   []string
+	processor Processor
+}
+
+var chains = map[string]Chain{}
+var globalChain Chain
+var chainsMutex = &sync.RWMutex{}
+
+//Chains are the processors of a config, built by Load and used once they are applied.
+type Chains struct {
+	targets map[string]Chain
+	global  Chain
+}
+
+//Configure builds the chains of the config, they replace the current ones if the config is valid.
+func Configure(cfg config.Config) error {
+	newChains, err := Load(cfg)
+	if err != nil {
+		return err
+	}
+	newChains.Apply()
+	return nil
+}
+
+//Load builds the chains of the config without using them, so the config can be checked first.
+func Load(cfg config.Config) (Chains, error) {
+	var entries []entry
+	for name, section := range cfg.Processor {
+		if section == nil || !section.Enabled {
+			continue
+		}
+		p, err := New(section.Type, section.Tag, section.Field, section.Pattern, section.Replacement, section.To, section.Value)
+		if err != nil {
+			return Chains{}, fmt.Errorf("Processor %s: %s", name, err)
+		}
+		var targets []string
+		for _, target := range strings.Split(section.Targets, ",") {
+			if target = strings.TrimSpace(target); target != "" {
+				targets = append(targets, strings.ToLower(target))
+			}
+		}
+		entries = append(entries, entry{name: name, order: section.Order, targets: targets, processor: p})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].order != entries[j].order {
+			return entries[i].order < entries[j].order
+		}
+		return entries[i].name < entries[j].name
+	})
+
+	newChains := map[string]Chain{}
+	var newGlobal Chain
+	for _, e := range entries {
+		if len(e.targets) == 0 {
+			newGlobal = append(newGlobal, e.processor)
+		}
+	}
+	for _, e := range entries {
+		for _, target := range e.targets {
+			if _, ok := newChains[target]; !ok {
+				newChains[target] = chainFor(entries, target)
+			}
+		}
+	}
+	return Chains{targets: newChains, global: newGlobal}, nil
+}
+
+//Apply replaces the current chains.
+func (c Chains) Apply() {
+	chainsMutex.Lock()
+	chains, globalChain = c.targets, c.global
+	chainsMutex.Unlock()
+}
+
+//Returns the global processors and the ones of the target in their order.
+func chainFor(entries []entry, target string) Chain {
+	var chain Chain
+	for _, e := range entries {
+		if len(e.targets) == 0 || contains(e.targets, target) {
+			chain = append(chain, e.processor)
+		}
+	}
+	return chain
+}
+
+func contains(hay []string, needle string) bool {
+	for _, h := range hay {
+		if h == needle {
+			return true
+		}
+	}
+	return false
+}
+
+//Process runs the chain of the target on the printable, nil means everything got dropped.
+//collector.SimplePrintable is already encoded for the target and passed as it is.
+func Process(target data.Target, printable collector.Printable) collector.Printable {
+	if _, ok := printable.(collector.SimplePrintable); ok {
+		return printable
+	}
+	chainsMutex.RLock()
+	chain, ok := chains[strings.ToLower(target.Name)]
+	if !ok {
+		chain = globalChain
+	}
+	chainsMutex.RUnlock()
+	return chain.Process(printable)
+}
+
+//New creates a processor of the given type: rename, drop, regex or tag.
+func New(typ, tag, field, pattern, replacement, to, value string) (Processor, error) {
+	var regex *regexp.Regexp
+	if pattern != "" {
+		var err error
+		if regex, err = regexp.Compile(pattern); err != nil {
+			return nil, err
+		}
+	}
+	var p Processor
+	var err error
+	switch strings.ToLower(typ) {
+	case "rename":
+		p, err = NewRename(tag, field, to)
+	case "drop":
+		p, err = NewDrop(tag, field, regex)
+	case "regex":
+		p, err = NewRegex(tag, regex, replacement, to)
+	case "tag":
+		p, err = NewTag(tag, value)
+	default:
+		err = fmt.Errorf("the type %q is not supported, use rename, drop, regex or tag", typ)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
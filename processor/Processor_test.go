@@ -0,0 +1,132 @@
+package processor
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"testing"
+)
+
+type testPrintable struct {
+	collector.Filterable
+	points []collector.Point
+}
+
+func (p testPrintable) Points() []collector.Point {
+	return p.points
+}
+
+func newTestPrintable() testPrintable {
+	return testPrintable{Filterable: collector.Filterable{Filter: "influx"}, points: []collector.Point{
+		{Kind: collector.PerfdataKind, Tags: map[string]string{"host": "web1.example.com", "performanceLabel": "rta"}, Fields: data.Fields{"value": data.Float(1)}},
+		{Kind: collector.PerfdataKind, Tags: map[string]string{"host": "web1.example.com", "performanceLabel": "check_multi::x"}, Fields: data.Fields{"value": data.Float(2)}},
+	}}
+}
+
+func TestConfigure(t *testing.T) {
+	config.InitConfigFromString(`[Processor "strip_domain"]
+	Enabled = true
+	Type = regex
+	Order = 1
+	Tag = host
+	Pattern = "^([^.]+)\\..*$"
+	Replacement = "$1"
+[Processor "drop_multi"]
+	Enabled = true
+	Type = drop
+	Order = 2
+	Tag = performanceLabel
+	Pattern = "^check_multi::"
+[Processor "site"]
+	Enabled = true
+	Type = tag
+	Order = 3
+	Targets = "Influx, kafka"
+	Tag = site
+	Value = berlin
+[Processor "disabled"]
+	Type = tag
+	Tag = disabled
+	Value = true`)
+	if err := Configure(config.GetConfig()); err != nil {
+		t.Fatal(err)
+	}
+	defer Configure(config.Config{})
+	original := newTestPrintable()
+
+	result := Process(data.Target{Name: "influx", Datatype: data.InfluxDB}, original)
+	points := result.Points()
+	if len(points) != 1 || points[0].Tags["host"] != "web1" || points[0].Tags["site"] != "berlin" || points[0].Tags["disabled"] != "" {
+		t.Errorf("Unexpected points: %v", points)
+	}
+	if !result.TestTargetFilter("influx") || result.TestTargetFilter("other") {
+		t.Error("The filter of the printable should be kept")
+	}
+	if original.points[0].Tags["host"] != "web1.example.com" {
+		t.Error("The original points are shared by the targets and must not change")
+	}
+
+	points = Process(data.Target{Name: "other", Datatype: data.InfluxDB}, original).Points()
+	if len(points) != 1 || points[0].Tags["host"] != "web1" || points[0].Tags["site"] != "" {
+		t.Errorf("Just the global processors should run: %v", points)
+	}
+
+	raw := collector.SimplePrintable{Text: "line"}
+	if Process(data.Target{Name: "influx"}, raw) != raw {
+		t.Error("SimplePrintables should be passed as they are")
+	}
+}
+
+func TestProcessDropsEverything(t *testing.T) {
+	t.Parallel()
+	chain := Chain{Tag{tag: "a", value: "b"}, dropAll{}}
+	if chain.Process(newTestPrintable()) != nil {
+		t.Error("Expected nil if every point got dropped")
+	}
+	if printable := newTestPrintable(); Chain(nil).Process(printable).Points()[0].Tags["a"] != "" {
+		t.Error("An empty chain should not change anything")
+	}
+}
+
+type dropAll struct{}
+
+func (dropAll) Process(*collector.Point) bool { return false }
+
+func TestConfigureInvalid(t *testing.T) {
+	for _, section := range []string{
+		`[Processor "x"]
+	Enabled = true
+	Type = unknown`,
+		`[Processor "x"]
+	Type = regex
+	Tag = host
+	Pattern = "("`,
+	} {
+		config.InitConfigFromString(section)
+		if err := Configure(config.GetConfig()); err == nil {
+			t.Errorf("Expected an error for %s", section)
+		}
+	}
+}
+
+func TestLoadAppliesNothing(t *testing.T) {
+	config.SetConfig(config.Config{})
+	config.InitConfigFromString(`[Processor "site"]
+	Enabled = true
+	Type = tag
+	Tag = site
+	Value = berlin`)
+	chains, err := Load(config.GetConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Configure(config.Config{})
+	target := data.Target{Name: "influx", Datatype: data.InfluxDB}
+	if points := Process(target, newTestPrintable()).Points(); points[0].Tags["site"] != "" {
+		t.Errorf("The chains should not be used before they are applied: %v", points)
+	}
+	chains.Apply()
+	if points := Process(target, newTestPrintable()).Points(); points[0].Tags["site"] != "berlin" {
+		t.Errorf("The chains should be used once they are applied: %v", points)
+	}
+}
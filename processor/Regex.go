@@ -0,0 +1,42 @@
+package processor
+
+import (
+	"errors"
+	"github.com/spitefulgrog/nagflux/collector"
+	"regexp"
+)
+
+//Regex replaces the value of a tag, like the relabeling of Prometheus. E.g. strips the domain of the host
+//with the Pattern `^([^.]+)\..*$` and the Replacement $1, or maps a command to a friendlier name.
+type Regex struct {
+	tag         string
+	pattern     *regexp.Regexp
+	replacement string
+	to          string
+}
+
+//NewRegex writes the replaced value into the tag to, or back into the tag if to is empty.
+//The replacement may contain $1 or ${name} for the groups of the pattern.
+func NewRegex(tag string, pattern *regexp.Regexp, replacement, to string) (*Regex, error) {
+	if tag == "" || pattern == nil {
+		return nil, errors.New("regex needs a Tag and a Pattern")
+	}
+	if to == "" {
+		to = tag
+	}
+	return &Regex{tag: tag, pattern: pattern, replacement: replacement, to: to}, nil
+}
+
+//Process replaces the value if the pattern matches, an empty result removes the tag.
+func (r Regex) Process(point *collector.Point) bool {
+	value, ok := point.Tags[r.tag]
+	if !ok || !r.pattern.MatchString(value) {
+		return true
+	}
+	if result := r.pattern.ReplaceAllString(value, r.replacement); result != "" {
+		point.Tags[r.to] = result
+	} else {
+		delete(point.Tags, r.to)
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package processor
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"regexp"
+	"testing"
+)
+
+func TestRegex(t *testing.T) {
+	t.Parallel()
+	stripDomain, _ := NewRegex("host", regexp.MustCompile(`^([^.]+)\..*$`), "$1", "")
+	for input, expected := range map[string]string{"web1.example.com": "web1", "web2": "web2"} {
+		point := collector.Point{Tags: map[string]string{"host": input}}
+		if !stripDomain.Process(&point) || point.Tags["host"] != expected {
+			t.Errorf("%s: expected %s, got %v", input, expected, point.Tags)
+		}
+	}
+
+	mapCommand, _ := NewRegex("command", regexp.MustCompile(`^check_nrpe_(.+)$`), "nrpe ${1}", "check")
+	point := collector.Point{Tags: map[string]string{"command": "check_nrpe_disk"}}
+	mapCommand.Process(&point)
+	if point.Tags["check"] != "nrpe disk" || point.Tags["command"] != "check_nrpe_disk" {
+		t.Errorf("Expected a new tag: %v", point.Tags)
+	}
+
+	remove, _ := NewRegex("unit", regexp.MustCompile(`^.*$`), "", "")
+	point = collector.Point{Tags: map[string]string{"unit": "ms"}}
+	remove.Process(&point)
+	if _, ok := point.Tags["unit"]; ok {
+		t.Errorf("An empty result should remove the tag: %v", point.Tags)
+	}
+}
+
+func TestNewRegexInvalid(t *testing.T) {
+	t.Parallel()
+	if _, err := NewRegex("", regexp.MustCompile("x"), "", ""); err == nil {
+		t.Error("A missing tag should fail")
+	}
+	if _, err := NewRegex("host", nil, "", ""); err == nil {
+		t.Error("A missing pattern should fail")
+	}
+}
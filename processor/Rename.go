@@ -0,0 +1,35 @@
+package processor
+
+import (
+	"errors"
+	"github.com/spitefulgrog/nagflux/collector"
+)
+
+//Rename renames a tag or a field, e.g. performanceLabel to label.
+type Rename struct {
+	tag   string
+	field string
+	to    string
+}
+
+//NewRename renames the tag, or the field if no tag is given, to the new name.
+func NewRename(tag, field, to string) (*Rename, error) {
+	if (tag == "") == (field == "") || to == "" {
+		return nil, errors.New("rename needs either a Tag or a Field and the new name in To")
+	}
+	return &Rename{tag: tag, field: field, to: to}, nil
+}
+
+//Process moves the value to the new name, points without the tag or field are kept as they are.
+func (r Rename) Process(point *collector.Point) bool {
+	if r.tag != "" {
+		if value, ok := point.Tags[r.tag]; ok {
+			delete(point.Tags, r.tag)
+			point.Tags[r.to] = value
+		}
+	} else if value, ok := point.Fields[r.field]; ok {
+		delete(point.Fields, r.field)
+		point.Fields[r.to] = value
+	}
+	return true
+}
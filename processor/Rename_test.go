@@ -0,0 +1,39 @@
+package processor
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/data"
+	"reflect"
+	"testing"
+)
+
+func TestRename(t *testing.T) {
+	t.Parallel()
+	tag, _ := NewRename("performanceLabel", "", "label")
+	field, _ := NewRename("", "value", "current")
+	point := collector.Point{Tags: map[string]string{"performanceLabel": "rta"}, Fields: data.Fields{"value": data.Float(1)}}
+	if !tag.Process(&point) || !field.Process(&point) {
+		t.Fatal("Rename should not drop points")
+	}
+	expected := collector.Point{Tags: map[string]string{"label": "rta"}, Fields: data.Fields{"current": data.Float(1)}}
+	if !reflect.DeepEqual(point, expected) {
+		t.Errorf("Expected:%v\nResult:%v", expected, point)
+	}
+
+	//missing ones are ignored
+	other := collector.Point{Tags: map[string]string{"host": "h"}, Fields: data.Fields{"warn": data.Float(1)}}
+	tag.Process(&other)
+	field.Process(&other)
+	if _, ok := other.Tags["label"]; ok || len(other.Fields) != 1 {
+		t.Errorf("Nothing should change: %v", other)
+	}
+}
+
+func TestNewRenameInvalid(t *testing.T) {
+	t.Parallel()
+	for _, args := range [][3]string{{"", "", "x"}, {"a", "b", "x"}, {"a", "", ""}} {
+		if _, err := NewRename(args[0], args[1], args[2]); err == nil {
+			t.Errorf("NewRename%v should fail", args)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package processor
+
+import (
+	"errors"
+	"github.com/spitefulgrog/nagflux/collector"
+)
+
+//Tag adds a static tag to every point, e.g. site=berlin. An existing tag is overwritten.
+type Tag struct {
+	tag   string
+	value string
+}
+
+//NewTag creates the processor, the value must not be empty.
+func NewTag(tag, value string) (*Tag, error) {
+	if tag == "" || value == "" {
+		return nil, errors.New("tag needs a Tag and a Value")
+	}
+	return &Tag{tag: tag, value: value}, nil
+}
+
+//Process sets the tag.
+func (t Tag) Process(point *collector.Point) bool {
+	point.Tags[t.tag] = t.value
+	return true
+}
@@ -0,0 +1,23 @@
+package processor
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"testing"
+)
+
+func TestTag(t *testing.T) {
+	t.Parallel()
+	site, err := NewTag("site", "berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tags := range []map[string]string{{}, {"site": "munich"}} {
+		point := collector.Point{Tags: tags}
+		if !site.Process(&point) || point.Tags["site"] != "berlin" {
+			t.Errorf("Expected site=berlin: %v", point.Tags)
+		}
+	}
+	if _, err := NewTag("site", ""); err == nil {
+		t.Error("An empty value should fail")
+	}
+}
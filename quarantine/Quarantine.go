@@ -0,0 +1,151 @@
+package quarantine
+
+import (
+	"fmt"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/statistics"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+//ReasonSuffix is appended to the name of the quarantined file for the file which explains why.
+const ReasonSuffix = ".reason"
+
+//Line is a broken line of a file, Number starts with 1.
+type Line struct {
+	Number int
+	Text   string
+	Reason string
+}
+
+var folder string
+var folderMutex = &sync.RWMutex{}
+
+//Folder is the quarantine folder of a config, an empty folder disables the quarantine.
+type Folder string
+
+//Configure sets the quarantine folder of the config and creates it.
+func Configure(cfg config.Config) error {
+	newFolder, err := Load(cfg)
+	if err != nil {
+		return err
+	}
+	newFolder.Apply()
+	return nil
+}
+
+//Load creates the quarantine folder of the config without using it, so the config can be checked first.
+func Load(cfg config.Config) (Folder, error) {
+	newFolder := cfg.Main.QuarantineFolder
+	if newFolder != "" {
+		if err := os.MkdirAll(newFolder, 0755); err != nil {
+			return "", fmt.Errorf("QuarantineFolder: %s", err)
+		}
+	}
+	return Folder(newFolder), nil
+}
+
+//Apply makes the folder the current quarantine.
+func (f Folder) Apply() {
+	folderMutex.Lock()
+	folder = string(f)
+	folderMutex.Unlock()
+}
+
+func currentFolder() string {
+	folderMutex.RLock()
+	defer folderMutex.RUnlock()
+	return folder
+}
+
+//File moves the broken file into the quarantine and writes the reason next to it. Returns false if there is no
+//quarantine or the file could not be moved, the caller deletes it then like before. collector names the source.
+func File(collector, file, name, reason string) bool {
+	count(collector, "file", 1)
+	logging.GetLogger().Warnf("%s: %s is broken: %s", collector, file, reason)
+	target := targetName(name)
+	if target == "" {
+		return false
+	}
+	if err := move(file, target); err != nil {
+		logging.GetLogger().Warn("Could not quarantine the file: ", err)
+		return false
+	}
+	writeReason(target, reason+"\n")
+	return true
+}
+
+//Lines writes the broken lines of the file into the quarantine, the reasons are written line by line next to them.
+func Lines(collector, name string, lines []Line) {
+	if len(lines) == 0 {
+		return
+	}
+	count(collector, "line", len(lines))
+	target := targetName(name)
+	if target == "" {
+		return
+	}
+	var text, reasons strings.Builder
+	for _, line := range lines {
+		text.WriteString(line.Text + "\n")
+		reasons.WriteString(fmt.Sprintf("line %d: %s\n", line.Number, line.Reason))
+	}
+	if err := ioutil.WriteFile(target, []byte(text.String()), 0644); err != nil {
+		logging.GetLogger().Warn("Could not quarantine the lines: ", err)
+		return
+	}
+	writeReason(target, reasons.String())
+}
+
+//Returns the path within the quarantine, the time is prepended so files with the same name don't collide.
+func targetName(name string) string {
+	current := currentFolder()
+	if current == "" {
+		return ""
+	}
+	return path.Join(current, time.Now().Format("20060102-150405.000000000")+"-"+path.Base(name))
+}
+
+func writeReason(target, reason string) {
+	if err := ioutil.WriteFile(target+ReasonSuffix, []byte(reason), 0644); err != nil {
+		logging.GetLogger().Warn("Could not write the reason: ", err)
+	}
+}
+
+//Renames the file, if the quarantine is on another filesystem it's copied.
+func move(source, target string) error {
+	if err := os.Rename(source, target); err == nil {
+		return nil
+	}
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(target)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(target)
+		return err
+	}
+	return os.Remove(source)
+}
+
+func count(collector, kind string, amount int) {
+	if counter := statistics.GetPrometheusServer().Quarantined; counter != nil {
+		counter.WithLabelValues(collector, kind).Add(float64(amount))
+	}
+}
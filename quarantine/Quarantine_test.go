@@ -0,0 +1,99 @@
+package quarantine
+
+import (
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/statistics"
+	"github.com/prometheus/client_model/go"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func configure(t *testing.T, folder string) {
+	cfg := config.Config{}
+	cfg.Main.QuarantineFolder = folder
+	if err := Configure(cfg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func counted(collector, kind string) float64 {
+	metric := &io_prometheus_client.Metric{}
+	statistics.GetPrometheusServer().Quarantined.WithLabelValues(collector, kind).Write(metric)
+	return metric.GetCounter().GetValue()
+}
+
+//Returns the content of the only quarantined file and its reason.
+func quarantined(t *testing.T, folder string) (string, string) {
+	files, _ := ioutil.ReadDir(folder)
+	if len(files) != 2 {
+		t.Fatalf("Expected a file and its reason, got %d files", len(files))
+	}
+	var content, reason []byte
+	for _, file := range files {
+		raw, _ := ioutil.ReadFile(path.Join(folder, file.Name()))
+		if strings.HasSuffix(file.Name(), ReasonSuffix) {
+			reason = raw
+		} else {
+			content = raw
+		}
+	}
+	return string(content), string(reason)
+}
+
+func TestQuarantine(t *testing.T) {
+	logging.InitTestLogger()
+	statistics.NewPrometheusServer("")
+	source := t.TempDir()
+	broken := path.Join(source, "broken")
+	ioutil.WriteFile(broken, []byte("table&foo\n"), 0644)
+
+	configure(t, "")
+	if File("nagflux", broken, "broken", "no time") {
+		t.Error("Without folder nothing can be quarantined")
+	}
+	if _, err := os.Stat(broken); err != nil {
+		t.Error("The file has to be left for the collector: ", err)
+	}
+	Lines("spoolfile", "spool", []Line{{Number: 1, Text: "x", Reason: "y"}})
+
+	folder := path.Join(t.TempDir(), "quarantine")
+	configure(t, folder)
+	defer configure(t, "")
+	if !File("nagflux", broken, "broken", "no time") {
+		t.Fatal("The file was not quarantined")
+	}
+	if _, err := os.Stat(broken); !os.IsNotExist(err) {
+		t.Error("The file should be moved: ", err)
+	}
+	if content, reason := quarantined(t, folder); content != "table&foo\n" || reason != "no time\n" {
+		t.Errorf("Unexpected quarantine: %q %q", content, reason)
+	}
+	if count := counted("nagflux", "file"); count != 2 {
+		t.Errorf("Expected two broken files, got %f", count)
+	}
+
+	folder = path.Join(t.TempDir(), "lines")
+	configure(t, folder)
+	Lines("spoolfile", "/var/spool/nagios/perfdata.1.processing", nil)
+	Lines("spoolfile", "/var/spool/nagios/perfdata.1", []Line{{Number: 2, Text: "foo", Reason: "bar"}, {Number: 5, Text: "baz", Reason: "qux"}})
+	if content, reason := quarantined(t, folder); content != "foo\nbaz\n" || reason != "line 2: bar\nline 5: qux\n" {
+		t.Errorf("Unexpected quarantine: %q %q", content, reason)
+	}
+	if count := counted("spoolfile", "line"); count != 3 {
+		t.Errorf("Expected three broken lines, got %f", count)
+	}
+}
+
+func TestConfigureInvalid(t *testing.T) {
+	file := path.Join(t.TempDir(), "file")
+	ioutil.WriteFile(file, nil, 0644)
+	cfg := config.Config{}
+	cfg.Main.QuarantineFolder = path.Join(file, "quarantine")
+	if err := Configure(cfg); err == nil {
+		t.Error("Expected an error")
+	}
+}
@@ -0,0 +1,132 @@
+package routing
+
+import (
+	"fmt"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"sort"
+	"sync"
+)
+
+//HostgroupSource knows the hostgroups of the hosts, it's the livestatus cache.
+type HostgroupSource interface {
+	Hostgroups(host string) []string
+}
+
+var rules []*Rule
+var hostgroups HostgroupSource
+var rulesMutex = &sync.RWMutex{}
+
+//Rules are the routes of a config in their order, read by Load and used once they are applied.
+type Rules []*Rule
+
+//Configure reads the routes of the config, they replace the current ones if the config is valid.
+func Configure(cfg config.Config) error {
+	newRules, err := Load(cfg)
+	if err != nil {
+		return err
+	}
+	newRules.Apply()
+	return nil
+}
+
+//Load reads the routes of the config without using them, so the config can be checked first.
+func Load(cfg config.Config) (Rules, error) {
+	var newRules Rules
+	for name, section := range cfg.Route {
+		if section == nil || !section.Enabled {
+			continue
+		}
+		rule, err := NewRule(section.Targets, section.Host, section.Service, section.Command, section.Label,
+			section.Hostgroup, section.Tag, section.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("Route %s: %s", name, err)
+		}
+		rule.name, rule.order = name, section.Order
+		newRules = append(newRules, rule)
+	}
+	sort.Slice(newRules, func(i, j int) bool {
+		if newRules[i].order != newRules[j].order {
+			return newRules[i].order < newRules[j].order
+		}
+		return newRules[i].name < newRules[j].name
+	})
+	return newRules, nil
+}
+
+//Apply replaces the current routes.
+func (r Rules) Apply() {
+	rulesMutex.Lock()
+	rules = r
+	rulesMutex.Unlock()
+}
+
+//SetHostgroupSource sets where the hostgroups of the hosts are looked up.
+func SetHostgroupSource(source HostgroupSource) {
+	rulesMutex.Lock()
+	hostgroups = source
+	rulesMutex.Unlock()
+}
+
+//Route returns the points of the printable which are sent to the target, nil if there are none.
+//The first matching rule decides, points without one keep the filter of the collector.
+//Printables with an explicit target are passed as they are.
+func Route(target data.Target, printable collector.Printable) collector.Printable {
+	if _, ok := printable.(collector.SimplePrintable); ok || !printable.Routable() {
+		return printable
+	}
+	rulesMutex.RLock()
+	currentRules, currentHostgroups := rules, hostgroups
+	rulesMutex.RUnlock()
+	if len(currentRules) == 0 {
+		return printable
+	}
+	byCollector := printable.TestTargetFilter(target.Name)
+	var points []collector.Point
+	for _, point := range printable.Points() {
+		matches := byCollector
+		if rule := firstMatch(currentRules, currentHostgroups, point); rule != nil {
+			matches = rule.Targets().TestTargetFilter(target.Name)
+		}
+		if matches {
+			points = append(points, point)
+		}
+	}
+	if len(points) == 0 {
+		return nil
+	}
+	return Routed{Printable: printable, target: collector.Filterable{Filter: target.Name}, points: points}
+}
+
+//Returns the first rule which matches the point, nil if there is none.
+func firstMatch(rules []*Rule, hostgroups HostgroupSource, point collector.Point) *Rule {
+	for _, rule := range rules {
+		if rule.Match(point, hostgroups) {
+			return rule
+		}
+	}
+	return nil
+}
+
+//Routed contains the points of a printable which are sent to one target.
+type Routed struct {
+	collector.Printable
+	target collector.Filterable
+	points []collector.Point
+}
+
+//Points returns the routed points.
+func (r Routed) Points() []collector.Point {
+	return r.points
+}
+
+//TestTargetFilter matches just the target the points were routed to.
+func (r Routed) TestTargetFilter(toTest string) bool {
+	return r.target.TestTargetFilter(toTest)
+}
+
+//Routable returns false, the points are routed already.
+func (r Routed) Routable() bool {
+	return false
+}
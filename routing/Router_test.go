@@ -0,0 +1,116 @@
+package routing
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"testing"
+)
+
+type testPrintable struct {
+	collector.Filterable
+	points []collector.Point
+}
+
+func (p testPrintable) Points() []collector.Point {
+	return p.points
+}
+
+var mssqlInflux = data.Target{Name: "mssql_influx", Datatype: data.InfluxDB}
+var defaultInflux = data.Target{Name: "influx", Datatype: data.InfluxDB}
+var kafka = data.Target{Name: "kafka", Datatype: data.Kafka}
+
+func configureRoutes(t *testing.T) {
+	config.InitConfigFromString(`[Route "mssql"]
+	Enabled = true
+	Order = 2
+	Targets = "mssql_influx, kafka"
+	Command = check_mssql*
+[Route "berlin_databases"]
+	Enabled = true
+	Order = 1
+	Targets = kafka
+	Hostgroup = databases
+	Tag = site=berlin
+	Tag = "env=prod*"
+[Route "disabled"]
+	Targets = nowhere
+	Host = *`)
+	if err := Configure(config.GetConfig()); err != nil {
+		t.Fatal(err)
+	}
+	SetHostgroupSource(fakeHostgroups{"db1": {"databases"}, "db2": {"databases"}})
+}
+
+func resetRoutes() {
+	Configure(config.Config{})
+	SetHostgroupSource(nil)
+}
+
+func TestRoute(t *testing.T) {
+	configureRoutes(t)
+	defer resetRoutes()
+	printable := testPrintable{Filterable: collector.NewRoutableFilterable("influx"), points: []collector.Point{
+		{Tags: map[string]string{"host": "db1", "command": "check_mssql_health", "site": "berlin", "env": "production"}},
+		{Tags: map[string]string{"host": "db2", "command": "check_mssql_health"}},
+		{Tags: map[string]string{"host": "web1", "command": "check_http"}},
+	}}
+
+	expected := map[data.Target][]string{mssqlInflux: {"db2"}, kafka: {"db1", "db2"}, defaultInflux: {"web1"}}
+	for target, hosts := range expected {
+		routed := Route(target, printable)
+		if routed == nil {
+			t.Errorf("%s: nothing got routed", target.Name)
+			continue
+		}
+		points := routed.Points()
+		if len(points) != len(hosts) {
+			t.Errorf("%s: expected %v, got %v", target.Name, hosts, points)
+			continue
+		}
+		for i, host := range hosts {
+			if points[i].Tags["host"] != host {
+				t.Errorf("%s: expected %v, got %v", target.Name, hosts, points)
+			}
+		}
+		if !routed.TestTargetFilter(target.Name) || routed.TestTargetFilter("other") || routed.Routable() {
+			t.Errorf("%s: the routed printable has the wrong filter", target.Name)
+		}
+	}
+	if routed := Route(data.Target{Name: "other", Datatype: data.InfluxDB}, printable); routed != nil {
+		t.Errorf("Nothing should be routed to other, got %v", routed.Points())
+	}
+}
+
+func TestRouteExplicitTarget(t *testing.T) {
+	configureRoutes(t)
+	defer resetRoutes()
+	printable := testPrintable{Filterable: collector.Filterable{Filter: "influx"}, points: []collector.Point{
+		{Tags: map[string]string{"host": "db2", "command": "check_mssql_health"}},
+	}}
+	if routed := Route(mssqlInflux, printable); routed == nil || routed.TestTargetFilter(mssqlInflux.Name) {
+		t.Error("A printable with an explicit target must not be routed")
+	}
+	simple := collector.SimplePrintable{Filterable: collector.RoutableFilterable, Text: "x", Datatype: data.InfluxDB}
+	if routed := Route(mssqlInflux, simple); routed != simple {
+		t.Error("A SimplePrintable must not be routed")
+	}
+}
+
+func TestRouteWithoutRules(t *testing.T) {
+	resetRoutes()
+	printable := testPrintable{Filterable: collector.RoutableFilterable, points: []collector.Point{{Tags: map[string]string{"host": "db2"}}}}
+	if routed := Route(defaultInflux, printable); routed == nil || !routed.Routable() {
+		t.Error("Without rules the printable has to be passed as it is")
+	}
+}
+
+func TestConfigureInvalid(t *testing.T) {
+	config.InitConfigFromString(`[Route "broken"]
+	Enabled = true
+	Targets = influx
+	Tag = site`)
+	if err := Configure(config.GetConfig()); err == nil {
+		t.Error("Expected an error")
+	}
+}
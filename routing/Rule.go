@@ -0,0 +1,114 @@
+package routing
+
+import (
+	"fmt"
+	"github.com/spitefulgrog/nagflux/collector"
+	"regexp"
+	"strings"
+)
+
+//Rule sends the points it matches to its targets. Every matcher which is set has to match.
+type Rule struct {
+	name      string
+	order     int
+	targets   collector.Filterable
+	tags      map[string]*regexp.Regexp
+	hostgroup *regexp.Regexp
+}
+
+//The matchers of the config and the tag of the point they are tested against.
+var matcherTags = []struct {
+	option string
+	tag    string
+}{
+	{"Host", "host"},
+	{"Service", "service"},
+	{"Command", "command"},
+	{"Label", "performanceLabel"},
+}
+
+//NewRule creates a rule, the patterns are globs or if regex is set regular expressions.
+//tags are key=pattern pairs, hostgroup is matched against every hostgroup of the host.
+func NewRule(targets, host, service, command, label, hostgroup string, tags []string, regex bool) (*Rule, error) {
+	if strings.TrimSpace(targets) == "" {
+		return nil, fmt.Errorf("there are no targets")
+	}
+	r := &Rule{targets: collector.Filterable{Filter: strings.Replace(targets, " ", "", -1)}, tags: map[string]*regexp.Regexp{}}
+	for i, pattern := range []string{host, service, command, label} {
+		if pattern == "" {
+			continue
+		}
+		compiled, err := compile(pattern, regex)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", matcherTags[i].option, err)
+		}
+		r.tags[matcherTags[i].tag] = compiled
+	}
+	for _, tag := range tags {
+		key, pattern, ok := strings.Cut(tag, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("Tag %q is not written as key=pattern", tag)
+		}
+		compiled, err := compile(pattern, regex)
+		if err != nil {
+			return nil, fmt.Errorf("Tag %s: %s", key, err)
+		}
+		r.tags[key] = compiled
+	}
+	if hostgroup != "" {
+		compiled, err := compile(hostgroup, regex)
+		if err != nil {
+			return nil, fmt.Errorf("Hostgroup: %s", err)
+		}
+		r.hostgroup = compiled
+	}
+	return r, nil
+}
+
+//Compiles a glob into an anchored regular expression, * matches any text and ? a single character.
+func compile(pattern string, regex bool) (*regexp.Regexp, error) {
+	if regex {
+		return regexp.Compile(pattern)
+	}
+	var expression strings.Builder
+	expression.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expression.WriteString(".*")
+		case '?':
+			expression.WriteString(".")
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expression.WriteString("$")
+	return regexp.Compile(expression.String())
+}
+
+//Match tests the point, a missing tag does not match. hostgroups may be nil if there is no livestatus.
+func (r *Rule) Match(point collector.Point, hostgroups HostgroupSource) bool {
+	for key, pattern := range r.tags {
+		value, ok := point.Tags[key]
+		if !ok || !pattern.MatchString(value) {
+			return false
+		}
+	}
+	if r.hostgroup == nil {
+		return true
+	}
+	if hostgroups == nil {
+		return false
+	}
+	for _, group := range hostgroups.Hostgroups(point.Tags["host"]) {
+		if r.hostgroup.MatchString(group) {
+			return true
+		}
+	}
+	return false
+}
+
+//Targets returns the filter of the targets the matched points are sent to.
+func (r *Rule) Targets() collector.Filterable {
+	return r.targets
+}
@@ -0,0 +1,77 @@
+package routing
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"testing"
+)
+
+type fakeHostgroups map[string][]string
+
+func (f fakeHostgroups) Hostgroups(host string) []string {
+	return f[host]
+}
+
+var mssql = collector.Point{Tags: map[string]string{"host": "db1", "service": "MSSQL Locks", "command": "check_mssql_health", "performanceLabel": "locks", "site": "berlin"}}
+var hostcheck = collector.Point{Tags: map[string]string{"host": "web1", "command": "check-host-alive", "performanceLabel": "rta"}}
+
+var matchData = []struct {
+	host, service, command, label, hostgroup string
+	tags                                     []string
+	regex                                    bool
+	point                                    collector.Point
+	expected                                 bool
+}{
+	{"", "", "check_mssql*", "", "", nil, false, mssql, true},
+	{"", "", "check_mssql*", "", "", nil, false, hostcheck, false},
+	{"db?", "MSSQL *", "", "", "", nil, false, mssql, true},
+	{"db", "", "", "", "", nil, false, mssql, false},
+	{"", "", "", "", "", []string{"site=ber*"}, false, mssql, true},
+	{"", "", "", "", "", []string{"site=ber*", "missing=*"}, false, mssql, false},
+	{"", "", "", "", "", []string{"site="}, false, mssql, false},
+	{"", "", "", "(", "", nil, false, collector.Point{Tags: map[string]string{"performanceLabel": "("}}, true},
+	{"", "", "^check_(mssql|oracle)", "", "", nil, true, mssql, true},
+	{"", "", "^check_oracle", "", "", nil, true, mssql, false},
+	//hostchecks have no service
+	{"", "*", "", "", "", nil, false, hostcheck, false},
+	{"", "", "", "", "databases", nil, false, mssql, true},
+	{"", "", "", "", "data*", nil, false, hostcheck, false},
+	{"", "", "", "", "", nil, false, hostcheck, true},
+}
+
+func TestRuleMatch(t *testing.T) {
+	groups := fakeHostgroups{"db1": {"linux", "databases"}, "web1": {"linux"}}
+	for i, d := range matchData {
+		rule, err := NewRule("influx", d.host, d.service, d.command, d.label, d.hostgroup, d.tags, d.regex)
+		if err != nil {
+			t.Errorf("%d: %s", i, err)
+			continue
+		}
+		if actual := rule.Match(d.point, groups); actual != d.expected {
+			t.Errorf("%d: expected %t, got %t", i, d.expected, actual)
+		}
+	}
+}
+
+func TestRuleMatchWithoutHostgroups(t *testing.T) {
+	rule, _ := NewRule("influx", "", "", "", "", "databases", nil, false)
+	if rule.Match(mssql, nil) {
+		t.Error("A hostgroup must not match without livestatus")
+	}
+}
+
+func TestNewRuleInvalid(t *testing.T) {
+	for i, args := range [][]string{
+		{"", "db1", ""},
+		{"influx", "(", "true"},
+		{"influx", "db1", "", "site"},
+		{"influx", "db1", "", "=berlin"},
+	} {
+		var tags []string
+		if len(args) > 3 {
+			tags = args[3:]
+		}
+		if _, err := NewRule(args[0], args[1], "", "", "", "", tags, args[2] == "true"); err == nil {
+			t.Errorf("%d: expected an error", i)
+		}
+	}
+}
@@ -20,8 +20,18 @@ type PrometheusServer struct {
 	SpoolFilesParsedDuration prometheus.Counter
 	SpoolFilesParsed         prometheus.Counter
 	SpoolFilesLines          prometheus.Counter
+	SpoolFilesSkippedLines   prometheus.Counter
 	BytesSend                *prometheus.CounterVec
 	SendDuration             *prometheus.CounterVec
+	AutoscalerWorkers        *prometheus.GaugeVec
+	AutoscalerUtilization    *prometheus.GaugeVec
+	AutoscalerQueueFill      *prometheus.GaugeVec
+	AutoscalerDecisions      *prometheus.CounterVec
+	Quarantined              *prometheus.CounterVec
+
+	//throughput of the compressed spoolfiles, by format
+	SpoolFilesDecompressedBytes    *prometheus.CounterVec
+	SpoolFilesDecompressionSeconds *prometheus.CounterVec
 }
 
 var server PrometheusServer
@@ -77,6 +87,30 @@ func initServerConfig() PrometheusServer {
 			Help:      "Nagiosspoolfilelines parsed",
 		})
 	prometheus.MustRegister(SpoolFilesParsedSize)
+	SpoolFilesSkippedLines := prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "nagflux",
+			Subsystem: "spoolfile",
+			Name:      "skipped_lines_total",
+			Help:      "Nagiosspoolfilelines skipped as they are longer than MaxLineSize",
+		})
+	prometheus.MustRegister(SpoolFilesSkippedLines)
+	SpoolFilesDecompressedBytes := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "nagflux",
+			Subsystem: "spoolfile",
+			Name:      "decompressed_bytes_total",
+			Help:      "Bytes read from compressed spoolfiles after decompression, by format: gzip, zstd",
+		}, []string{"format"})
+	prometheus.MustRegister(SpoolFilesDecompressedBytes)
+	SpoolFilesDecompressionSeconds := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "nagflux",
+			Subsystem: "spoolfile",
+			Name:      "decompression_seconds_total",
+			Help:      "Time spent reading and decompressing compressed spoolfiles, by format: gzip, zstd",
+		}, []string{"format"})
+	prometheus.MustRegister(SpoolFilesDecompressionSeconds)
 	BytesSend := prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: "nagflux",
@@ -93,11 +127,54 @@ func initServerConfig() PrometheusServer {
 			Help:      "Time per package to sent to database",
 		}, []string{"type"})
 	prometheus.MustRegister(SendDuration)
+	AutoscalerWorkers := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "nagflux",
+			Subsystem: "autoscaler",
+			Name:      "workers",
+			Help:      "Current amount of workers per target",
+		}, []string{"target", "type"})
+	prometheus.MustRegister(AutoscalerWorkers)
+	AutoscalerUtilization := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "nagflux",
+			Subsystem: "autoscaler",
+			Name:      "utilization_ratio",
+			Help:      "Share of the last interval the workers spent sending",
+		}, []string{"target", "type"})
+	prometheus.MustRegister(AutoscalerUtilization)
+	AutoscalerQueueFill := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "nagflux",
+			Subsystem: "autoscaler",
+			Name:      "queue_fill_ratio",
+			Help:      "Fill level of the queue of the target",
+		}, []string{"target", "type"})
+	prometheus.MustRegister(AutoscalerQueueFill)
+	AutoscalerDecisions := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "nagflux",
+			Subsystem: "autoscaler",
+			Name:      "decisions_total",
+			Help:      "Decisions of the autoscaler, by decision: add, remove, keep",
+		}, []string{"target", "type", "decision"})
+	prometheus.MustRegister(AutoscalerDecisions)
+	Quarantined := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "nagflux",
+			Subsystem: "quarantine",
+			Name:      "entries_total",
+			Help:      "Broken files and lines, by collector and kind: file, line",
+		}, []string{"collector", "kind"})
+	prometheus.MustRegister(Quarantined)
 
 	return PrometheusServer{bufferLength: bufferLength, SpoolFilesOnDisk: spoolFilesOnDisk,
 		SpoolFilesInQueue: SpoolFilesInQueue, SpoolFilesParsedDuration: SpoolFilesParsedDuration,
-		SpoolFilesLines: SpoolFilesParsedSize, SpoolFilesParsed: SpoolFilesParsed,
-		BytesSend: BytesSend, SendDuration: SendDuration}
+		SpoolFilesLines: SpoolFilesParsedSize, SpoolFilesParsed: SpoolFilesParsed, SpoolFilesSkippedLines: SpoolFilesSkippedLines,
+		SpoolFilesDecompressedBytes: SpoolFilesDecompressedBytes, SpoolFilesDecompressionSeconds: SpoolFilesDecompressionSeconds,
+		BytesSend: BytesSend, SendDuration: SendDuration,
+		AutoscalerWorkers: AutoscalerWorkers, AutoscalerUtilization: AutoscalerUtilization,
+		AutoscalerQueueFill: AutoscalerQueueFill, AutoscalerDecisions: AutoscalerDecisions, Quarantined: Quarantined}
 }
 
 //NewPrometheusServer creates a new PrometheusServer
@@ -127,7 +204,7 @@ func GetPrometheusServer() PrometheusServer {
 func (s PrometheusServer) WatchResultQueueLength(channels collector.ResultQueues) {
 	go func() {
 		for {
-			for k, c := range channels {
+			for k, c := range channels.Snapshot() {
 				s.bufferLength.WithLabelValues(fmt.Sprint(k)).Set(float64(len(c)))
 			}
 			time.Sleep(time.Duration(100 * time.Millisecond))
@@ -0,0 +1,29 @@
+package statistics
+
+import (
+	"github.com/spitefulgrog/nagflux/data"
+	"sync"
+	"time"
+)
+
+var sendTimes = map[data.Target]time.Duration{}
+var sendTimesMutex = &sync.Mutex{}
+
+//AddSendTime adds the time a worker of the target needed to send one package, including the retries.
+func AddSendTime(target data.Target, duration time.Duration) {
+	if duration < 0 {
+		return
+	}
+	sendTimesMutex.Lock()
+	sendTimes[target] += duration
+	sendTimesMutex.Unlock()
+}
+
+//TakeSendTime returns the sum of the send times since the last call.
+func TakeSendTime(target data.Target) time.Duration {
+	sendTimesMutex.Lock()
+	defer sendTimesMutex.Unlock()
+	duration := sendTimes[target]
+	delete(sendTimes, target)
+	return duration
+}
@@ -0,0 +1,157 @@
+package target
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/statistics"
+	"github.com/kdar/factorlog"
+	"time"
+)
+
+//Interval in which the autoscaler measures the target and decides.
+const autoscaleInterval = time.Duration(5) * time.Second
+
+//Intervals to wait after a change, so the new amount of workers can show its effect.
+const autoscaleCooldown = 2
+
+//A worker is added if the workers are busy nearly all the time and the queue fills up.
+const growUtilization = 0.9
+const growQueueFill = 0.8
+
+//A worker is removed if the workers idle a quarter of the time and the queue is not filling up.
+const shrinkUtilization = 0.75
+const shrinkQueueFill = 0.5
+
+//Decisions of the autoscaler, used as label of the prometheus metrics.
+const (
+	DecisionAdd    = "add"
+	DecisionRemove = "remove"
+	DecisionKeep   = "keep"
+)
+
+//Scalable is a connector which can report and change its amount of workers.
+type Scalable interface {
+	HasWorker
+	AmountWorkers() int
+}
+
+//Autoscaler adds and removes workers of a target, depending on the fill of its queue and the time the workers spend sending.
+type Autoscaler struct {
+	quit       chan bool
+	target     data.Target
+	connector  Scalable
+	queue      chan collector.Printable
+	minWorkers int
+	maxWorkers int
+	cooldown   int
+	log        *factorlog.FactorLog
+	promServer statistics.PrometheusServer
+	IsRunning  bool
+}
+
+//NewAutoscaler starts scaling the connector between minWorkers and maxWorkers.
+func NewAutoscaler(target data.Target, connector Scalable, queue chan collector.Printable, minWorkers, maxWorkers int) *Autoscaler {
+	a := &Autoscaler{
+		quit: make(chan bool), target: target, connector: connector, queue: queue,
+		minWorkers: minWorkers, maxWorkers: maxWorkers, log: logging.GetLogger(),
+		promServer: statistics.GetPrometheusServer(), IsRunning: true,
+	}
+	statistics.TakeSendTime(target)
+	go a.run()
+	return a
+}
+
+//Stop stops the autoscaler, the workers are left as they are.
+func (a *Autoscaler) Stop() {
+	if a.IsRunning {
+		a.quit <- true
+		<-a.quit
+		a.IsRunning = false
+		a.log.Debug("Autoscaler(" + a.target.Name + ") stopped")
+	}
+}
+
+func (a *Autoscaler) run() {
+	last := time.Now()
+	for {
+		select {
+		case <-a.quit:
+			a.quit <- true
+			return
+		case <-time.After(autoscaleInterval):
+			now := time.Now()
+			a.step(now.Sub(last))
+			last = now
+		}
+	}
+}
+
+//Measures the target over the elapsed time, applies the decision and returns it.
+func (a *Autoscaler) step(elapsed time.Duration) string {
+	workers := a.connector.AmountWorkers()
+	utilization := 0.0
+	if workers > 0 && elapsed > 0 {
+		utilization = statistics.TakeSendTime(a.target).Seconds() / (float64(workers) * elapsed.Seconds())
+	}
+	if utilization > 1 {
+		//A send which started in the previous interval counts completely to this one
+		utilization = 1
+	}
+	fill := 0.0
+	if cap(a.queue) > 0 {
+		fill = float64(len(a.queue)) / float64(cap(a.queue))
+	}
+
+	decision := decide(utilization, fill, workers, a.minWorkers, a.maxWorkers)
+	if decision != DecisionKeep && !a.isAlive() {
+		//More workers won't help a target which is down and fewer won't change anything
+		decision = DecisionKeep
+	}
+	if a.cooldown > 0 {
+		a.cooldown--
+		decision = DecisionKeep
+	}
+	switch decision {
+	case DecisionAdd:
+		a.connector.AddWorker()
+	case DecisionRemove:
+		a.connector.RemoveWorker()
+	}
+	if decision != DecisionKeep {
+		a.cooldown = autoscaleCooldown
+		a.log.Infof("Autoscaler(%s): %s worker, utilization %0.2f, queue %0.2f", a.target.Name, decision, utilization, fill)
+	}
+
+	labels := []string{a.target.Name, string(a.target.Datatype)}
+	a.promServer.AutoscalerWorkers.WithLabelValues(labels...).Set(float64(a.connector.AmountWorkers()))
+	a.promServer.AutoscalerUtilization.WithLabelValues(labels...).Set(utilization)
+	a.promServer.AutoscalerQueueFill.WithLabelValues(labels...).Set(fill)
+	a.promServer.AutoscalerDecisions.WithLabelValues(append(labels, decision)...).Inc()
+	return decision
+}
+
+//Targets which can't tell count as alive.
+func (a *Autoscaler) isAlive() bool {
+	if c, ok := a.connector.(interface {
+		IsAlive() bool
+	}); ok {
+		return c.IsAlive()
+	}
+	return true
+}
+
+//decide returns whether a worker should be added or removed. The bounds are restored first, e.g. after a manual change.
+func decide(utilization, fill float64, workers, minWorkers, maxWorkers int) string {
+	switch {
+	case workers < minWorkers:
+		return DecisionAdd
+	case workers > maxWorkers:
+		return DecisionRemove
+	case workers < maxWorkers && utilization >= growUtilization && fill >= growQueueFill:
+		return DecisionAdd
+	case workers > minWorkers && utilization <= shrinkUtilization && fill < shrinkQueueFill:
+		return DecisionRemove
+	}
+	return DecisionKeep
+}
@@ -0,0 +1,83 @@
+package target
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/statistics"
+	"testing"
+	"time"
+)
+
+type fakeConnector struct {
+	workers int
+	alive   bool
+}
+
+func (c *fakeConnector) AddWorker()         { c.workers++ }
+func (c *fakeConnector) RemoveWorker()      { c.workers-- }
+func (c *fakeConnector) AmountWorkers() int { return c.workers }
+func (c *fakeConnector) IsAlive() bool      { return c.alive }
+
+var decideData = []struct {
+	utilization float64
+	fill        float64
+	workers     int
+	expected    string
+}{
+	{0.95, 0.9, 2, DecisionAdd},
+	{0.95, 0.9, 5, DecisionKeep},
+	{0.95, 0.1, 2, DecisionKeep},
+	{0.5, 0.9, 2, DecisionKeep},
+	{0.5, 0.1, 3, DecisionRemove},
+	{0.5, 0.1, 2, DecisionKeep},
+	{0.8, 0.1, 3, DecisionKeep},
+	{0.5, 0.6, 3, DecisionKeep},
+	{0, 0, 1, DecisionAdd},
+	{1, 1, 6, DecisionRemove},
+}
+
+func TestDecide(t *testing.T) {
+	for i, d := range decideData {
+		if actual := decide(d.utilization, d.fill, d.workers, 2, 5); actual != d.expected {
+			t.Errorf("%d: expected %s, got %s", i, d.expected, actual)
+		}
+	}
+}
+
+func TestAutoscalerStep(t *testing.T) {
+	prometheusOnce.Do(func() { statistics.NewPrometheusServer("") })
+	target := data.Target{Name: "scale", Datatype: data.InfluxDB}
+	connector := &fakeConnector{workers: 2, alive: true}
+	queue := make(chan collector.Printable, 10)
+	for i := 0; i < 9; i++ {
+		queue <- collector.SimplePrintable{}
+	}
+	a := NewAutoscaler(target, connector, queue, 2, 5)
+	a.Stop()
+
+	statistics.AddSendTime(target, time.Duration(20)*time.Second)
+	if decision := a.step(time.Duration(10) * time.Second); decision != DecisionAdd || connector.workers != 3 {
+		t.Errorf("expected a new worker, got %s and %d workers", decision, connector.workers)
+	}
+
+	//The cooldown prevents a second worker right away
+	statistics.AddSendTime(target, time.Duration(30)*time.Second)
+	if decision := a.step(time.Duration(10) * time.Second); decision != DecisionKeep || connector.workers != 3 {
+		t.Errorf("expected to wait, got %s and %d workers", decision, connector.workers)
+	}
+	a.cooldown = 0
+
+	connector.alive = false
+	statistics.AddSendTime(target, time.Duration(30)*time.Second)
+	if decision := a.step(time.Duration(10) * time.Second); decision != DecisionKeep || connector.workers != 3 {
+		t.Errorf("expected no change while the target is down, got %s and %d workers", decision, connector.workers)
+	}
+	connector.alive = true
+
+	for len(queue) > 0 {
+		<-queue
+	}
+	if decision := a.step(time.Duration(10) * time.Second); decision != DecisionRemove || connector.workers != 2 {
+		t.Errorf("expected a removed worker, got %s and %d workers", decision, connector.workers)
+	}
+}
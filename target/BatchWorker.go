@@ -0,0 +1,213 @@
+package target
+
+import (
+	"errors"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/collector/nagflux"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/statistics"
+	"github.com/spitefulgrog/nagflux/wal"
+	"github.com/kdar/factorlog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Size of a batch, it is sent earlier if no data arrives within batchTimeout.
+const batchSize = 500
+const batchTimeout = time.Duration(5) * time.Second
+
+//Time to wait before a failed send is repeated or a target which is down is tested again.
+const retryInterval = time.Duration(10) * time.Second
+
+//ErrorInterrupted is returned by WaitForQuitOrGoOn if the worker is stopped.
+var ErrorInterrupted = errors.New("Got interrupted")
+
+//Dumpfiles are shared by the workers of a target.
+var dumpMutex = &sync.Mutex{}
+
+//Sender is the part of a worker which depends on the target.
+type Sender interface {
+	//IsReady returns true if the target takes data. Otherwise it tests the target again and the worker waits before
+	//it asks again.
+	IsReady() bool
+	//Send sends a batch of printables which are meant for the target.
+	Send(batch []collector.Printable)
+	//Encode converts a printable into the entries of the write-ahead log and the dumpfile.
+	Encode(printable collector.Printable) []string
+}
+
+//BatchWorker reads the printables of its target from the queue and passes them in batches to the Sender, until it is
+//stopped. The remaining data is spilled to the write-ahead log then. Senders which implement Close() are closed at the end.
+type BatchWorker struct {
+	ID           int
+	Jobs         chan collector.Printable
+	Target       data.Target
+	DumpFile     string
+	Log          *factorlog.FactorLog
+	sender       Sender
+	quit         chan bool
+	quitInternal chan bool
+	promServer   statistics.PrometheusServer
+}
+
+//NewBatchWorker returns a worker for the sender, it is started by Start.
+func NewBatchWorker(workerID int, jobs chan collector.Printable, target data.Target, dumpFile string, sender Sender) *BatchWorker {
+	return &BatchWorker{
+		ID: workerID, Jobs: jobs, Target: target, DumpFile: nagflux.GenDumpfileName(dumpFile, target),
+		Log: logging.GetLogger(), sender: sender, quit: make(chan bool), quitInternal: make(chan bool, 1),
+		promServer: statistics.GetPrometheusServer(),
+	}
+}
+
+//Start starts reading the queue.
+func (worker *BatchWorker) Start() {
+	go worker.run()
+}
+
+//Stop stops the worker after the current batch is sent or spilled.
+func (worker *BatchWorker) Stop() {
+	worker.quitInternal <- true
+	worker.quit <- true
+	<-worker.quit
+	worker.Log.Debugf("Worker(%s) stopped", worker.Target)
+}
+
+//Tries to send data all the time.
+func (worker *BatchWorker) run() {
+	var batch []collector.Printable
+	for {
+		if !worker.sender.IsReady() {
+			select {
+			case <-worker.quit:
+				worker.quit <- true
+				return
+			case <-time.After(retryInterval):
+			}
+			continue
+		}
+		select {
+		case <-worker.quit:
+			worker.Log.Debugf("Worker(%s) quitting...", worker.Target)
+			worker.send(batch)
+			if closer, ok := worker.sender.(interface {
+				Close()
+			}); ok {
+				closer.Close()
+			}
+			worker.quit <- true
+			return
+		case job := <-worker.Jobs:
+			if job.TestTargetFilter(worker.Target.Name) {
+				batch = append(batch, job)
+				if len(batch) == batchSize {
+					worker.send(batch)
+					batch = nil
+				}
+			}
+		case <-time.After(batchTimeout):
+			worker.send(batch)
+			batch = nil
+		}
+	}
+}
+
+func (worker *BatchWorker) send(batch []collector.Printable) {
+	if len(batch) > 0 {
+		worker.sender.Send(batch)
+	}
+}
+
+//WaitForQuitOrGoOn waits before a send is repeated, it returns ErrorInterrupted if the worker got stopped meanwhile.
+//The sender should pass its batch to DumpRemaining then and return.
+func (worker *BatchWorker) WaitForQuitOrGoOn() error {
+	select {
+	//Got stop signal
+	case <-worker.quitInternal:
+		worker.Log.Debug("Received quit")
+		worker.quitInternal <- true
+		return ErrorInterrupted
+	//Timeout and retry
+	case <-time.After(retryInterval):
+		return nil
+	}
+}
+
+//DumpRemaining spills the batch and the printables of the target which are left in the queue, if a quit signal arises.
+func (worker *BatchWorker) DumpRemaining(batch []collector.Printable) {
+	worker.Log.Debugf("Global queue %d own queue %d", len(worker.Jobs), len(batch))
+	if len(worker.Jobs) != 0 || len(batch) != 0 {
+		worker.Log.Debug("Saving printables to disk")
+		batch = append(batch, worker.readFromQueue()...)
+		worker.Log.Debugf("dumping %d printables", len(batch))
+		var lines []string
+		for _, printable := range batch {
+			lines = append(lines, worker.sender.Encode(printable)...)
+		}
+		worker.Spill(lines)
+	}
+}
+
+//Reads the printables of the target from the global queue, until it stays empty for a moment.
+func (worker *BatchWorker) readFromQueue() []collector.Printable {
+	var result []collector.Printable
+	for {
+		select {
+		case job := <-worker.Jobs:
+			if job.TestTargetFilter(worker.Target.Name) {
+				result = append(result, job)
+			}
+		case <-time.After(time.Duration(200) * time.Millisecond):
+			return result
+		}
+	}
+}
+
+//Spill writes the lines to the write-ahead log, they are sent again once the target is reachable. If that fails
+//they end up in the dumpfile.
+func (worker *BatchWorker) Spill(lines []string) {
+	if err := wal.Append(worker.Target, lines); err != nil {
+		worker.Log.Critical(err)
+		worker.DumpLines(worker.DumpFile, lines)
+	}
+}
+
+//DumpErrors writes the lines which got rejected by the target to the error dumpfile.
+func (worker *BatchWorker) DumpErrors(messageForLog string, lines []string) {
+	errorFile := worker.DumpFile + "-errors"
+	worker.Log.Warnf("Dumping data with errors to: %s", errorFile)
+	worker.DumpLines(errorFile, append([]string{messageForLog}, lines...))
+}
+
+//DumpLines appends the lines to the file, a line break is added if a line has none.
+func (worker *BatchWorker) DumpLines(filename string, lines []string) {
+	dumpMutex.Lock()
+	defer dumpMutex.Unlock()
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		worker.Log.Critical(err)
+		return
+	}
+	defer f.Close()
+	for _, line := range lines {
+		if !strings.HasSuffix(line, "\n") {
+			line += "\n"
+		}
+		if _, err = f.WriteString(line); err != nil {
+			worker.Log.Critical(err)
+		}
+	}
+}
+
+//Sent records the size of the payload in bytes and the duration of a send for the metrics and the autoscaler. Batches
+//which got spilled are not recorded.
+func (worker *BatchWorker) Sent(label string, size int, startTime time.Time) {
+	worker.promServer.BytesSend.WithLabelValues(label).Add(float64(size))
+	timeDiff := float64(time.Since(startTime).Seconds() * 1000)
+	if timeDiff >= 0 {
+		worker.promServer.SendDuration.WithLabelValues(label).Add(timeDiff)
+	}
+	statistics.AddSendTime(worker.Target, time.Since(startTime))
+}
@@ -0,0 +1,89 @@
+package target
+
+import (
+	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/kdar/factorlog"
+	"sync"
+)
+
+//Worker is a worker of a target which can be stopped.
+type Worker interface {
+	Stop()
+}
+
+//Workers are the workers of a connector, they implement Scalable. They are changed by the autoscaler and the admin
+//API at the same time, so every access is guarded by the mutex.
+type Workers struct {
+	mutex      sync.Mutex
+	workers    []Worker
+	maxWorkers int
+	nextID     int
+	start      func(workerID int) Worker
+	log        *factorlog.FactorLog
+}
+
+//NewWorkers starts the given amount of workers, start has to return a running worker.
+func NewWorkers(amount, maxWorkers int, start func(workerID int) Worker) *Workers {
+	workers := &Workers{maxWorkers: maxWorkers, start: start, log: logging.GetLogger()}
+	for w := 0; w < amount; w++ {
+		workers.workers = append(workers.workers, workers.startNext())
+	}
+	return workers
+}
+
+func (w *Workers) startNext() Worker {
+	worker := w.start(w.nextID)
+	w.nextID++
+	return worker
+}
+
+//AddWorker creates a new worker
+func (w *Workers) AddWorker() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	oldLength := len(w.workers)
+	if oldLength < w.maxWorkers {
+		w.workers = append(w.workers, w.startNext())
+		w.log.Infof("Starting Worker: %d -> %d", oldLength, len(w.workers))
+	}
+}
+
+//RemoveWorker stops a worker, it is stopped after it left the list so the others can be changed meanwhile.
+func (w *Workers) RemoveWorker() {
+	w.mutex.Lock()
+	oldLength := len(w.workers)
+	if oldLength <= 1 {
+		w.mutex.Unlock()
+		return
+	}
+	lastWorkerIndex := oldLength - 1
+	worker := w.workers[lastWorkerIndex]
+	w.workers = w.workers[:lastWorkerIndex]
+	w.mutex.Unlock()
+	worker.Stop()
+	w.log.Infof("Stopping Worker: %d -> %d", oldLength, lastWorkerIndex)
+}
+
+//AmountWorkers current amount of workers.
+func (w *Workers) AmountWorkers() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return len(w.workers)
+}
+
+//StopWorkers stops all workers at once and waits for them.
+func (w *Workers) StopWorkers() {
+	w.mutex.Lock()
+	workers := w.workers
+	w.workers = nil
+	w.mutex.Unlock()
+	var wg sync.WaitGroup
+	for _, worker := range workers {
+		wg.Add(1)
+		go func(worker Worker) {
+			defer wg.Done()
+			worker.Stop()
+		}(worker)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,96 @@
+package target
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/statistics"
+	"sync"
+	"testing"
+	"time"
+)
+
+var prometheusOnce sync.Once
+
+type fakeWorker struct {
+	stopped *int
+	mutex   *sync.Mutex
+}
+
+func (w fakeWorker) Stop() {
+	w.mutex.Lock()
+	*w.stopped++
+	w.mutex.Unlock()
+}
+
+func newFakeWorkers(amount, maxWorkers int) (*Workers, *int, *int) {
+	started, stopped := 0, 0
+	mutex := &sync.Mutex{}
+	workers := NewWorkers(amount, maxWorkers, func(workerID int) Worker {
+		mutex.Lock()
+		started++
+		mutex.Unlock()
+		return fakeWorker{stopped: &stopped, mutex: mutex}
+	})
+	return workers, &started, &stopped
+}
+
+func TestWorkersBounds(t *testing.T) {
+	workers, started, stopped := newFakeWorkers(1, 2)
+	workers.RemoveWorker()
+	if workers.AmountWorkers() != 1 {
+		t.Errorf("the last worker was removed, %d workers left", workers.AmountWorkers())
+	}
+	workers.AddWorker()
+	workers.AddWorker()
+	if workers.AmountWorkers() != 2 {
+		t.Errorf("expected at most 2 workers, got %d", workers.AmountWorkers())
+	}
+	workers.StopWorkers()
+	if workers.AmountWorkers() != 0 || *started != 2 || *stopped != 2 {
+		t.Errorf("expected 2 started and stopped workers, got %d workers, %d started, %d stopped", workers.AmountWorkers(), *started, *stopped)
+	}
+}
+
+//Run with -race, the autoscaler and the admin API change the workers at the same time.
+func TestWorkersAutoscalerAndAdmin(t *testing.T) {
+	prometheusOnce.Do(func() { statistics.NewPrometheusServer("") })
+	target := data.Target{Name: "concurrent", Datatype: data.InfluxDB}
+	workers, started, stopped := newFakeWorkers(2, 5)
+	queue := make(chan collector.Printable, 10)
+	for i := 0; i < 9; i++ {
+		queue <- collector.SimplePrintable{}
+	}
+	a := NewAutoscaler(target, workers, queue, 1, 5)
+	a.Stop()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			a.cooldown = 0
+			statistics.AddSendTime(target, time.Duration(20)*time.Second)
+			a.step(time.Duration(10) * time.Second)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if i%2 == 0 {
+				workers.AddWorker()
+			} else {
+				workers.RemoveWorker()
+			}
+			workers.AmountWorkers()
+		}
+	}()
+	wg.Wait()
+
+	if amount := workers.AmountWorkers(); amount < 1 || amount > 5 {
+		t.Errorf("expected between 1 and 5 workers, got %d", amount)
+	}
+	workers.StopWorkers()
+	if *started != *stopped {
+		t.Errorf("%d workers were started but %d stopped", *started, *stopped)
+	}
+}
@@ -1,12 +1,16 @@
 package elasticsearch
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/spitefulgrog/nagflux/collector"
 	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
 	"github.com/spitefulgrog/nagflux/helper"
 	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/target"
 	"github.com/kdar/factorlog"
+	"io/ioutil"
 	"net/http"
 	"strings"
 	"time"
@@ -17,28 +21,32 @@ type Connector struct {
 	connectionHost string
 	index          string
 	dumpFile       string
-	workers        []*Worker
-	maxWorkers     int
+	*target.Workers
 	jobs           chan collector.Printable
-	quit           chan bool
 	log            *factorlog.FactorLog
 	version        string
 	isAlive        bool
 	templateExists bool
 	httpClient     http.Client
+	username       string
+	password       string
+	apiKey         string
+	target         data.Target
 }
 
 //ConnectorFactory Constructor which will create some workers if the connection is established.
-func ConnectorFactory(jobs chan collector.Printable, connectionHost, index, dumpFile, version string, workerAmount, maxWorkers int, createDatabaseIfNotExists bool) *Connector {
+//If an apiKey is given it is used for the authentication, otherwise basic auth if an username is given.
+func ConnectorFactory(jobs chan collector.Printable, connectionHost, index, dumpFile, version, username, password, apiKey string, workerAmount, maxWorkers int, createDatabaseIfNotExists bool, target data.Target) *Connector {
 	if connectionHost[len(connectionHost)-1] != '/' {
 		connectionHost += "/"
 	}
-	s := &Connector{connectionHost, index, dumpFile, make([]*Worker, workerAmount), maxWorkers,
-		jobs, make(chan bool), logging.GetLogger(), version,
+	s := &Connector{connectionHost, index, dumpFile, nil,
+		jobs, logging.GetLogger(), version,
 		false, false, http.Client{Timeout: time.Duration(5 * time.Second)},
+		username, password, apiKey, target,
 	}
 
-	gen := WorkerGenerator(jobs, connectionHost+"_bulk", index, dumpFile, version, s)
+	gen := WorkerGenerator(jobs, connectionHost+"_bulk", index, dumpFile, version, s, target)
 
 	s.TestIfIsAlive()
 	for i := 0; i < 5 && !s.isAlive; i++ {
@@ -60,39 +68,10 @@ func ConnectorFactory(jobs chan collector.Printable, connectionHost, index, dump
 		s.log.Panic("Template does not exists and was not able to created")
 	}
 
-	for w := 0; w < workerAmount; w++ {
-		s.workers[w] = gen(w)
-	}
-	go s.run()
+	s.Workers = startWorkers(workerAmount, maxWorkers, gen)
 	return s
 }
 
-//AddWorker creates a new worker
-func (connector *Connector) AddWorker() {
-	oldLength := connector.AmountWorkers()
-	if oldLength < connector.maxWorkers {
-		gen := WorkerGenerator(connector.jobs, connector.connectionHost+"/_bulk", connector.index, connector.dumpFile, connector.version, connector)
-		connector.workers = append(connector.workers, gen(oldLength+2))
-		connector.log.Infof("Starting Worker: %d -> %d", oldLength, connector.AmountWorkers())
-	}
-}
-
-//RemoveWorker stops a worker
-func (connector *Connector) RemoveWorker() {
-	oldLength := connector.AmountWorkers()
-	if oldLength > 1 {
-		lastWorkerIndex := oldLength - 1
-		connector.workers[lastWorkerIndex].Stop()
-		connector.workers = connector.workers[:lastWorkerIndex]
-		connector.log.Infof("Stopping Worker: %d -> %d", oldLength, connector.AmountWorkers())
-	}
-}
-
-//AmountWorkers current amount of workers.
-func (connector Connector) AmountWorkers() int {
-	return len(connector.workers)
-}
-
 //IsAlive is the database system alive.
 func (connector Connector) IsAlive() bool {
 	return connector.isAlive
@@ -103,69 +82,103 @@ func (connector Connector) DatabaseExists() bool {
 	return connector.templateExists
 }
 
+//Encode converts the printable into entries of the write-ahead log.
+func (connector Connector) Encode(printable collector.Printable) []string {
+	return EncodePrintable(printable, connector.version, connector.index)
+}
+
 //Stop the connector and its workers.
 func (connector *Connector) Stop() {
-	connector.quit <- true
-	<-connector.quit
+	connector.StopWorkers()
 	connector.log.Debug("ElasticsearchConnectorFactory stopped")
 }
 
-//Waits just for the end.
-func (connector *Connector) run() {
-	for {
-		select {
-		case <-connector.quit:
-			for _, worker := range connector.workers {
-				go worker.Stop()
-			}
-			for len(connector.workers) > 0 {
-				for connector.workers[0].IsRunning == true {
-					time.Sleep(time.Duration(100) * time.Millisecond)
-				}
-				if len(connector.workers) > 1 {
-					connector.workers = connector.workers[1:]
-				} else {
-					connector.workers = connector.workers[:0]
-				}
-			}
-			connector.quit <- true
-			return
-		}
-	}
-}
-
 //TestIfIsAlive test active if the database system is alive.
 func (connector *Connector) TestIfIsAlive() bool {
-	result := helper.RequestedReturnCodeIsOK(connector.httpClient, connector.connectionHost, "HEAD")
+	result, _ := connector.sendRequest("HEAD", connector.connectionHost, "")
 	connector.isAlive = result
 	return result
 }
 
 //TestTemplateExists test active if the template exists.
 func (connector *Connector) TestTemplateExists() bool {
-	result, body := helper.SentReturnCodeIsOK(connector.httpClient, connector.connectionHost+"_template", "GET", "")
-	if result && strings.Contains(body, fmt.Sprintf(`"%s":`, connector.index)) {
-		connector.templateExists = true
+	if helper.ElasticUsesMappingTypes(connector.version) {
+		result, body := connector.sendRequest("GET", connector.connectionHost+"_template", "")
+		connector.templateExists = result && strings.Contains(body, fmt.Sprintf(`"%s":`, connector.index))
 	} else {
-		connector.templateExists = false
+		connector.templateExists, _ = connector.sendRequest("GET", connector.templateURL(), "")
 	}
 	return connector.templateExists
 }
 
 //createTemplate creates the nagflux template.
 func (connector *Connector) createTemplate() bool {
-	mapping := fmt.Sprintf(NagfluxTemplate,
+	template := NagfluxTemplate
+	if !helper.ElasticUsesMappingTypes(connector.version) {
+		if connector.supportsIndexTemplates() {
+			template = NagfluxIndexTemplate
+		} else {
+			template = NagfluxTypelessTemplate
+		}
+	}
+	mapping := fmt.Sprintf(template,
 		connector.index,
 		config.GetConfig().ElasticsearchGlobal.NumberOfShards,
 		config.GetConfig().ElasticsearchGlobal.NumberOfReplicas,
 	)
-	createIndex, _ := helper.SentReturnCodeIsOK(connector.httpClient, connector.connectionHost+"_template/"+connector.index, "PUT", mapping)
+	createIndex, body := connector.sendRequest("PUT", connector.templateURL(), mapping)
 	if !createIndex {
+		connector.log.Warn("Could not create template: ", body)
 		return false
 	}
 	return true
 }
 
+//supportsIndexTemplates is true if the composable _index_template API is available.
+func (connector Connector) supportsIndexTemplates() bool {
+	return helper.IsOpenSearch(connector.version) || helper.VersionOrdinal(connector.version) >= helper.VersionOrdinal("7.8")
+}
+
+//templateURL returns the URL of the nagflux template, depending on the version.
+func (connector Connector) templateURL() string {
+	if !helper.ElasticUsesMappingTypes(connector.version) && connector.supportsIndexTemplates() {
+		return connector.connectionHost + "_index_template/" + connector.index
+	}
+	return connector.connectionHost + "_template/" + connector.index
+}
+
+//authorize adds the credentials to the request, if configured.
+func (connector Connector) authorize(req *http.Request) {
+	if connector.apiKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+connector.apiKey)
+	} else if connector.username != "" {
+		req.SetBasicAuth(connector.username, connector.password)
+	}
+}
+
+//sendRequest makes an authorized request. If the returncode is 2XX it will return true and the body else the error message.
+func (connector Connector) sendRequest(function, url, data string) (bool, string) {
+	req, err := http.NewRequest(function, url, bytes.NewBufferString(data))
+	if err != nil {
+		return false, err.Error()
+	}
+	req.Header.Set("User-Agent", "Nagflux")
+	if data != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	connector.authorize(req)
+	resp, err := connector.httpClient.Do(req)
+	if err != nil {
+		return false, err.Error()
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return true, string(body)
+	}
+	return false, resp.Status
+}
+
 //NagfluxTemplate creates a template for settings and mapping for nagflux indices.
 const NagfluxTemplate = `{
   "template": "%s-*",
@@ -0,0 +1,66 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/logging"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateTemplate(t *testing.T) {
+	logging.InitTestLogger()
+	config.InitConfigFromString(`[ElasticsearchGlobal]
+	NumberOfShards = 1
+	NumberOfReplicas = 1`)
+	for _, data := range []struct {
+		version, path string
+		username      string
+		apiKey        string
+		authorization string
+	}{
+		{"2.1", "/_template/nagflux", "", "", ""},
+		{"7.4", "/_template/nagflux", "elastic", "", "Basic ZWxhc3RpYzpzZWNyZXQ="},
+		{"8.11", "/_index_template/nagflux", "elastic", "a2V5", "ApiKey a2V5"},
+		{"opensearch-2.11", "/_index_template/nagflux", "", "", ""},
+	} {
+		var body map[string]interface{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != data.path {
+				t.Errorf("%s: expected path:%s, actual:%s", data.version, data.path, r.URL.Path)
+			}
+			if auth := r.Header.Get("Authorization"); auth != data.authorization {
+				t.Errorf("%s: expected auth:%s, actual:%s", data.version, data.authorization, auth)
+			}
+			raw, _ := ioutil.ReadAll(r.Body)
+			if err := json.Unmarshal(raw, &body); err != nil {
+				t.Errorf("%s: template is not valid JSON: %s", data.version, err)
+			}
+		}))
+		connector := Connector{connectionHost: server.URL + "/", index: "nagflux", version: data.version,
+			log: logging.GetLogger(), username: data.username, password: "secret", apiKey: data.apiKey}
+		if !connector.createTemplate() {
+			t.Errorf("%s: template was not created", data.version)
+		}
+		mappings, _ := body["mappings"].(map[string]interface{})
+		template, composable := body["template"].(map[string]interface{})
+		if composable {
+			mappings, _ = template["mappings"].(map[string]interface{})
+		}
+		_, typed := mappings["messages"]
+		if typed != (data.version == "2.1") || composable != (data.path == "/_index_template/nagflux") {
+			t.Errorf("%s: unexpected template: %v", data.version, body)
+		}
+		if !typed {
+			properties, _ := mappings["properties"].(map[string]interface{})
+			measurement, _ := properties["measurement"].(map[string]interface{})
+			message, _ := properties["message"].(map[string]interface{})
+			if measurement["type"] != "keyword" || message["type"] != "text" {
+				t.Errorf("%s: unexpected properties: %v", data.version, properties)
+			}
+		}
+		server.Close()
+	}
+}
@@ -0,0 +1,50 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/helper"
+	"strconv"
+)
+
+//EncodePrintable renders the points of the printable as bulk requests, one action and document per point.
+//A collector.SimplePrintable is taken as it is, if it was meant for Elasticsearch.
+func EncodePrintable(printable collector.Printable, version, index string) []string {
+	if p, ok := printable.(collector.SimplePrintable); ok {
+		if p.Datatype == data.Elasticsearch && p.Text != "" {
+			return []string{p.Text}
+		}
+		return nil
+	}
+	var documents []string
+	for _, point := range printable.Points() {
+		documents = append(documents, EncodePoint(point, version, index))
+	}
+	return documents
+}
+
+//EncodePoint renders the action and the document of the point, the measurement is used as mapping type. Versions without
+//mapping types get the measurement as field. The document contains the timestamp, the tags as strings and the fields with their type.
+func EncodePoint(point collector.Point, version, index string) string {
+	point = point.WithHostcheckAlias(config.GetConfig().ElasticsearchGlobal.HostcheckAlias)
+	timestamp := strconv.FormatInt(point.Timestamp, 10)
+	head := helper.GenElasticBulkHeader(version, helper.GenIndex(index, timestamp), point.Measurement) + "\n"
+	document := `{"timestamp":` + timestamp
+	if !helper.ElasticUsesMappingTypes(version) {
+		m, _ := json.Marshal(point.Measurement)
+		document += `,"measurement":` + string(m)
+	}
+	for _, key := range point.TagKeys() {
+		k, _ := json.Marshal(key)
+		v, _ := json.Marshal(point.Tags[key])
+		document += "," + string(k) + ":" + string(v)
+	}
+	for _, key := range point.FieldKeys() {
+		k, _ := json.Marshal(key)
+		v, _ := point.Fields[key].MarshalJSON()
+		document += "," + string(k) + ":" + string(v)
+	}
+	return head + document + "}\n"
+}
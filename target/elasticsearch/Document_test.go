@@ -0,0 +1,46 @@
+package elasticsearch
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"testing"
+)
+
+func TestEncodePoint(t *testing.T) {
+	config.InitConfigFromString(`[ElasticsearchGlobal]
+	HostcheckAlias = "host check"
+	IndexRotation = "monthly"`)
+	point := collector.Point{
+		Kind: collector.MessageKind, Measurement: "messages", Timestamp: 1458988932000,
+		Tags:   map[string]string{"host": "host 1", "author": "philip", "type": "comment"},
+		Fields: data.Fields{"message": data.String(`hallo "world"`)},
+	}
+	expected := `{"index":{"_index":"index-2016.03","_type":"messages"}}
+{"timestamp":1458988932000,"author":"philip","host":"host 1","service":"host check","type":"comment","message":"hallo \"world\""}
+`
+	if actual := EncodePoint(point, "2.0", "index"); actual != expected {
+		t.Errorf("Expected:%s\nResult:%s", expected, actual)
+	}
+
+	expected = `{"index":{"_index":"index-2016.03"}}
+{"timestamp":1458988932000,"measurement":"messages","author":"philip","host":"host 1","service":"host check","type":"comment","message":"hallo \"world\""}
+`
+	for _, version := range []string{"7.10", "8.11", "opensearch-2.11"} {
+		if actual := EncodePoint(point, version, "index"); actual != expected {
+			t.Errorf("%s: Expected:%s\nResult:%s", version, expected, actual)
+		}
+	}
+
+	metric := collector.Point{
+		Kind: collector.PerfdataKind, Measurement: "metrics", Timestamp: 1458988932000,
+		Tags:   map[string]string{"host": "h", "service": "s"},
+		Fields: data.Fields{"value": data.Float(1), "count": data.Int(2)},
+	}
+	expected = `{"index":{"_index":"index-2016.03","_type":"metrics"}}
+{"timestamp":1458988932000,"host":"h","service":"s","count":2,"value":1.0}
+`
+	if actual := EncodePoint(metric, "2.0", "index"); actual != expected {
+		t.Errorf("Expected:%s\nResult:%s", expected, actual)
+	}
+}
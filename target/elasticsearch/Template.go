@@ -0,0 +1,113 @@
+package elasticsearch
+
+//typelessSettingsAndMappings are the settings and the mapping without mapping types, metrics and messages share one mapping
+//and are told apart by the measurement. The message is text, a keyword would reject outputs longer than 32766 bytes.
+const typelessSettingsAndMappings = `
+  "settings": {
+    "index": {
+      "number_of_shards": "%d",
+      "number_of_replicas": "%d",
+      "refresh_interval": "60s"
+    }
+  },
+  "mappings": {
+    "_source": {
+      "enabled": false
+    },
+    "dynamic_templates": [
+      {
+        "strings": {
+          "mapping": {
+            "type": "keyword"
+          },
+          "match_mapping_type": "string",
+          "match": "*"
+        }
+      }
+    ],
+    "properties": {
+      "timestamp": {
+        "format": "strict_date_optional_time||epoch_millis",
+        "type": "date"
+      },
+      "measurement": {
+        "type": "keyword"
+      },
+      "host": {
+        "type": "keyword"
+      },
+      "service": {
+        "type": "keyword"
+      },
+      "author": {
+        "type": "keyword"
+      },
+      "type": {
+        "type": "keyword"
+      },
+      "message": {
+        "type": "text",
+        "fields": {
+          "keyword": {
+            "type": "keyword",
+            "ignore_above": 8191
+          }
+        }
+      },
+      "command": {
+        "type": "keyword"
+      },
+      "performanceLabel": {
+        "type": "keyword"
+      },
+      "value": {
+        "type": "float"
+      },
+      "warn": {
+        "type": "float"
+      },
+      "warn-min": {
+        "type": "float"
+      },
+      "warn-max": {
+        "type": "float"
+      },
+      "warn-fill": {
+        "type": "keyword"
+      },
+      "crit": {
+        "type": "float"
+      },
+      "crit-min": {
+        "type": "float"
+      },
+      "crit-max": {
+        "type": "float"
+      },
+      "crit-fill": {
+        "type": "keyword"
+      },
+      "min": {
+        "type": "float"
+      },
+      "max": {
+        "type": "float"
+      },
+      "downtime": {
+        "type": "boolean"
+      }
+    }
+  }`
+
+//NagfluxIndexTemplate is the composable index template for Elasticsearch 7.8 and newer and OpenSearch.
+const NagfluxIndexTemplate = `{
+  "index_patterns": ["%s-*"],
+  "priority": 100,
+  "template": {` + typelessSettingsAndMappings + `
+  }
+}`
+
+//NagfluxTypelessTemplate is the legacy template without mapping types for Elasticsearch 7.0 till 7.7.
+const NagfluxTypelessTemplate = `{
+  "index_patterns": ["%s-*"],` + typelessSettingsAndMappings + `
+}`
@@ -0,0 +1,43 @@
+package elasticsearch
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/statistics"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWorkerSendsBatch(t *testing.T) {
+	logging.InitTestLogger()
+	config.InitConfigFromString(`[ElasticsearchGlobal]
+	IndexRotation = "monthly"`)
+	statistics.NewPrometheusServer("")
+	bodies := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := ioutil.ReadAll(r.Body)
+		bodies <- r.URL.Path + " " + string(raw)
+		w.Write([]byte(`{"errors":false}`))
+	}))
+	defer server.Close()
+
+	connector := &Connector{isAlive: true, templateExists: true, log: logging.GetLogger()}
+	jobs := make(chan collector.Printable)
+	target := data.Target{Name: "es", Datatype: data.Elasticsearch}
+	worker := WorkerGenerator(jobs, server.URL+"/_bulk", "nagflux", "", "8.11", connector, target)(0)
+	jobs <- collector.SimplePrintable{Filterable: collector.AllFilterable, Datatype: data.Elasticsearch, Text: `{"index":{}}` + "\n{}\n"}
+	worker.Stop()
+
+	select {
+	case body := <-bodies:
+		if body != "/_bulk {\"index\":{}}\n{}\n" {
+			t.Errorf("Unexpected request: %q", body)
+		}
+	default:
+		t.Error("The batch was not sent on stop")
+	}
+}
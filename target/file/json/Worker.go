@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/config"
 	"github.com/spitefulgrog/nagflux/data"
 	"github.com/kdar/factorlog"
 	"io/ioutil"
@@ -92,7 +93,18 @@ func (t JSONFileWorker) run() {
 }
 
 func (t JSONFileWorker) writeData(data []collector.Printable) {
-	if len(data) == 0 {
+	points := []collector.Point{}
+	for _, printable := range data {
+		for _, point := range printable.Points() {
+			points = append(points, point.WithHostcheckAlias(config.GetConfig().InfluxDBGlobal.HostcheckAlias))
+		}
+	}
+	t.writePoints(points)
+}
+
+//Writes the points as JSON array if the files are rotated, one object per line otherwise.
+func (t JSONFileWorker) writePoints(points []collector.Point) {
+	if len(points) == 0 {
 		return
 	}
 	filePath := t.getFilename()
@@ -100,9 +112,9 @@ func (t JSONFileWorker) writeData(data []collector.Printable) {
 		if _, err := os.Stat(filePath); err == nil {
 			t.log.Debugf("JSON file(%s) already exists, waiting for an second", filePath)
 			time.Sleep(time.Duration(1) * time.Second)
-			t.writeData(data)
+			t.writePoints(points)
 		}
-		out, err := json.Marshal(data)
+		out, err := json.Marshal(points)
 		if err != nil {
 			t.log.Critical("JSON rotation marshal err:", err)
 			return
@@ -113,8 +125,8 @@ func (t JSONFileWorker) writeData(data []collector.Printable) {
 		}
 	} else {
 		dataToWrite := []byte("")
-		for _, d := range data {
-			out, err := json.Marshal(d)
+		for _, point := range points {
+			out, err := json.Marshal(point)
 			if err != nil {
 				t.log.Critical("JSON no rotation marshal err:", err)
 				return
@@ -0,0 +1,118 @@
+package graphite
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/target"
+	"github.com/kdar/factorlog"
+	"net"
+	"time"
+)
+
+const (
+	//Plaintext is the line based Carbon protocol, usually on port 2003.
+	Plaintext = "plaintext"
+	//Pickle is the pickle based Carbon protocol, usually on port 2004.
+	Pickle = "pickle"
+)
+
+//Connector makes the basic connection to a Carbon daemon.
+type Connector struct {
+	connectionHost string
+	protocol       string
+	template       string
+	prefix         string
+	dumpFile       string
+	*target.Workers
+	jobs                  chan collector.Printable
+	log                   *factorlog.FactorLog
+	isAlive               bool
+	timeout               time.Duration
+	target                data.Target
+	stopReadingDataIfDown bool
+}
+
+//ConnectorFactory Constructor which will create some workers if the connection is established.
+func ConnectorFactory(jobs chan collector.Printable, connectionHost, protocol, template, prefix, dumpFile string,
+	workerAmount, maxWorkers int, stopReadingDataIfDown bool, target data.Target, clientTimeout int) *Connector {
+	s := &Connector{
+		connectionHost: connectionHost, protocol: protocol, template: template, prefix: prefix, dumpFile: dumpFile,
+		jobs: jobs, log: logging.GetLogger(), isAlive: false, timeout: time.Duration(clientTimeout) * time.Second,
+		target: target, stopReadingDataIfDown: stopReadingDataIfDown,
+	}
+	if s.protocol != Plaintext && s.protocol != Pickle {
+		s.log.Warnf("Graphite(%s) protocol '%s' is unknown, options are: %s, %s. Using %s", target.Name, protocol, Plaintext, Pickle, Plaintext)
+		s.protocol = Plaintext
+	}
+
+	gen := WorkerGenerator(jobs, s, dumpFile, target, stopReadingDataIfDown)
+	s.TestIfIsAlive(stopReadingDataIfDown)
+	if !s.isAlive && !stopReadingDataIfDown {
+		s.log.Warnf("Graphite server(%s) is down but starting anyway due to 'stopReadingDataIfDown' = %t", target.Name, stopReadingDataIfDown)
+	} else {
+		if !s.isAlive {
+			s.log.Info("Waiting for Graphite server(" + target.Name + ")")
+		}
+		for !s.isAlive {
+			s.TestIfIsAlive(stopReadingDataIfDown)
+			time.Sleep(time.Duration(5) * time.Second)
+			s.log.Debugln("Waiting for Graphite server (" + target.Name + ")")
+		}
+		s.log.Debug("Graphite(" + target.Name + ") is running")
+	}
+
+	s.Workers = startWorkers(workerAmount, maxWorkers, gen)
+	return s
+}
+
+//IsAlive is the Carbon daemon reachable.
+func (connector Connector) IsAlive() bool {
+	return connector.isAlive
+}
+
+//Encode converts the printable into entries of the write-ahead log, everything besides perfdata is ignored.
+func (connector Connector) Encode(printable collector.Printable) []string {
+	if p, ok := printable.(collector.SimplePrintable); ok {
+		if p.Datatype == data.Graphite {
+			return []string{p.Text}
+		}
+		return nil
+	}
+	return metricsToLines(PrintableToMetrics(printable, connector.template, connector.prefix))
+}
+
+//Stop the connector and its workers.
+func (connector *Connector) Stop() {
+	connector.StopWorkers()
+	connector.log.Debug("GraphiteConnectorFactory stopped")
+}
+
+//TestIfIsAlive test active if the Carbon daemon accepts connections.
+func (connector *Connector) TestIfIsAlive(stopReadingDataIfDown bool) bool {
+	result := false
+	if conn, err := connector.dial(); err == nil {
+		conn.Close()
+		result = true
+	}
+	connector.isAlive = result
+	connector.log.Infof("Is Graphite(%s) running: %t", connector.target.Name, result)
+	if stopReadingDataIfDown {
+		config.StoreValue(connector.target, !result)
+	}
+	return result
+}
+
+//dial opens a new connection to the Carbon daemon.
+func (connector Connector) dial() (net.Conn, error) {
+	return net.DialTimeout("tcp", connector.connectionHost, connector.timeout)
+}
+
+//encode renders the metrics in the configured protocol.
+func (connector Connector) encode(metrics []Metric) []byte {
+	if connector.protocol == Pickle {
+		return EncodePickle(metrics)
+	}
+	return EncodePlaintext(metrics)
+}
@@ -0,0 +1,139 @@
+package graphite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/config"
+	"math"
+	"strconv"
+	"strings"
+)
+
+//DefaultTemplate is used if no template is configured.
+const DefaultTemplate = "{prefix}.{host}.{service}.{label}.{field}"
+
+//Metric is a single Carbon datapoint.
+type Metric struct {
+	Path      string
+	Value     float64
+	Timestamp int64
+}
+
+var pathSanitizer = strings.NewReplacer(".", "_", " ", "_", "\t", "_", "\n", "_", "\\", "_", "'", "")
+
+//SanitizePath replaces the chars which have a special meaning in Carbon paths.
+func SanitizePath(input string) string {
+	return pathSanitizer.Replace(input)
+}
+
+//String returns the metric in the plaintext protocol.
+func (m Metric) String() string {
+	return fmt.Sprintf("%s %s %d\n", m.Path, strconv.FormatFloat(m.Value, 'f', -1, 64), m.Timestamp)
+}
+
+//ParseMetric parses a line of the plaintext protocol.
+func ParseMetric(line string) (Metric, error) {
+	parts := strings.Fields(line)
+	if len(parts) != 3 {
+		return Metric{}, errors.New("Not a plaintext metric: " + line)
+	}
+	value, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return Metric{}, err
+	}
+	timestamp, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return Metric{}, err
+	}
+	return Metric{Path: parts[0], Value: value, Timestamp: timestamp}, nil
+}
+
+//PointToMetrics renders every numeric field with the given template, just perfdata points are converted.
+//Placeholders are {prefix}, {host}, {service}, {command}, {label}, {unit} and {field}.
+func PointToMetrics(point collector.Point, template, prefix string) []Metric {
+	if point.Kind != collector.PerfdataKind {
+		return nil
+	}
+	point = point.WithHostcheckAlias(config.GetConfig().InfluxDBGlobal.HostcheckAlias)
+	var result []Metric
+	for _, field := range point.FieldKeys() {
+		value, ok := point.Fields[field].Float64()
+		if !ok {
+			//e.g. unknown=true
+			continue
+		}
+		path := strings.NewReplacer(
+			"{prefix}", prefix,
+			"{host}", SanitizePath(point.Tags["host"]),
+			"{service}", SanitizePath(point.Tags["service"]),
+			"{command}", SanitizePath(point.Tags["command"]),
+			"{label}", SanitizePath(point.Tags["performanceLabel"]),
+			"{unit}", SanitizePath(point.Tags["unit"]),
+			"{field}", SanitizePath(field),
+		).Replace(template)
+		result = append(result, Metric{Path: dropEmptyNodes(path), Value: value, Timestamp: point.Timestamp / 1000})
+	}
+	return result
+}
+
+//dropEmptyNodes removes the empty nodes which are left by empty placeholders like {unit}, even adjacent ones.
+func dropEmptyNodes(path string) string {
+	var nodes []string
+	for _, node := range strings.Split(path, ".") {
+		if node != "" {
+			nodes = append(nodes, node)
+		}
+	}
+	return strings.Join(nodes, ".")
+}
+
+//PrintableToMetrics converts every point of the printable.
+func PrintableToMetrics(printable collector.Printable, template, prefix string) []Metric {
+	var result []Metric
+	for _, point := range printable.Points() {
+		result = append(result, PointToMetrics(point, template, prefix)...)
+	}
+	return result
+}
+
+//EncodePlaintext renders the metrics in the plaintext protocol.
+func EncodePlaintext(metrics []Metric) []byte {
+	var buffer bytes.Buffer
+	for _, m := range metrics {
+		buffer.WriteString(m.String())
+	}
+	return buffer.Bytes()
+}
+
+//EncodePickle renders the metrics as a length prefixed pickle (protocol 2) of [(path, (timestamp, value)), ...].
+func EncodePickle(metrics []Metric) []byte {
+	var payload bytes.Buffer
+	payload.Write([]byte{0x80, 2}) //PROTO 2
+	payload.WriteByte(']')         //EMPTY_LIST
+	payload.WriteByte('(')         //MARK
+	for _, m := range metrics {
+		payload.WriteByte('X') //BINUNICODE
+		binary.Write(&payload, binary.LittleEndian, uint32(len(m.Path)))
+		payload.WriteString(m.Path)
+		if m.Timestamp >= math.MinInt32 && m.Timestamp <= math.MaxInt32 {
+			payload.WriteByte('J') //BININT
+			binary.Write(&payload, binary.LittleEndian, int32(m.Timestamp))
+		} else {
+			payload.WriteByte('G') //BINFLOAT
+			binary.Write(&payload, binary.BigEndian, float64(m.Timestamp))
+		}
+		payload.WriteByte('G') //BINFLOAT
+		binary.Write(&payload, binary.BigEndian, m.Value)
+		payload.WriteByte(0x86) //TUPLE2 (timestamp, value)
+		payload.WriteByte(0x86) //TUPLE2 (path, (timestamp, value))
+	}
+	payload.WriteByte('e') //APPENDS
+	payload.WriteByte('.') //STOP
+
+	result := make([]byte, 4, 4+payload.Len())
+	binary.BigEndian.PutUint32(result, uint32(payload.Len()))
+	return append(result, payload.Bytes()...)
+}
@@ -0,0 +1,76 @@
+package graphite
+
+import (
+	"bytes"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/collector/spoolfile"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"reflect"
+	"testing"
+)
+
+var perf = spoolfile.PerformanceData{
+	Filterable:       collector.AllFilterable,
+	Hostname:         "web.example.com",
+	Service:          "disk usage",
+	Command:          "check_disk",
+	PerformanceLabel: "'C: used'",
+	Time:             "1441791000123",
+	Tags:             map[string]string{},
+	Fields:           data.Fields{"value": data.Float(44.5), "warn-min": data.Float(89.0), "unknown": data.Bool(true)},
+}
+
+func TestPrintableToMetrics(t *testing.T) {
+	config.InitConfigFromString(`[InfluxDBGlobal]
+	HostcheckAlias = "hostcheck"`)
+	expected := []Metric{
+		{Path: "nagflux.web_example_com.disk_usage.C:_used.value", Value: 44.5, Timestamp: 1441791000},
+		{Path: "nagflux.web_example_com.disk_usage.C:_used.warn-min", Value: 89, Timestamp: 1441791000},
+	}
+	if actual := PrintableToMetrics(perf, DefaultTemplate, "nagflux"); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected:%v\nResult:%v", expected, actual)
+	}
+
+	hostcheck := perf
+	hostcheck.Service = ""
+	actual := PrintableToMetrics(hostcheck, "{prefix}.{host}.{unit}.{service}.{field}", "")
+	if len(actual) != 2 || actual[0].Path != "web_example_com.hostcheck.value" {
+		t.Errorf("Empty placeholders should not create empty nodes: %v", actual)
+	}
+
+	noCommand := perf
+	noCommand.Command = ""
+	actual = PrintableToMetrics(noCommand, "{prefix}.{host}.{command}.{unit}.{field}", "")
+	if len(actual) != 2 || actual[0].Path != "web_example_com.value" {
+		t.Errorf("Adjacent empty placeholders should not create empty nodes: %v", actual)
+	}
+}
+
+func TestParseMetric(t *testing.T) {
+	t.Parallel()
+	metric := Metric{Path: "a.b.c", Value: 1.5, Timestamp: 1441791000}
+	parsed, err := ParseMetric(metric.String())
+	if err != nil || parsed != metric {
+		t.Errorf("Expected:%v Result:%v Error:%v", metric, parsed, err)
+	}
+	if _, err := ParseMetric("a.b.c 1.5"); err == nil {
+		t.Error("Expected an error for an incomplete line")
+	}
+}
+
+func TestEncodePickle(t *testing.T) {
+	t.Parallel()
+	result := EncodePickle([]Metric{{Path: "a.b", Value: 1, Timestamp: 2}})
+	expected := []byte{
+		0, 0, 0, 30,
+		0x80, 2, ']', '(',
+		'X', 3, 0, 0, 0, 'a', '.', 'b',
+		'J', 2, 0, 0, 0,
+		'G', 0x3f, 0xf0, 0, 0, 0, 0, 0, 0,
+		0x86, 0x86, 'e', '.',
+	}
+	if !bytes.Equal(result, expected) {
+		t.Errorf("Expected:%v\nResult:%v", expected, result)
+	}
+}
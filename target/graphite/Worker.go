@@ -0,0 +1,157 @@
+package graphite
+
+import (
+	"bytes"
+	"errors"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/target"
+	"net"
+	"time"
+)
+
+//Worker sends the batches of its BatchWorker to the Carbon daemon.
+type Worker struct {
+	*target.BatchWorker
+	connector             *Connector
+	conn                  net.Conn
+	stopReadingDataIfDown bool
+}
+
+var errorFailedToSend = errors.New("Could not send data")
+
+//WorkerGenerator generates a new Worker and starts it.
+func WorkerGenerator(jobs chan collector.Printable, connector *Connector, dumpFile string,
+	t data.Target, stopReadingDataIfDown bool) func(workerId int) *Worker {
+	return func(workerId int) *Worker {
+		worker := &Worker{connector: connector, stopReadingDataIfDown: stopReadingDataIfDown}
+		worker.BatchWorker = target.NewBatchWorker(workerId, jobs, t, dumpFile, worker)
+		worker.Start()
+		return worker
+	}
+}
+
+//Starts the workers of a connector.
+func startWorkers(amount, maxWorkers int, gen func(workerId int) *Worker) *target.Workers {
+	return target.NewWorkers(amount, maxWorkers, func(workerID int) target.Worker {
+		return gen(workerID)
+	})
+}
+
+//IsReady tests the Carbon daemon again if it was down, unless the data is read anyway.
+func (worker *Worker) IsReady() bool {
+	if worker.stopReadingDataIfDown && !worker.connector.IsAlive() {
+		worker.connector.TestIfIsAlive(worker.stopReadingDataIfDown)
+		return false
+	}
+	return true
+}
+
+//Encode converts the printable into lines of the plaintext protocol.
+func (worker *Worker) Encode(printable collector.Printable) []string {
+	return worker.connector.Encode(printable)
+}
+
+//Close closes the kept connection when the worker stops.
+func (worker *Worker) Close() {
+	worker.closeConnection()
+}
+
+//Send sends the given printables as metrics to the Carbon daemon.
+func (worker *Worker) Send(printables []collector.Printable) {
+	var metrics []Metric
+	for _, printable := range printables {
+		metrics = append(metrics, worker.castJobToMetrics(printable)...)
+	}
+	if len(metrics) == 0 {
+		return
+	}
+
+	startTime := time.Now()
+	pending, size, sendErr := worker.sendData(metrics)
+	if sendErr != nil {
+		worker.connector.TestIfIsAlive(worker.stopReadingDataIfDown)
+		for i := 0; i < 2 && sendErr != nil; i++ {
+			if err := worker.WaitForQuitOrGoOn(); err != nil {
+				//No error handling, because it's time to terminate
+				worker.Spill(metricsToLines(pending))
+				worker.DumpRemaining(nil)
+				return
+			}
+			//Resend what did not get through
+			var written int
+			pending, written, sendErr = worker.sendData(pending)
+			size += written
+		}
+		if sendErr != nil {
+			//if there is still an error spill the metrics and go on
+			worker.Log.Info("Spilling metrics which couldn't be sent to the write-ahead log")
+			worker.Spill(metricsToLines(pending))
+			return
+		}
+	}
+	worker.Sent("Graphite", size, startTime)
+}
+
+//sends the metrics over the kept connection, reconnects if needed. Returns the metrics which did not get through and
+//the amount of bytes which did. Carbon drops an incomplete line or pickle frame with the connection, so the complete
+//lines of a partial write are sent, a pickle frame is sent completely or not at all.
+func (worker *Worker) sendData(metrics []Metric) ([]Metric, int, error) {
+	if worker.conn == nil {
+		conn, err := worker.connector.dial()
+		if err != nil {
+			worker.Log.Warn(err)
+			return metrics, 0, errorFailedToSend
+		}
+		worker.conn = conn
+	}
+	if worker.connector.timeout > 0 {
+		worker.conn.SetWriteDeadline(time.Now().Add(worker.connector.timeout))
+	}
+	rawData := worker.connector.encode(metrics)
+	n, err := worker.conn.Write(rawData)
+	if err == nil {
+		return nil, n, nil
+	}
+	worker.Log.Warn(err)
+	worker.closeConnection()
+	if worker.connector.protocol == Pickle {
+		return metrics, 0, errorFailedToSend
+	}
+	//every metric is one line
+	complete := bytes.Count(rawData[:n], []byte("\n"))
+	return metrics[complete:], bytes.LastIndexByte(rawData[:n], '\n') + 1, errorFailedToSend
+}
+
+//closeConnection closes the connection, the next send will open a new one.
+func (worker *Worker) closeConnection() {
+	if worker.conn != nil {
+		worker.conn.Close()
+		worker.conn = nil
+	}
+}
+
+//metricsToLines converts the metrics to lines of the plaintext protocol.
+func metricsToLines(metrics []Metric) []string {
+	lines := make([]string, 0, len(metrics))
+	for _, m := range metrics {
+		lines = append(lines, m.String())
+	}
+	return lines
+}
+
+//Converts an collector.Printable to metrics, everything besides perfdata and dumped metrics is ignored.
+func (worker Worker) castJobToMetrics(job collector.Printable) []Metric {
+	if printable, ok := job.(collector.SimplePrintable); ok {
+		if printable.Datatype != data.Graphite || printable.Text == "" {
+			return nil
+		}
+		metric, err := ParseMetric(printable.Text)
+		if err != nil {
+			worker.Log.Warn("Could not parse dumped metric: ", err)
+			return nil
+		}
+		return []Metric{metric}
+	}
+	return PrintableToMetrics(job, worker.connector.template, worker.connector.prefix)
+}
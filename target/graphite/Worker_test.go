@@ -0,0 +1,87 @@
+package graphite
+
+import (
+	"errors"
+	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/target"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+//brokenConn accepts the given amount of bytes, then the write fails.
+type brokenConn struct {
+	net.Conn
+	accept int
+}
+
+func (c *brokenConn) Write(b []byte) (int, error) {
+	if len(b) > c.accept {
+		return c.accept, errors.New("broken pipe")
+	}
+	return len(b), nil
+}
+
+func (c *brokenConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func (c *brokenConn) Close() error { return nil }
+
+var partialMetrics = []Metric{
+	{Path: "a.value", Value: 1, Timestamp: 1441791000},
+	{Path: "b.value", Value: 2, Timestamp: 1441791000},
+	{Path: "c.value", Value: 3, Timestamp: 1441791000},
+}
+
+func newTestWorker(connectionHost, protocol string, conn net.Conn) *Worker {
+	logging.InitTestLogger()
+	connector := &Connector{connectionHost: connectionHost, protocol: protocol, log: logging.GetLogger(), timeout: time.Duration(5) * time.Second}
+	return &Worker{BatchWorker: &target.BatchWorker{Log: logging.GetLogger()}, connector: connector, conn: conn}
+}
+
+func TestSendDataResendsTheRestOfAPartialWrite(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		raw, _ := ioutil.ReadAll(conn)
+		received <- string(raw)
+	}()
+
+	//the first line and half of the second one get through
+	firstLine := len(partialMetrics[0].String())
+	worker := newTestWorker(listener.Addr().String(), Plaintext, &brokenConn{accept: firstLine + 3})
+	pending, written, err := worker.sendData(partialMetrics)
+	if err == nil || written != firstLine || !reflect.DeepEqual(pending, partialMetrics[1:]) {
+		t.Fatalf("Unexpected result: %v %d %v", pending, written, err)
+	}
+	if worker.conn != nil {
+		t.Error("The broken connection should be closed")
+	}
+
+	pending, written, err = worker.sendData(pending)
+	if err != nil || pending != nil {
+		t.Fatalf("Unexpected result: %v %v", pending, err)
+	}
+	worker.closeConnection()
+	expected := string(EncodePlaintext(partialMetrics[1:]))
+	if actual := <-received; actual != expected || written != len(expected) {
+		t.Errorf("Expected:%q\nResult:%q", expected, actual)
+	}
+}
+
+func TestSendDataResendsAPartialPickleFrame(t *testing.T) {
+	worker := newTestWorker("127.0.0.1:0", Pickle, &brokenConn{accept: 10})
+	pending, written, err := worker.sendData(partialMetrics)
+	if err == nil || written != 0 || !reflect.DeepEqual(pending, partialMetrics) {
+		t.Errorf("Unexpected result: %v %d %v", pending, written, err)
+	}
+}
@@ -0,0 +1,18 @@
+package influx
+
+//BucketsResult represents the JSON result of /api/v2/buckets
+type BucketsResult struct {
+	Buckets []struct {
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		OrgID string `json:"orgID"`
+	} `json:"buckets"`
+}
+
+//OrgsResult represents the JSON result of /api/v2/orgs
+type OrgsResult struct {
+	Orgs []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"orgs"`
+}
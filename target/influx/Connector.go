@@ -9,6 +9,7 @@ import (
 	"github.com/spitefulgrog/nagflux/data"
 	"github.com/spitefulgrog/nagflux/helper"
 	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/target"
 	"github.com/kdar/factorlog"
 	"io/ioutil"
 	"net/http"
@@ -17,13 +18,11 @@ import (
 
 //Connector makes the basic connection to an Influxdb.
 type Connector struct {
-	connectionHost        string
-	connectionArgs        string
-	dumpFile              string
-	workers               []*Worker
-	maxWorkers            int
+	connectionHost string
+	connectionArgs string
+	dumpFile       string
+	*target.Workers
 	jobs                  chan collector.Printable
-	quit                  chan bool
 	log                   *factorlog.FactorLog
 	version               string
 	isAlive               bool
@@ -47,8 +46,7 @@ func ConnectorFactory(jobs chan collector.Printable, connectionHost, connectionA
 	client := http.Client{Timeout: timeout, Transport: transport}
 	s := &Connector{
 		connectionHost: connectionHost, connectionArgs: connectionArgs, dumpFile: dumpFile,
-		workers: make([]*Worker, workerAmount), maxWorkers: maxWorkers, jobs: jobs, quit: make(chan bool),
-		log: logging.GetLogger(), version: version, isAlive: false, databaseExists: false, databaseName: databaseName,
+		jobs: jobs, log: logging.GetLogger(), version: version, isAlive: false, databaseExists: false, databaseName: databaseName,
 		httpClient: client, target: target, stopReadingDataIfDown: stopReadingDataIfDown,
 	}
 
@@ -88,42 +86,10 @@ func ConnectorFactory(jobs chan collector.Printable, connectionHost, connectionA
 		}
 	}
 
-	for w := 0; w < workerAmount; w++ {
-		s.workers[w] = gen(w)
-	}
-	go s.run()
+	s.Workers = startWorkers(workerAmount, maxWorkers, gen)
 	return s
 }
 
-//AddWorker creates a new worker
-func (connector *Connector) AddWorker() {
-	oldLength := connector.AmountWorkers()
-	if oldLength < connector.maxWorkers {
-		gen := WorkerGenerator(
-			connector.jobs, connector.connectionHost+"/write?"+connector.connectionArgs,
-			connector.dumpFile, connector.version, connector, connector.target, connector.stopReadingDataIfDown,
-		)
-		connector.workers = append(connector.workers, gen(oldLength+2))
-		connector.log.Infof("Starting Worker: %d -> %d", oldLength, connector.AmountWorkers())
-	}
-}
-
-//RemoveWorker stops a worker
-func (connector *Connector) RemoveWorker() {
-	oldLength := connector.AmountWorkers()
-	if oldLength > 1 {
-		lastWorkerIndex := oldLength - 1
-		connector.workers[lastWorkerIndex].Stop()
-		connector.workers = connector.workers[:lastWorkerIndex]
-		connector.log.Infof("Stopping Worker: %d -> %d", oldLength, connector.AmountWorkers())
-	}
-}
-
-//AmountWorkers current amount of workers.
-func (connector Connector) AmountWorkers() int {
-	return len(connector.workers)
-}
-
 //IsAlive is the database system alive.
 func (connector Connector) IsAlive() bool {
 	return connector.isAlive
@@ -134,37 +100,25 @@ func (connector Connector) DatabaseExists() bool {
 	return connector.databaseExists
 }
 
+//clientTimeout returns the timeout used by the http client.
+func (connector Connector) clientTimeout() time.Duration {
+	return connector.httpClient.Timeout
+}
+
+//authorize does nothing, the credentials of InfluxDB 1.x are part of the arguments.
+func (connector Connector) authorize(req *http.Request) {}
+
+//Encode converts the printable into entries of the write-ahead log.
+func (connector Connector) Encode(printable collector.Printable) []string {
+	return EncodePrintable(printable, connector.version)
+}
+
 //Stop the connector and its workers.
 func (connector *Connector) Stop() {
-	connector.quit <- true
-	<-connector.quit
+	connector.StopWorkers()
 	connector.log.Debug("InfluxConnectorFactory stopped")
 }
 
-//Waits just for the end.
-func (connector *Connector) run() {
-	for {
-		select {
-		case <-connector.quit:
-			for _, worker := range connector.workers {
-				go worker.Stop()
-			}
-			for len(connector.workers) > 0 {
-				for connector.workers[0].IsRunning == true {
-					time.Sleep(time.Duration(100) * time.Millisecond)
-				}
-				if len(connector.workers) > 1 {
-					connector.workers = connector.workers[1:]
-				} else {
-					connector.workers = connector.workers[:0]
-				}
-			}
-			connector.quit <- true
-			return
-		}
-	}
-}
-
 //TestIfIsAlive test active if the database system is alive.
 func (connector *Connector) TestIfIsAlive(stopReadingDataIfDown bool) bool {
 	result := helper.RequestedReturnCodeIsOK(connector.httpClient, connector.connectionHost+"/ping", "GET")
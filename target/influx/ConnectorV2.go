@@ -0,0 +1,233 @@
+package influx
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/helper"
+	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/target"
+	"github.com/kdar/factorlog"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+//V2Version is passed to the encoder, the 2.x line protocol is the same as the one of 0.9+.
+const V2Version = "2.0"
+
+//ConnectorV2 makes the basic connection to an InfluxDB 2.x, using the /api/v2 endpoints.
+type ConnectorV2 struct {
+	connectionHost string
+	organization   string
+	bucket         string
+	token          string
+	dumpFile       string
+	*target.Workers
+	jobs                  chan collector.Printable
+	log                   *factorlog.FactorLog
+	isAlive               bool
+	bucketExists          bool
+	httpClient            http.Client
+	target                data.Target
+	stopReadingDataIfDown bool
+}
+
+//ConnectorV2Factory Constructor which will create some workers if the connection is established.
+func ConnectorV2Factory(jobs chan collector.Printable, connectionHost, organization, bucket, token, dumpFile string,
+	workerAmount, maxWorkers int, createBucketIfNotExists, stopReadingDataIfDown bool, target data.Target, clientTimeout int) *ConnectorV2 {
+	timeout := time.Duration(time.Duration(clientTimeout) * time.Second)
+	transport := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	client := http.Client{Timeout: timeout, Transport: transport}
+	s := &ConnectorV2{
+		connectionHost: connectionHost, organization: organization, bucket: bucket, token: token, dumpFile: dumpFile,
+		jobs: jobs, log: logging.GetLogger(), isAlive: false, bucketExists: false,
+		httpClient: client, target: target, stopReadingDataIfDown: stopReadingDataIfDown,
+	}
+
+	gen := WorkerGenerator(jobs, s.writeURL(), dumpFile, V2Version, s, target, stopReadingDataIfDown)
+	s.TestIfIsAlive(stopReadingDataIfDown)
+	if !s.isAlive && !stopReadingDataIfDown {
+		s.log.Warnf("InfluxDB server(%s) is down but starting anyway due to 'stopReadingDataIfDown' = %t", target.Name, stopReadingDataIfDown)
+	} else {
+		if !s.isAlive {
+			s.log.Info("Waiting for InfluxDB server(" + target.Name + ")")
+		}
+		for !s.isAlive {
+			s.TestIfIsAlive(stopReadingDataIfDown)
+			time.Sleep(time.Duration(5) * time.Second)
+			s.log.Debugln("Waiting for InfluxDB server (" + target.Name + ")")
+		}
+		if s.isAlive {
+			s.log.Debug("Influxdb(" + target.Name + ") is running")
+		}
+		s.TestDatabaseExists()
+		for i := 0; i < 5 && !s.bucketExists; i++ {
+			time.Sleep(time.Duration(2) * time.Second)
+			if createBucketIfNotExists {
+				s.CreateBucket()
+			}
+			s.TestDatabaseExists()
+		}
+		if !s.bucketExists {
+			s.log.Critical("InfluxDB Bucket(" + bucket + ") does not exists and Nagflux was not able to create it")
+		}
+	}
+
+	s.Workers = startWorkers(workerAmount, maxWorkers, gen)
+	return s
+}
+
+//writeURL returns the url of the write endpoint, the timestamps of nagflux are always in ms.
+func (connector ConnectorV2) writeURL() string {
+	return fmt.Sprintf("%s/api/v2/write?org=%s&bucket=%s&precision=ms",
+		connector.connectionHost, url.QueryEscape(connector.organization), url.QueryEscape(connector.bucket))
+}
+
+//IsAlive is the database system alive.
+func (connector ConnectorV2) IsAlive() bool {
+	return connector.isAlive
+}
+
+//DatabaseExists does the bucket exist.
+func (connector ConnectorV2) DatabaseExists() bool {
+	return connector.bucketExists
+}
+
+//clientTimeout returns the timeout used by the http client.
+func (connector ConnectorV2) clientTimeout() time.Duration {
+	return connector.httpClient.Timeout
+}
+
+//authorize adds the token to the request.
+func (connector ConnectorV2) authorize(req *http.Request) {
+	if connector.token != "" {
+		req.Header.Set("Authorization", "Token "+connector.token)
+	}
+}
+
+//Encode converts the printable into entries of the write-ahead log.
+func (connector ConnectorV2) Encode(printable collector.Printable) []string {
+	return EncodePrintable(printable, V2Version)
+}
+
+//Stop the connector and its workers.
+func (connector *ConnectorV2) Stop() {
+	connector.StopWorkers()
+	connector.log.Debug("InfluxConnectorV2Factory stopped")
+}
+
+//TestIfIsAlive test active if the database system is alive.
+func (connector *ConnectorV2) TestIfIsAlive(stopReadingDataIfDown bool) bool {
+	result := helper.RequestedReturnCodeIsOK(connector.httpClient, connector.connectionHost+"/ping", "GET")
+	connector.isAlive = result
+	connector.log.Infof("Is InfluxDB(%s) running: %t", connector.target.Name, result)
+	if stopReadingDataIfDown {
+		config.StoreValue(connector.target, !result)
+	}
+	return result
+}
+
+//TestDatabaseExists test active if the bucket exists.
+func (connector *ConnectorV2) TestDatabaseExists() bool {
+	body, err := connector.get(fmt.Sprintf("/api/v2/buckets?org=%s&name=%s",
+		url.QueryEscape(connector.organization), url.QueryEscape(connector.bucket)))
+	if err != nil {
+		connector.log.Warn(err)
+		connector.bucketExists = false
+		return false
+	}
+	var jsonResult BucketsResult
+	if err := json.Unmarshal(body, &jsonResult); err != nil {
+		connector.log.Warn(err)
+	} else {
+		for _, bucket := range jsonResult.Buckets {
+			if bucket.Name == connector.bucket {
+				connector.bucketExists = true
+				return true
+			}
+		}
+	}
+	connector.bucketExists = false
+	return false
+}
+
+//CreateBucket creates the bucket, with an infinite retention, within the organization.
+func (connector *ConnectorV2) CreateBucket() bool {
+	orgID, err := connector.organizationID()
+	if err != nil {
+		connector.log.Warn("Could not create bucket:"+connector.bucket+" ", err)
+		return false
+	}
+	payload, _ := json.Marshal(map[string]interface{}{
+		"orgID":          orgID,
+		"name":           connector.bucket,
+		"retentionRules": []interface{}{},
+	})
+	req, err := http.NewRequest("POST", connector.connectionHost+"/api/v2/buckets", bytes.NewBuffer(payload))
+	if err != nil {
+		connector.log.Warn(err)
+		return false
+	}
+	req.Header.Set("User-Agent", "Nagflux")
+	req.Header.Set("Content-Type", "application/json")
+	connector.authorize(req)
+	resp, err := connector.httpClient.Do(req)
+	if err != nil {
+		connector.log.Warn(err)
+		return false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		connector.log.Warnf("Could not create bucket:%s %s - %s", connector.bucket, resp.Status, string(body))
+		return false
+	}
+	return true
+}
+
+//organizationID looks up the id of the configured organization.
+func (connector ConnectorV2) organizationID() (string, error) {
+	body, err := connector.get("/api/v2/orgs?org=" + url.QueryEscape(connector.organization))
+	if err != nil {
+		return "", err
+	}
+	var jsonResult OrgsResult
+	if err := json.Unmarshal(body, &jsonResult); err != nil {
+		return "", err
+	}
+	for _, org := range jsonResult.Orgs {
+		if org.Name == connector.organization {
+			return org.ID, nil
+		}
+	}
+	return "", fmt.Errorf("organization %s not found", connector.organization)
+}
+
+//get sends an authorized GET request to the given path and returns the body if the status code is 2XX.
+func (connector ConnectorV2) get(path string) ([]byte, error) {
+	req, err := http.NewRequest("GET", connector.connectionHost+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Nagflux")
+	connector.authorize(req)
+	resp, err := connector.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("InfluxDB status: %s - %s", resp.Status, string(body))
+	}
+	return body, nil
+}
@@ -0,0 +1,147 @@
+package influx
+
+import (
+	"encoding/json"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/statistics"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+//fakeInfluxDB2 is an InfluxDB 2.x with the organization acme, the bucket is missing till it gets created.
+type fakeInfluxDB2 struct {
+	t        *testing.T
+	mutex    sync.Mutex
+	requests []string
+	created  bool
+	written  chan string
+}
+
+func newFakeInfluxDB2(t *testing.T) (*fakeInfluxDB2, *httptest.Server) {
+	fake := &fakeInfluxDB2{t: t, written: make(chan string, 1)}
+	return fake, httptest.NewServer(fake)
+}
+
+func (f *fakeInfluxDB2) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	//ping doesn't need a token
+	if auth := r.Header.Get("Authorization"); auth != "Token secret" && r.URL.Path != "/ping" {
+		f.t.Errorf("%s %s: unexpected authorization: %s", r.Method, r.URL, auth)
+	}
+	switch r.Method + " " + r.URL.Path {
+	case "GET /ping":
+		w.WriteHeader(http.StatusNoContent)
+	case "GET /api/v2/orgs":
+		if r.URL.Query().Get("org") == "acme" {
+			w.Write([]byte(`{"orgs":[{"id":"0123","name":"acme"}]}`))
+		} else {
+			w.Write([]byte(`{"orgs":[]}`))
+		}
+	case "GET /api/v2/buckets":
+		if r.URL.Query().Get("org") != "acme" || r.URL.Query().Get("name") != "nag flux" {
+			f.t.Errorf("unexpected bucket lookup: %s", r.URL.RawQuery)
+		}
+		if f.created {
+			w.Write([]byte(`{"buckets":[{"id":"4567","name":"nag flux","orgID":"0123"}]}`))
+		} else {
+			w.Write([]byte(`{"buckets":[]}`))
+		}
+	case "POST /api/v2/buckets":
+		var bucket map[string]interface{}
+		raw, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(raw, &bucket); err != nil || bucket["orgID"] != "0123" || bucket["name"] != "nag flux" {
+			f.t.Errorf("unexpected bucket: %s", raw)
+		}
+		f.created = true
+		w.WriteHeader(http.StatusCreated)
+	case "POST /api/v2/write":
+		raw, _ := ioutil.ReadAll(r.Body)
+		f.written <- r.URL.RawQuery + " " + string(raw)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeInfluxDB2) sent(request string) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for _, r := range f.requests {
+		if r == request {
+			return true
+		}
+	}
+	return false
+}
+
+func newTestConnectorV2(host, organization string) *ConnectorV2 {
+	return &ConnectorV2{
+		connectionHost: host, organization: organization, bucket: "nag flux", token: "secret",
+		log: logging.GetLogger(), httpClient: http.Client{Timeout: time.Duration(5) * time.Second},
+		target: data.Target{Name: "v2", Datatype: data.InfluxDB2},
+	}
+}
+
+func TestConnectorV2WriteURL(t *testing.T) {
+	connector := newTestConnectorV2("http://127.0.0.1:8086", "acme & co")
+	expected := "http://127.0.0.1:8086/api/v2/write?org=acme+%26+co&bucket=nag+flux&precision=ms"
+	if actual := connector.writeURL(); actual != expected {
+		t.Errorf("Expected:%s\nResult:%s", expected, actual)
+	}
+}
+
+func TestConnectorV2CreatesBucketAndWrites(t *testing.T) {
+	logging.InitTestLogger()
+	statistics.NewPrometheusServer("")
+	fake, server := newFakeInfluxDB2(t)
+	defer server.Close()
+	connector := newTestConnectorV2(server.URL, "acme")
+
+	if !connector.TestIfIsAlive(false) {
+		t.Error("The server should be alive")
+	}
+	if connector.TestDatabaseExists() {
+		t.Error("The bucket should not exist yet")
+	}
+	if !connector.CreateBucket() || !fake.sent("GET /api/v2/orgs") || !fake.sent("POST /api/v2/buckets") {
+		t.Errorf("The bucket was not created: %v", fake.requests)
+	}
+	if !connector.TestDatabaseExists() {
+		t.Error("The bucket should exist after it got created")
+	}
+
+	jobs := make(chan collector.Printable)
+	worker := WorkerGenerator(jobs, connector.writeURL(), "", V2Version, connector, connector.target, false)(0)
+	jobs <- collector.SimplePrintable{Filterable: collector.AllFilterable, Datatype: data.InfluxDB2, Text: "metrics value=1.0 1000"}
+	worker.Stop()
+	select {
+	case written := <-fake.written:
+		if expected := "org=acme&bucket=nag+flux&precision=ms metrics value=1.0 1000\n"; written != expected {
+			t.Errorf("Expected:%q\nResult:%q", expected, written)
+		}
+	default:
+		t.Error("Nothing was written")
+	}
+}
+
+func TestConnectorV2UnknownOrganization(t *testing.T) {
+	logging.InitTestLogger()
+	fake, server := newFakeInfluxDB2(t)
+	defer server.Close()
+	connector := newTestConnectorV2(server.URL, "unknown")
+
+	if _, err := connector.organizationID(); err == nil || err.Error() != "organization unknown not found" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if connector.CreateBucket() || fake.sent("POST /api/v2/buckets") {
+		t.Error("No bucket should be created without the organization")
+	}
+}
@@ -0,0 +1,46 @@
+package influx
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/helper"
+	"strconv"
+	"strings"
+)
+
+var measurementEscaper = strings.NewReplacer(`,`, `\,`, ` `, `\ `)
+
+//EncodePrintable renders the points of the printable in the line protocol, one line per point.
+//A collector.SimplePrintable is taken as it is, if it was meant for InfluxDB.
+func EncodePrintable(printable collector.Printable, version string) []string {
+	if p, ok := printable.(collector.SimplePrintable); ok {
+		if (p.Datatype == data.InfluxDB || p.Datatype == data.InfluxDB2) && p.Text != "" {
+			return []string{p.Text}
+		}
+		return nil
+	}
+	var lines []string
+	for _, point := range printable.Points() {
+		if line := EncodePoint(point, version); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+//EncodePoint renders the point in the line protocol, tags without value are left out.
+//Points without fields can't be written and result in an empty string.
+func EncodePoint(point collector.Point, version string) string {
+	if len(point.Fields) == 0 {
+		return ""
+	}
+	point = point.WithHostcheckAlias(config.GetConfig().InfluxDBGlobal.HostcheckAlias)
+	line := measurementEscaper.Replace(point.Measurement)
+	for _, key := range point.TagKeys() {
+		if value := helper.SanitizeInfluxInput(point.Tags[key]); value != "" {
+			line += "," + helper.SanitizeInfluxInput(key) + "=" + value
+		}
+	}
+	return line + " " + helper.PrintFieldsForInfluxDB(point.Fields, version) + " " + strconv.FormatInt(point.Timestamp, 10)
+}
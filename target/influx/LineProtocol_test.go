@@ -0,0 +1,68 @@
+package influx
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/collector/spoolfile"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"reflect"
+	"testing"
+)
+
+func TestEncodePoint(t *testing.T) {
+	config.InitConfigFromString(`[InfluxDBGlobal]
+	HostcheckAlias = "hostcheck"`)
+	for _, d := range []struct {
+		point    collector.Point
+		expected string
+	}{
+		{collector.Point{
+			Kind: collector.MessageKind, Measurement: "messages", Timestamp: 1458988932000,
+			Tags:   map[string]string{"host": "host 1", "service": "service 1", "author": "philip", "type": "comment"},
+			Fields: data.Fields{"message": data.String(`hallo "world"`)},
+		}, `messages,author=philip,host=host\ 1,service=service\ 1,type=comment message="hallo \"world\"" 1458988932000`},
+		{collector.Point{
+			Kind: collector.MessageKind, Measurement: "messages", Timestamp: 0,
+			Tags:   map[string]string{"host": "host 1", "author": ""},
+			Fields: data.Fields{"message": data.String("WARN:<br> ")},
+		}, `messages,host=host\ 1,service=hostcheck message="WARN:<br> " 0`},
+		{collector.Point{
+			Kind: collector.CustomKind, Measurement: "my table", Timestamp: 10,
+			Tags:   map[string]string{"a,b": "c d"},
+			Fields: data.Fields{"value": data.Uint(1)},
+		}, `my\ table,a\,b=c\ d value=1i 10`},
+		{collector.Point{Kind: collector.CustomKind, Measurement: "empty", Timestamp: 10}, ""},
+	} {
+		if actual := EncodePoint(d.point, "1.0"); actual != d.expected {
+			t.Errorf("Expected:%s\nResult:%s", d.expected, actual)
+		}
+	}
+}
+
+func TestEncodePrintable(t *testing.T) {
+	config.InitConfigFromString(`[InfluxDBGlobal]
+	HostcheckAlias = "hostcheck"`)
+	perf := spoolfile.PerformanceData{
+		Filterable:       collector.AllFilterable,
+		Hostname:         "host1",
+		Command:          "check_disk",
+		PerformanceLabel: "'C: used'",
+		Unit:             "%",
+		Time:             "1441791000000",
+		Tags:             map[string]string{"site": "berlin"},
+		Fields:           data.Fields{"value": data.Float(44), "unknown": data.Bool(false)},
+	}
+	expected := []string{`metrics,command=check_disk,host=host1,performanceLabel=C:\ used,service=hostcheck,site=berlin,unit=% unknown=false,value=44.0 1441791000000`}
+	if actual := EncodePrintable(perf, "1.0"); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected:%v\nResult:%v", expected, actual)
+	}
+
+	raw := collector.SimplePrintable{Text: "line", Datatype: data.InfluxDB2}
+	if actual := EncodePrintable(raw, "2.0"); !reflect.DeepEqual(actual, []string{"line"}) {
+		t.Errorf("The text should be passed as it is: %v", actual)
+	}
+	raw.Datatype = data.Elasticsearch
+	if actual := EncodePrintable(raw, "2.0"); len(actual) != 0 {
+		t.Errorf("The text of other targets should be ignored: %v", actual)
+	}
+}
@@ -0,0 +1,301 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+const clientID = "nagflux"
+
+var errorNoBrokerReachable = errors.New("No Kafka broker reachable")
+var errorUnknownTopic = errors.New("Topic is unknown and could not be created")
+
+//Error codes which won't get better by retrying, everything else is worth a retry.
+var nonRetriableErrors = map[int16]string{
+	2:  "CORRUPT_MESSAGE",
+	10: "MESSAGE_TOO_LARGE",
+	17: "INVALID_TOPIC_EXCEPTION",
+	18: "RECORD_LIST_TOO_LARGE",
+	29: "TOPIC_AUTHORIZATION_FAILED",
+	87: "INVALID_RECORD",
+}
+
+//ProduceError contains the messages which were not accepted by the brokers.
+type ProduceError struct {
+	//Retriable messages failed due to network errors or leader changes.
+	Retriable []Message
+	//Rejected messages got an error which won't change by sending them again.
+	Rejected []Message
+	Reason   error
+}
+
+func (e *ProduceError) Error() string {
+	return fmt.Sprintf("%d messages failed, %d rejected: %s", len(e.Retriable), len(e.Rejected), e.Reason)
+}
+
+//Client is a minimal Kafka producer, it is not safe for concurrent use.
+type Client struct {
+	bootstrap     []string
+	requiredAcks  int16
+	timeout       time.Duration
+	correlationID int32
+	addresses     map[int32]string
+	leaders       map[string][]int32
+	conns         map[int32]net.Conn
+	roundRobin    int
+}
+
+//NewClient creates a client, connections are established on demand.
+func NewClient(bootstrap []string, requiredAcks int16, timeout time.Duration) *Client {
+	return &Client{
+		bootstrap: bootstrap, requiredAcks: requiredAcks, timeout: timeout,
+		addresses: map[int32]string{}, leaders: map[string][]int32{}, conns: map[int32]net.Conn{},
+	}
+}
+
+//Close closes all broker connections.
+func (c *Client) Close() {
+	for id, conn := range c.conns {
+		conn.Close()
+		delete(c.conns, id)
+	}
+}
+
+//RefreshMetadata asks one of the bootstrap brokers for the leaders of the given topics.
+func (c *Client) RefreshMetadata(topics []string) error {
+	var body encoder
+	body.putInt32(int32(len(topics)))
+	for _, topic := range topics {
+		body.putString(topic)
+	}
+	body.putInt8(1) //allow auto topic creation
+
+	lastErr := errorNoBrokerReachable
+	for _, address := range c.bootstrap {
+		conn, err := net.DialTimeout("tcp", address, c.timeout)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		response, err := c.roundTrip(conn, apiKeyMetadata, metadataVersion, body.Bytes(), true)
+		conn.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return c.parseMetadata(response)
+	}
+	return lastErr
+}
+
+func (c *Client) parseMetadata(response []byte) error {
+	d := decoder{buf: response}
+	d.int32() //throttle time
+	for i := d.arrayLength(); i > 0; i-- {
+		nodeID := d.int32()
+		host := d.string()
+		port := d.int32()
+		d.string() //rack
+		c.addresses[nodeID] = net.JoinHostPort(host, fmt.Sprint(port))
+	}
+	d.string() //cluster id
+	d.int32()  //controller id
+	for i := d.arrayLength(); i > 0; i-- {
+		topicError := d.int16()
+		topic := d.string()
+		d.int8() //is internal
+		var leaders []int32
+		for j := d.arrayLength(); j > 0; j-- {
+			d.int16() //partition error
+			partition := d.int32()
+			leader := d.int32()
+			for k := d.arrayLength(); k > 0; k-- {
+				d.int32() //replicas
+			}
+			for k := d.arrayLength(); k > 0; k-- {
+				d.int32() //isr
+			}
+			if partition >= 0 && int(partition) < 1<<16 {
+				for len(leaders) <= int(partition) {
+					leaders = append(leaders, -1)
+				}
+				leaders[partition] = leader
+			}
+		}
+		if topicError == 0 && len(leaders) > 0 {
+			c.leaders[topic] = leaders
+		} else {
+			delete(c.leaders, topic)
+		}
+	}
+	return d.err
+}
+
+//Produce sends the messages to the leaders of their partitions, a *ProduceError lists the messages which failed.
+func (c *Client) Produce(messages []Message) error {
+	var missing []string
+	for _, m := range messages {
+		if _, ok := c.leaders[m.Topic]; !ok {
+			missing = append(missing, m.Topic)
+		}
+	}
+	if len(missing) > 0 {
+		if err := c.RefreshMetadata(missing); err != nil {
+			return &ProduceError{Retriable: messages, Reason: err}
+		}
+	}
+
+	//leader -> topic -> partition -> messages
+	batches := map[int32]map[string]map[int32][]Message{}
+	result := &ProduceError{}
+	for _, m := range messages {
+		leaders, ok := c.leaders[m.Topic]
+		if !ok {
+			result.Retriable = append(result.Retriable, m)
+			result.Reason = errorUnknownTopic
+			continue
+		}
+		partition := c.partition(m.Key, len(leaders))
+		leader := leaders[partition]
+		if batches[leader] == nil {
+			batches[leader] = map[string]map[int32][]Message{}
+		}
+		if batches[leader][m.Topic] == nil {
+			batches[leader][m.Topic] = map[int32][]Message{}
+		}
+		batches[leader][m.Topic][partition] = append(batches[leader][m.Topic][partition], m)
+	}
+
+	for leader, topics := range batches {
+		if err := c.produceToBroker(leader, topics, result); err != nil {
+			c.closeConn(leader)
+			for _, partitions := range topics {
+				for _, partitionMessages := range partitions {
+					result.Retriable = append(result.Retriable, partitionMessages...)
+				}
+			}
+			result.Reason = err
+		}
+	}
+	if len(result.Retriable) > 0 {
+		//the leaders may have moved
+		c.leaders = map[string][]int32{}
+	}
+	if len(result.Retriable) > 0 || len(result.Rejected) > 0 {
+		return result
+	}
+	return nil
+}
+
+//Chooses the partition by key, messages without a key are spread round robin.
+func (c *Client) partition(key string, partitions int) int32 {
+	if key != "" {
+		return partitionForKey(key, partitions)
+	}
+	c.roundRobin = (c.roundRobin + 1) % partitions
+	return int32(c.roundRobin)
+}
+
+//Sends one produce request to a broker and sorts the failed partitions into the result.
+func (c *Client) produceToBroker(leader int32, topics map[string]map[int32][]Message, result *ProduceError) error {
+	var body encoder
+	body.putNullableString(nil) //transactional id
+	body.putInt16(c.requiredAcks)
+	body.putInt32(int32(c.timeout / time.Millisecond))
+	body.putInt32(int32(len(topics)))
+	for topic, partitions := range topics {
+		body.putString(topic)
+		body.putInt32(int32(len(partitions)))
+		for partition, partitionMessages := range partitions {
+			body.putInt32(partition)
+			body.putBytes(encodeRecordBatch(partitionMessages))
+		}
+	}
+
+	conn, err := c.conn(leader)
+	if err != nil {
+		return err
+	}
+	response, err := c.roundTrip(conn, apiKeyProduce, produceVersion, body.Bytes(), c.requiredAcks != 0)
+	if err != nil || c.requiredAcks == 0 {
+		return err
+	}
+
+	d := decoder{buf: response}
+	for i := d.arrayLength(); i > 0; i-- {
+		topic := d.string()
+		for j := d.arrayLength(); j > 0; j-- {
+			partition := d.int32()
+			errorCode := d.int16()
+			d.int64() //base offset
+			d.int64() //log append time
+			if errorCode == 0 || d.err != nil {
+				continue
+			}
+			failed := topics[topic][partition]
+			if name, ok := nonRetriableErrors[errorCode]; ok {
+				result.Rejected = append(result.Rejected, failed...)
+				result.Reason = fmt.Errorf("%s/%d: %s", topic, partition, name)
+			} else {
+				result.Retriable = append(result.Retriable, failed...)
+				result.Reason = fmt.Errorf("%s/%d: error code %d", topic, partition, errorCode)
+			}
+		}
+	}
+	return d.err
+}
+
+//Returns the cached connection to the broker or opens a new one.
+func (c *Client) conn(nodeID int32) (net.Conn, error) {
+	if conn, ok := c.conns[nodeID]; ok {
+		return conn, nil
+	}
+	address, ok := c.addresses[nodeID]
+	if !ok {
+		return nil, fmt.Errorf("Unknown Kafka broker: %d", nodeID)
+	}
+	conn, err := net.DialTimeout("tcp", address, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	c.conns[nodeID] = conn
+	return conn, nil
+}
+
+func (c *Client) closeConn(nodeID int32) {
+	if conn, ok := c.conns[nodeID]; ok {
+		conn.Close()
+		delete(c.conns, nodeID)
+	}
+}
+
+//Writes the request and reads the response body, without the response header.
+func (c *Client) roundTrip(conn net.Conn, apiKey, apiVersion int16, body []byte, expectResponse bool) ([]byte, error) {
+	c.correlationID++
+	conn.SetDeadline(time.Now().Add(c.timeout))
+	if _, err := conn.Write(encodeRequest(apiKey, apiVersion, c.correlationID, clientID, body)); err != nil {
+		return nil, err
+	}
+	if !expectResponse {
+		return nil, nil
+	}
+	var size int32
+	if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+		return nil, err
+	}
+	if size < 4 || size > 64<<20 {
+		return nil, errorMalformedResponse
+	}
+	response := make([]byte, size)
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return nil, err
+	}
+	if int32(binary.BigEndian.Uint32(response)) != c.correlationID {
+		return nil, errorMalformedResponse
+	}
+	return response[4:], nil
+}
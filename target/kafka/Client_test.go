@@ -0,0 +1,272 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type record struct {
+	partition int32
+	key       string
+	value     string
+}
+
+//fakeBroker is a single node stand-in which speaks Metadata v4 and Produce v3.
+type fakeBroker struct {
+	listener   net.Listener
+	partitions int
+	errorCodes map[string]int16
+	mutex      sync.Mutex
+	records    map[string][]record
+	t          *testing.T
+}
+
+func newFakeBroker(t *testing.T, partitions int) *fakeBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := &fakeBroker{listener: listener, partitions: partitions, errorCodes: map[string]int16{}, records: map[string][]record{}, t: t}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go broker.serve(conn)
+		}
+	}()
+	return broker
+}
+
+func (b *fakeBroker) address() string {
+	return b.listener.Addr().String()
+}
+
+func (b *fakeBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		var size int32
+		if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+			return
+		}
+		request := make([]byte, size)
+		if _, err := io.ReadFull(conn, request); err != nil {
+			return
+		}
+		d := decoder{buf: request}
+		apiKey, apiVersion, correlationID := d.int16(), d.int16(), d.int32()
+		d.string() //client id
+		var response encoder
+		response.putInt32(correlationID)
+		switch {
+		case apiKey == apiKeyMetadata && apiVersion == metadataVersion:
+			b.metadata(&d, &response)
+		case apiKey == apiKeyProduce && apiVersion == produceVersion:
+			b.produce(&d, &response)
+		default:
+			b.t.Errorf("Unexpected request: %d v%d", apiKey, apiVersion)
+			return
+		}
+		if d.err != nil {
+			b.t.Error(d.err)
+			return
+		}
+		var frame encoder
+		frame.putBytes(response.Bytes())
+		conn.Write(frame.Bytes())
+	}
+}
+
+func (b *fakeBroker) metadata(d *decoder, response *encoder) {
+	var topics []string
+	for i := d.arrayLength(); i > 0; i-- {
+		topics = append(topics, d.string())
+	}
+	d.int8()
+	host, port, _ := net.SplitHostPort(b.address())
+	portNumber, _ := strconv.Atoi(port)
+	response.putInt32(0)
+	response.putInt32(1)
+	response.putInt32(0)
+	response.putString(host)
+	response.putInt32(int32(portNumber))
+	response.putNullableString(nil)
+	response.putNullableString(nil)
+	response.putInt32(0)
+	response.putInt32(int32(len(topics)))
+	for _, topic := range topics {
+		response.putInt16(0)
+		response.putString(topic)
+		response.putInt8(0)
+		response.putInt32(int32(b.partitions))
+		for p := 0; p < b.partitions; p++ {
+			response.putInt16(0)
+			response.putInt32(int32(p))
+			response.putInt32(0)
+			response.putInt32(1)
+			response.putInt32(0)
+			response.putInt32(1)
+			response.putInt32(0)
+		}
+	}
+}
+
+func (b *fakeBroker) produce(d *decoder, response *encoder) {
+	d.int16() //transactional id
+	d.int16() //acks
+	d.int32() //timeout
+	topics := d.arrayLength()
+	response.putInt32(int32(topics))
+	for ; topics > 0; topics-- {
+		topic := d.string()
+		response.putString(topic)
+		partitions := d.arrayLength()
+		response.putInt32(int32(partitions))
+		for ; partitions > 0; partitions-- {
+			partition := d.int32()
+			b.decodeRecordBatch(topic, partition, d.bytes())
+			response.putInt32(partition)
+			response.putInt16(b.errorCodes[topic])
+			response.putInt64(0)
+			response.putInt64(-1)
+		}
+	}
+	response.putInt32(0)
+}
+
+func (b *fakeBroker) decodeRecordBatch(topic string, partition int32, batch []byte) {
+	d := decoder{buf: batch}
+	d.int64()
+	if length := int(d.int32()); length != len(batch)-12 {
+		b.t.Errorf("Batch length: expected:%d, actual:%d", len(batch)-12, length)
+	}
+	d.int32()
+	if magic := d.int8(); magic != recordBatchV2 {
+		b.t.Errorf("Unexpected magic: %d", magic)
+	}
+	crc := uint32(d.int32())
+	if actual := crc32.Checksum(batch[d.off:], crc32c); actual != crc {
+		b.t.Errorf("CRC mismatch: expected:%d, actual:%d", crc, actual)
+	}
+	d.int16()
+	d.int32()
+	d.int64()
+	d.int64()
+	d.int64()
+	d.int16()
+	d.int32()
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for i := d.int32(); i > 0; i-- {
+		d.varint() //length
+		d.int8()
+		d.varint()
+		d.varint()
+		key := d.varBytes()
+		value := d.varBytes()
+		d.varint()
+		b.records[topic] = append(b.records[topic], record{partition: partition, key: string(key), value: string(value)})
+	}
+	if d.err != nil {
+		b.t.Error(d.err)
+	}
+}
+
+func (b *fakeBroker) received(topic string) []record {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return append([]record(nil), b.records[topic]...)
+}
+
+func TestMurmur2(t *testing.T) {
+	t.Parallel()
+	//values of the Java client
+	for input, expected := range map[string]int32{
+		"21": -973932308, "foobar": -790332482, "a-little-bit-long-string": -985981536,
+		"a-little-bit-longer-string": -1486304829, "abc": 479470107,
+	} {
+		if actual := murmur2([]byte(input)); actual != expected {
+			t.Errorf("murmur2(%s): expected:%d, actual:%d", input, expected, actual)
+		}
+	}
+}
+
+func TestClientProduce(t *testing.T) {
+	t.Parallel()
+	broker := newFakeBroker(t, 3)
+	defer broker.listener.Close()
+
+	client := NewClient([]string{"127.0.0.1:1", broker.address()}, 1, time.Duration(2)*time.Second)
+	defer client.Close()
+	messages := []Message{
+		{Topic: "perf", Key: "host1;load", Value: "a", Timestamp: 1000},
+		{Topic: "perf", Key: "host2;disk", Value: "b", Timestamp: 1001},
+		{Topic: "perf", Key: "host1;load", Value: "c", Timestamp: 1002},
+		{Topic: "msg", Key: "host1;load", Value: "d", Timestamp: 1003},
+	}
+	if err := client.Produce(messages); err != nil {
+		t.Fatal(err)
+	}
+
+	perf := broker.received("perf")
+	if len(perf) != 3 {
+		t.Fatalf("Expected 3 records, got: %v", perf)
+	}
+	partitions := map[string]int32{}
+	values := map[string]bool{}
+	for _, r := range perf {
+		if p, ok := partitions[r.key]; ok && p != r.partition {
+			t.Errorf("Key %s was sent to partition %d and %d", r.key, p, r.partition)
+		}
+		partitions[r.key] = r.partition
+		values[r.value] = true
+		if expected := partitionForKey(r.key, 3); r.partition != expected {
+			t.Errorf("Key %s: expected partition:%d, actual:%d", r.key, expected, r.partition)
+		}
+	}
+	if !values["a"] || !values["b"] || !values["c"] {
+		t.Errorf("Missing values: %v", perf)
+	}
+	if msg := broker.received("msg"); len(msg) != 1 || msg[0].value != "d" || msg[0].partition != partitions["host1;load"] {
+		t.Errorf("Unexpected messages: %v", msg)
+	}
+}
+
+func TestClientProduceErrors(t *testing.T) {
+	t.Parallel()
+	broker := newFakeBroker(t, 1)
+	defer broker.listener.Close()
+	broker.errorCodes["big"] = 10
+	broker.errorCodes["moving"] = 6
+
+	client := NewClient([]string{broker.address()}, -1, time.Duration(2)*time.Second)
+	defer client.Close()
+	err := client.Produce([]Message{
+		{Topic: "big", Value: "a"},
+		{Topic: "moving", Value: "b"},
+		{Topic: "fine", Value: "c"},
+	})
+	produceErr, ok := err.(*ProduceError)
+	if !ok {
+		t.Fatalf("Expected a ProduceError, got: %v", err)
+	}
+	if len(produceErr.Rejected) != 1 || produceErr.Rejected[0].Value != "a" {
+		t.Errorf("Unexpected rejected messages: %v", produceErr.Rejected)
+	}
+	if len(produceErr.Retriable) != 1 || produceErr.Retriable[0].Value != "b" {
+		t.Errorf("Unexpected retriable messages: %v", produceErr.Retriable)
+	}
+
+	client = NewClient([]string{"127.0.0.1:1"}, 1, time.Duration(2)*time.Second)
+	err = client.Produce([]Message{{Topic: "fine", Value: "c"}})
+	if produceErr, ok := err.(*ProduceError); !ok || len(produceErr.Retriable) != 1 {
+		t.Errorf("Expected the message to be retriable, got: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package kafka
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/target"
+	"github.com/kdar/factorlog"
+	"strings"
+	"time"
+)
+
+//Connector makes the basic connection to a Kafka cluster.
+type Connector struct {
+	brokers      []string
+	topics       Topics
+	format       string
+	requiredAcks int16
+	dumpFile     string
+	*target.Workers
+	jobs                  chan collector.Printable
+	log                   *factorlog.FactorLog
+	isAlive               bool
+	timeout               time.Duration
+	target                data.Target
+	stopReadingDataIfDown bool
+}
+
+//ConnectorFactory Constructor which will create some workers if the connection is established.
+//The brokers are a comma separated list of host:port which are used to bootstrap the cluster metadata.
+func ConnectorFactory(jobs chan collector.Printable, brokers string, topics Topics, format string, requiredAcks int,
+	dumpFile string, workerAmount, maxWorkers int, stopReadingDataIfDown bool, target data.Target, clientTimeout int) *Connector {
+	s := &Connector{
+		brokers: splitBrokers(brokers), topics: topics, format: format, requiredAcks: int16(requiredAcks),
+		dumpFile: dumpFile, jobs: jobs, log: logging.GetLogger(), isAlive: false,
+		timeout: time.Duration(clientTimeout) * time.Second, target: target, stopReadingDataIfDown: stopReadingDataIfDown,
+	}
+	if s.format != FormatInflux && s.format != FormatJSON {
+		s.log.Warnf("Kafka(%s) format '%s' is unknown, options are: %s, %s. Using %s", target.Name, format, FormatInflux, FormatJSON, FormatInflux)
+		s.format = FormatInflux
+	}
+	if s.requiredAcks != 1 && s.requiredAcks != -1 {
+		s.log.Warnf("Kafka(%s) RequiredAcks %d is not supported, options are: 1 (leader), -1 (all replicas). Using 1", target.Name, requiredAcks)
+		s.requiredAcks = 1
+	}
+
+	gen := WorkerGenerator(jobs, s, dumpFile, target, stopReadingDataIfDown)
+	s.TestIfIsAlive(stopReadingDataIfDown)
+	if !s.isAlive && !stopReadingDataIfDown {
+		s.log.Warnf("Kafka(%s) is down but starting anyway due to 'stopReadingDataIfDown' = %t", target.Name, stopReadingDataIfDown)
+	} else {
+		if !s.isAlive {
+			s.log.Info("Waiting for Kafka(" + target.Name + ")")
+		}
+		for !s.isAlive {
+			s.TestIfIsAlive(stopReadingDataIfDown)
+			time.Sleep(time.Duration(5) * time.Second)
+			s.log.Debugln("Waiting for Kafka (" + target.Name + ")")
+		}
+		s.log.Debug("Kafka(" + target.Name + ") is running")
+	}
+
+	s.Workers = startWorkers(workerAmount, maxWorkers, gen)
+	return s
+}
+
+func splitBrokers(brokers string) []string {
+	var result []string
+	for _, broker := range strings.Split(brokers, ",") {
+		if broker = strings.TrimSpace(broker); broker != "" {
+			result = append(result, broker)
+		}
+	}
+	return result
+}
+
+//IsAlive is the Kafka cluster reachable.
+func (connector Connector) IsAlive() bool {
+	return connector.isAlive
+}
+
+//Encode converts the printable into entries of the write-ahead log.
+func (connector Connector) Encode(printable collector.Printable) []string {
+	if p, ok := printable.(collector.SimplePrintable); ok {
+		if p.Datatype == data.Kafka {
+			return []string{p.Text}
+		}
+		return nil
+	}
+	return messagesToLines(PrintableToMessages(printable, connector.topics, connector.format))
+}
+
+//Stop the connector and its workers.
+func (connector *Connector) Stop() {
+	connector.StopWorkers()
+	connector.log.Debug("KafkaConnectorFactory stopped")
+}
+
+//TestIfIsAlive test active if one of the brokers answers the metadata request for the topics.
+func (connector *Connector) TestIfIsAlive(stopReadingDataIfDown bool) bool {
+	client := connector.newClient()
+	err := client.RefreshMetadata([]string{connector.topics.Perfdata, connector.topics.Messages})
+	result := err == nil
+	if !result {
+		connector.log.Debug(err)
+	}
+	connector.isAlive = result
+	connector.log.Infof("Is Kafka(%s) running: %t", connector.target.Name, result)
+	if stopReadingDataIfDown {
+		config.StoreValue(connector.target, !result)
+	}
+	return result
+}
+
+//newClient creates a client with the settings of this connector, every worker has its own.
+func (connector Connector) newClient() *Client {
+	return NewClient(connector.brokers, connector.requiredAcks, connector.timeout)
+}
@@ -0,0 +1,84 @@
+package kafka
+
+import (
+	"encoding/json"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/target/influx"
+)
+
+const (
+	//FormatInflux sends the Influx line protocol as payload.
+	FormatInflux = "influx"
+	//FormatJSON sends a JSON object as payload.
+	FormatJSON = "json"
+	//DefaultPerfdataTopic is used if no topic for the perfdata is configured.
+	DefaultPerfdataTopic = "nagflux-perfdata"
+	//DefaultMessagesTopic is used if no topic for notifications, comments and downtimes is configured.
+	DefaultMessagesTopic = "nagflux-messages"
+)
+
+//The line protocol of InfluxDB 1.x, unsigned integers are written as integers.
+const influxVersion = "1.0"
+
+//Message is a single record which will be sent to a topic.
+type Message struct {
+	Topic     string `json:"topic"`
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+//Topics contains the topic per kind of data.
+type Topics struct {
+	Perfdata string
+	Messages string
+}
+
+//PrintableToMessages converts every point of the printable to a message, the topic depends on the kind of the point,
+//the key on the host and service. Points which produce an empty payload are skipped.
+func PrintableToMessages(printable collector.Printable, topics Topics, format string) []Message {
+	var messages []Message
+	for _, point := range printable.Points() {
+		if message, ok := PointToMessage(point, topics, format); ok {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+//PointToMessage converts the point to a message with the timestamp of the point in ms, the second return value is false if
+//the payload is empty.
+func PointToMessage(point collector.Point, topics Topics, format string) (Message, bool) {
+	point = point.WithHostcheckAlias(config.GetConfig().InfluxDBGlobal.HostcheckAlias)
+	topic, key := topics.Perfdata, genKey(point.Tags["host"], point.Tags["service"])
+	if point.Kind == collector.MessageKind {
+		topic = topics.Messages
+	}
+	if point.Tags["host"] == "" {
+		key = point.Measurement
+	}
+
+	var value string
+	if format == FormatJSON {
+		raw, err := json.Marshal(point)
+		if err != nil {
+			return Message{}, false
+		}
+		value = string(raw)
+	} else {
+		value = influx.EncodePoint(point, influxVersion)
+	}
+	if value == "" {
+		return Message{}, false
+	}
+	return Message{Topic: topic, Key: key, Value: value, Timestamp: point.Timestamp}, true
+}
+
+//Generates the key, which keeps the data of one service on one partition.
+func genKey(host, service string) string {
+	if service == "" {
+		service = config.GetConfig().InfluxDBGlobal.HostcheckAlias
+	}
+	return host + ";" + service
+}
@@ -0,0 +1,58 @@
+package kafka
+
+import (
+	"encoding/json"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/collector/spoolfile"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"testing"
+)
+
+func TestPrintableToMessages(t *testing.T) {
+	config.InitConfigFromString(`[InfluxDBGlobal]
+	HostcheckAlias = "hostcheck"`)
+	topics := Topics{Perfdata: "perf", Messages: "msg"}
+	perf := spoolfile.PerformanceData{
+		Filterable:       collector.AllFilterable,
+		Hostname:         "host1",
+		Command:          "check_load",
+		PerformanceLabel: "load1",
+		Time:             "1441791000123",
+		Fields:           data.Fields{"value": data.Float(0.5)},
+	}
+
+	messages := PrintableToMessages(perf, topics, FormatInflux)
+	expected := "metrics,command=check_load,host=host1,performanceLabel=load1,service=hostcheck value=0.5 1441791000123"
+	if len(messages) != 1 || messages[0].Topic != "perf" || messages[0].Key != "host1;hostcheck" || messages[0].Value != expected {
+		t.Errorf("Unexpected influx messages: %v", messages)
+	}
+
+	messages = PrintableToMessages(perf, topics, FormatJSON)
+	var decoded collector.Point
+	if len(messages) != 1 || json.Unmarshal([]byte(messages[0].Value), &decoded) != nil || decoded.Tags["host"] != "host1" || decoded.Fields["value"] != data.Float(0.5) {
+		t.Errorf("Unexpected json messages: %v", messages)
+	}
+
+	if messages := PrintableToMessages(collector.SimplePrintable{Text: "x", Datatype: data.InfluxDB}, topics, FormatInflux); len(messages) != 0 {
+		t.Error("SimplePrintables should not be converted")
+	}
+}
+
+func TestPointToMessage(t *testing.T) {
+	config.InitConfigFromString(`[InfluxDBGlobal]
+	HostcheckAlias = "hostcheck"`)
+	topics := Topics{Perfdata: "perf", Messages: "msg"}
+	point := collector.Point{
+		Kind: collector.MessageKind, Measurement: "messages", Timestamp: 1000,
+		Tags: map[string]string{"host": "host1", "service": "ping"}, Fields: data.Fields{"message": data.String("down")},
+	}
+	if message, ok := PointToMessage(point, topics, FormatInflux); !ok || message.Topic != "msg" || message.Key != "host1;ping" || message.Timestamp != 1000 {
+		t.Errorf("Unexpected message: %v", message)
+	}
+
+	custom := collector.Point{Kind: collector.CustomKind, Measurement: "table", Fields: data.Fields{"value": data.Int(1)}}
+	if message, ok := PointToMessage(custom, topics, FormatInflux); !ok || message.Topic != "perf" || message.Key != "table" || message.Value != "table value=1i 0" {
+		t.Errorf("Unexpected message: %v", message)
+	}
+}
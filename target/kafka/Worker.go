@@ -0,0 +1,133 @@
+package kafka
+
+import (
+	"encoding/json"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/target"
+	"time"
+)
+
+//Worker sends the batches of its BatchWorker to Kafka.
+type Worker struct {
+	*target.BatchWorker
+	connector             *Connector
+	client                *Client
+	stopReadingDataIfDown bool
+}
+
+//WorkerGenerator generates a new Worker and starts it.
+func WorkerGenerator(jobs chan collector.Printable, connector *Connector, dumpFile string,
+	t data.Target, stopReadingDataIfDown bool) func(workerId int) *Worker {
+	return func(workerId int) *Worker {
+		worker := &Worker{connector: connector, client: connector.newClient(), stopReadingDataIfDown: stopReadingDataIfDown}
+		worker.BatchWorker = target.NewBatchWorker(workerId, jobs, t, dumpFile, worker)
+		worker.Start()
+		return worker
+	}
+}
+
+//Starts the workers of a connector.
+func startWorkers(amount, maxWorkers int, gen func(workerId int) *Worker) *target.Workers {
+	return target.NewWorkers(amount, maxWorkers, func(workerID int) target.Worker {
+		return gen(workerID)
+	})
+}
+
+//IsReady tests Kafka again if it was down, unless the data is read anyway.
+func (worker *Worker) IsReady() bool {
+	if worker.stopReadingDataIfDown && !worker.connector.IsAlive() {
+		worker.connector.TestIfIsAlive(worker.stopReadingDataIfDown)
+		return false
+	}
+	return true
+}
+
+//Encode converts the printable into JSON lines of messages.
+func (worker *Worker) Encode(printable collector.Printable) []string {
+	return worker.connector.Encode(printable)
+}
+
+//Close closes the client when the worker stops.
+func (worker *Worker) Close() {
+	worker.client.Close()
+}
+
+//Send produces the given printables as messages, rejected messages are dumped to the error file, the others are retried.
+func (worker *Worker) Send(printables []collector.Printable) {
+	var messages []Message
+	for _, printable := range printables {
+		messages = append(messages, worker.castJobToMessages(printable)...)
+	}
+	if len(messages) == 0 {
+		return
+	}
+
+	startTime := time.Now()
+	pending := messages
+	for i := 0; ; i++ {
+		err := worker.client.Produce(pending)
+		if err == nil {
+			break
+		}
+		worker.Log.Warn(err)
+		produceErr, ok := err.(*ProduceError)
+		if !ok {
+			produceErr = &ProduceError{Retriable: pending, Reason: err}
+		}
+		if len(produceErr.Rejected) > 0 {
+			worker.DumpErrors("\n\nSome of the messages got rejected..\n", messagesToLines(produceErr.Rejected))
+		}
+		pending = produceErr.Retriable
+		if len(pending) == 0 {
+			break
+		}
+		if i == 0 {
+			worker.connector.TestIfIsAlive(worker.stopReadingDataIfDown)
+		}
+		if i == 2 {
+			//if there is still an error spill the messages and go on
+			worker.Log.Info("Spilling messages which couldn't be sent to the write-ahead log")
+			worker.Spill(messagesToLines(pending))
+			return
+		}
+		if err := worker.WaitForQuitOrGoOn(); err != nil {
+			//No error handling, because it's time to terminate
+			worker.Spill(messagesToLines(pending))
+			worker.DumpRemaining(nil)
+			return
+		}
+	}
+
+	bytesSend := 0
+	for _, message := range messages {
+		bytesSend += len(message.Key) + len(message.Value)
+	}
+	worker.Sent("Kafka", bytesSend, startTime)
+}
+
+//messagesToLines converts the messages to JSON lines.
+func messagesToLines(messages []Message) []string {
+	lines := make([]string, 0, len(messages))
+	for _, message := range messages {
+		line, _ := json.Marshal(message)
+		lines = append(lines, string(line)+"\n")
+	}
+	return lines
+}
+
+//Converts an collector.Printable to messages, dumped messages are replayed as they are.
+func (worker Worker) castJobToMessages(job collector.Printable) []Message {
+	if printable, ok := job.(collector.SimplePrintable); ok {
+		if printable.Datatype != data.Kafka || printable.Text == "" {
+			return nil
+		}
+		var message Message
+		if err := json.Unmarshal([]byte(printable.Text), &message); err != nil {
+			worker.Log.Warn("Could not parse dumped message: ", err)
+			return nil
+		}
+		return []Message{message}
+	}
+	return PrintableToMessages(job, worker.connector.topics, worker.connector.format)
+}
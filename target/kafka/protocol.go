@@ -0,0 +1,273 @@
+package kafka
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+//The subset of the Kafka protocol which is needed to produce: Metadata v4 and Produce v3 with RecordBatch v2.
+//Both are supported from Kafka 0.11 up to the current releases.
+const (
+	apiKeyProduce   int16 = 0
+	apiKeyMetadata  int16 = 3
+	produceVersion  int16 = 3
+	metadataVersion int16 = 4
+	recordBatchV2   int8  = 2
+)
+
+var errorMalformedResponse = errors.New("Malformed Kafka response")
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+//encoder writes the primitive types of the Kafka protocol, everything is big endian.
+type encoder struct {
+	bytes.Buffer
+}
+
+func (e *encoder) putInt8(v int8) {
+	e.WriteByte(byte(v))
+}
+
+func (e *encoder) putInt16(v int16) {
+	binary.Write(e, binary.BigEndian, v)
+}
+
+func (e *encoder) putInt32(v int32) {
+	binary.Write(e, binary.BigEndian, v)
+}
+
+func (e *encoder) putInt64(v int64) {
+	binary.Write(e, binary.BigEndian, v)
+}
+
+func (e *encoder) putString(v string) {
+	e.putInt16(int16(len(v)))
+	e.WriteString(v)
+}
+
+func (e *encoder) putNullableString(v *string) {
+	if v == nil {
+		e.putInt16(-1)
+		return
+	}
+	e.putString(*v)
+}
+
+func (e *encoder) putBytes(v []byte) {
+	e.putInt32(int32(len(v)))
+	e.Write(v)
+}
+
+//putVarint writes a zigzag encoded varint, like it is used within records.
+func (e *encoder) putVarint(v int64) {
+	buf := make([]byte, binary.MaxVarintLen64)
+	e.Write(buf[:binary.PutVarint(buf, v)])
+}
+
+//putVarBytes writes a varint length followed by the data, nil is written as -1.
+func (e *encoder) putVarBytes(v []byte) {
+	if v == nil {
+		e.putVarint(-1)
+		return
+	}
+	e.putVarint(int64(len(v)))
+	e.Write(v)
+}
+
+//decoder reads the primitive types of the Kafka protocol, the first error is kept and stops further reading.
+type decoder struct {
+	buf []byte
+	off int
+	err error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || d.off+n > len(d.buf) {
+		d.err = errorMalformedResponse
+		return nil
+	}
+	result := d.buf[d.off : d.off+n]
+	d.off += n
+	return result
+}
+
+func (d *decoder) int8() int8 {
+	if b := d.next(1); b != nil {
+		return int8(b[0])
+	}
+	return 0
+}
+
+func (d *decoder) int16() int16 {
+	if b := d.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *decoder) int32() int32 {
+	if b := d.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *decoder) int64() int64 {
+	if b := d.next(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (d *decoder) string() string {
+	length := d.int16()
+	if length < 0 {
+		return ""
+	}
+	return string(d.next(int(length)))
+}
+
+func (d *decoder) bytes() []byte {
+	length := d.int32()
+	if length < 0 {
+		return nil
+	}
+	return d.next(int(length))
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.buf[d.off:])
+	if n <= 0 {
+		d.err = errorMalformedResponse
+		return 0
+	}
+	d.off += n
+	return v
+}
+
+func (d *decoder) varBytes() []byte {
+	length := d.varint()
+	if length < 0 {
+		return nil
+	}
+	return d.next(int(length))
+}
+
+//arrayLength reads the length of an array and guards against lengths which can't fit into the buffer.
+func (d *decoder) arrayLength() int {
+	length := int(d.int32())
+	if length < 0 {
+		return 0
+	}
+	if length > len(d.buf)-d.off {
+		d.err = errorMalformedResponse
+		return 0
+	}
+	return length
+}
+
+//encodeRequest frames the body with the request header v1.
+func encodeRequest(apiKey, apiVersion int16, correlationID int32, clientID string, body []byte) []byte {
+	var e encoder
+	e.putInt32(int32(2 + 2 + 4 + 2 + len(clientID) + len(body)))
+	e.putInt16(apiKey)
+	e.putInt16(apiVersion)
+	e.putInt32(correlationID)
+	e.putString(clientID)
+	e.Write(body)
+	return e.Bytes()
+}
+
+//encodeRecordBatch creates an uncompressed RecordBatch v2 of the given messages.
+func encodeRecordBatch(messages []Message) []byte {
+	firstTimestamp := messages[0].Timestamp
+	maxTimestamp := firstTimestamp
+	for _, m := range messages {
+		if m.Timestamp > maxTimestamp {
+			maxTimestamp = m.Timestamp
+		}
+	}
+
+	var records encoder
+	for i, m := range messages {
+		var record encoder
+		record.putInt8(0) //attributes
+		record.putVarint(m.Timestamp - firstTimestamp)
+		record.putVarint(int64(i))
+		if m.Key == "" {
+			record.putVarBytes(nil)
+		} else {
+			record.putVarBytes([]byte(m.Key))
+		}
+		record.putVarBytes([]byte(m.Value))
+		record.putVarint(0) //headers
+		records.putVarint(int64(record.Len()))
+		records.Write(record.Bytes())
+	}
+
+	//everything which is covered by the crc
+	var crcPart encoder
+	crcPart.putInt16(0) //attributes, no compression
+	crcPart.putInt32(int32(len(messages) - 1))
+	crcPart.putInt64(firstTimestamp)
+	crcPart.putInt64(maxTimestamp)
+	crcPart.putInt64(-1) //producer id
+	crcPart.putInt16(-1) //producer epoch
+	crcPart.putInt32(-1) //base sequence
+	crcPart.putInt32(int32(len(messages)))
+	crcPart.Write(records.Bytes())
+
+	var batch encoder
+	batch.putInt64(0) //base offset
+	batch.putInt32(int32(4 + 1 + 4 + crcPart.Len()))
+	batch.putInt32(-1) //partition leader epoch
+	batch.putInt8(recordBatchV2)
+	binary.Write(&batch, binary.BigEndian, crc32.Checksum(crcPart.Bytes(), crc32c))
+	batch.Write(crcPart.Bytes())
+	return batch.Bytes()
+}
+
+//murmur2 is the hash of the Java client's default partitioner, so the same key lands on the same partition.
+func murmur2(data []byte) int32 {
+	length := len(data)
+	const m = uint32(0x5bd1e995)
+	const r = 24
+	h := uint32(0x9747b28c) ^ uint32(length)
+	for i := 0; i+4 <= length; i += 4 {
+		k := binary.LittleEndian.Uint32(data[i : i+4])
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+	tail := length &^ 3
+	switch length % 4 {
+	case 3:
+		h ^= uint32(data[tail+2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[tail+1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[tail])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return int32(h)
+}
+
+//partitionForKey returns the partition like the Java client would choose it.
+func partitionForKey(key string, partitions int) int32 {
+	return (murmur2([]byte(key)) & 0x7fffffff) % int32(partitions)
+}
@@ -0,0 +1,95 @@
+package opentsdb
+
+import (
+	"crypto/tls"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/helper"
+	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/target"
+	"github.com/kdar/factorlog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//Connector makes the basic connection to an OpenTSDB.
+type Connector struct {
+	connectionHost string
+	metricTemplate string
+	dumpFile       string
+	*target.Workers
+	jobs                  chan collector.Printable
+	log                   *factorlog.FactorLog
+	isAlive               bool
+	httpClient            http.Client
+	target                data.Target
+	stopReadingDataIfDown bool
+}
+
+//ConnectorFactory Constructor which will create some workers if the connection is established.
+func ConnectorFactory(jobs chan collector.Printable, connectionHost, metricTemplate, dumpFile string,
+	workerAmount, maxWorkers int, stopReadingDataIfDown bool, target data.Target, clientTimeout int) *Connector {
+	connectionHost = strings.TrimRight(connectionHost, "/")
+	timeout := time.Duration(time.Duration(clientTimeout) * time.Second)
+	transport := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	client := http.Client{Timeout: timeout, Transport: transport}
+	s := &Connector{
+		connectionHost: connectionHost, metricTemplate: metricTemplate, dumpFile: dumpFile,
+		jobs: jobs, log: logging.GetLogger(), isAlive: false, httpClient: client, target: target,
+		stopReadingDataIfDown: stopReadingDataIfDown,
+	}
+
+	gen := WorkerGenerator(jobs, connectionHost+"/api/put?details", dumpFile, s, target, stopReadingDataIfDown)
+	s.TestIfIsAlive(stopReadingDataIfDown)
+	if !s.isAlive && !stopReadingDataIfDown {
+		s.log.Warnf("OpenTSDB server(%s) is down but starting anyway due to 'stopReadingDataIfDown' = %t", target.Name, stopReadingDataIfDown)
+	} else {
+		if !s.isAlive {
+			s.log.Info("Waiting for OpenTSDB server(" + target.Name + ")")
+		}
+		for !s.isAlive {
+			s.TestIfIsAlive(stopReadingDataIfDown)
+			time.Sleep(time.Duration(5) * time.Second)
+			s.log.Debugln("Waiting for OpenTSDB server (" + target.Name + ")")
+		}
+		s.log.Debug("OpenTSDB(" + target.Name + ") is running")
+	}
+
+	s.Workers = startWorkers(workerAmount, maxWorkers, gen)
+	return s
+}
+
+//IsAlive is the database system alive.
+func (connector Connector) IsAlive() bool {
+	return connector.isAlive
+}
+
+//Encode converts the printable into entries of the write-ahead log, everything besides perfdata is ignored.
+func (connector Connector) Encode(printable collector.Printable) []string {
+	if p, ok := printable.(collector.SimplePrintable); ok {
+		if p.Datatype == data.OpenTSDB {
+			return []string{p.Text}
+		}
+		return nil
+	}
+	return datapointsToLines(PrintableToDatapoints(printable, connector.metricTemplate))
+}
+
+//Stop the connector and its workers.
+func (connector *Connector) Stop() {
+	connector.StopWorkers()
+	connector.log.Debug("OpenTSDBConnectorFactory stopped")
+}
+
+//TestIfIsAlive test active if the database system is alive.
+func (connector *Connector) TestIfIsAlive(stopReadingDataIfDown bool) bool {
+	result := helper.RequestedReturnCodeIsOK(connector.httpClient, connector.connectionHost+"/api/version", "GET")
+	connector.isAlive = result
+	connector.log.Infof("Is OpenTSDB(%s) running: %t", connector.target.Name, result)
+	if stopReadingDataIfDown {
+		config.StoreValue(connector.target, !result)
+	}
+	return result
+}
@@ -0,0 +1,79 @@
+package opentsdb
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/config"
+	"regexp"
+	"strings"
+)
+
+//DefaultMetricTemplate is used if no template is configured.
+const DefaultMetricTemplate = "nagflux.{field}"
+
+//Datapoint is a single entry of an /api/put request.
+type Datapoint struct {
+	Metric    string            `json:"metric"`
+	Timestamp int64             `json:"timestamp"`
+	Value     float64           `json:"value"`
+	Tags      map[string]string `json:"tags"`
+}
+
+//PutResult is the response of /api/put?details
+type PutResult struct {
+	Success int `json:"success"`
+	Failed  int `json:"failed"`
+	Errors  []struct {
+		Datapoint Datapoint `json:"datapoint"`
+		Error     string    `json:"error"`
+	} `json:"errors"`
+}
+
+var invalidChars = regexp.MustCompile(`[^\pL\pN\-_\./]`)
+
+//Sanitize replaces every char which is not allowed within metric names, tag keys and values.
+func Sanitize(input string) string {
+	return invalidChars.ReplaceAllString(strings.Trim(input, `'`), "_")
+}
+
+//PointToDatapoints creates one datapoint per numeric field, the name is rendered with the template.
+//Just perfdata points are converted. Placeholders are {host}, {service}, {command}, {label}, {unit} and {field}.
+func PointToDatapoints(point collector.Point, metricTemplate string) []Datapoint {
+	if point.Kind != collector.PerfdataKind {
+		return nil
+	}
+	point = point.WithHostcheckAlias(config.GetConfig().InfluxDBGlobal.HostcheckAlias)
+	tags := map[string]string{}
+	for k, v := range point.Tags {
+		if key, value := Sanitize(k), Sanitize(v); key != "" && value != "" {
+			tags[key] = value
+		}
+	}
+
+	var result []Datapoint
+	for _, field := range point.FieldKeys() {
+		value, ok := point.Fields[field].Float64()
+		if !ok {
+			//e.g. unknown=true
+			continue
+		}
+		metric := strings.NewReplacer(
+			"{host}", tags["host"],
+			"{service}", tags["service"],
+			"{command}", tags["command"],
+			"{label}", tags["performanceLabel"],
+			"{unit}", tags["unit"],
+			"{field}", Sanitize(field),
+		).Replace(metricTemplate)
+		result = append(result, Datapoint{Metric: metric, Timestamp: point.Timestamp, Value: value, Tags: tags})
+	}
+	return result
+}
+
+//PrintableToDatapoints converts every point of the printable.
+func PrintableToDatapoints(printable collector.Printable, metricTemplate string) []Datapoint {
+	var result []Datapoint
+	for _, point := range printable.Points() {
+		result = append(result, PointToDatapoints(point, metricTemplate)...)
+	}
+	return result
+}
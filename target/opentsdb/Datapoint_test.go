@@ -0,0 +1,45 @@
+package opentsdb
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/collector/spoolfile"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"reflect"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	t.Parallel()
+	for input, expected := range map[string]string{"'C: used'": "C__used", "a/b.c-d_e": "a/b.c-d_e", "µs": "µs", "a=b": "a_b"} {
+		if actual := Sanitize(input); actual != expected {
+			t.Errorf("Sanitize(%s): expected:%s, actual:%s", input, expected, actual)
+		}
+	}
+}
+
+func TestPrintableToDatapoints(t *testing.T) {
+	config.InitConfigFromString(`[InfluxDBGlobal]
+	HostcheckAlias = "hostcheck"`)
+	perf := spoolfile.PerformanceData{
+		Filterable:       collector.AllFilterable,
+		Hostname:         "host1",
+		Command:          "check_load",
+		PerformanceLabel: "load1",
+		Time:             "1441791000123",
+		Tags:             map[string]string{"site": "berlin", "empty": ""},
+		Fields:           data.Fields{"value": data.Float(0.5), "unknown": data.Bool(true)},
+	}
+	expected := []Datapoint{{
+		Metric:    "nagios.check_load.value",
+		Timestamp: 1441791000123,
+		Value:     0.5,
+		Tags: map[string]string{
+			"host": "host1", "service": "hostcheck", "command": "check_load",
+			"performanceLabel": "load1", "site": "berlin",
+		},
+	}}
+	if actual := PrintableToDatapoints(perf, "nagios.{command}.{field}"); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected:%v\nResult:%v", expected, actual)
+	}
+}
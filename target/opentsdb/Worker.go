@@ -0,0 +1,200 @@
+package opentsdb
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/target"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+//Worker sends the batches of its BatchWorker to the OpenTSDB.
+type Worker struct {
+	*target.BatchWorker
+	connection            string
+	connector             *Connector
+	httpClient            http.Client
+	stopReadingDataIfDown bool
+}
+
+var errorBadRequest = errors.New("400 Bad Request")
+var errorHTTPClient = errors.New("Http Client got an error")
+var errorFailedToSend = errors.New("Could not send data")
+var error500 = errors.New("Error 500")
+
+//WorkerGenerator generates a new Worker and starts it.
+func WorkerGenerator(jobs chan collector.Printable, connection, dumpFile string,
+	connector *Connector, t data.Target, stopReadingDataIfDown bool) func(workerId int) *Worker {
+	return func(workerId int) *Worker {
+		timeout := connector.httpClient.Timeout
+		transport := &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+		}
+		client := http.Client{Timeout: timeout, Transport: transport}
+		worker := &Worker{
+			connection: connection, connector: connector, httpClient: client,
+			stopReadingDataIfDown: stopReadingDataIfDown,
+		}
+		worker.BatchWorker = target.NewBatchWorker(workerId, jobs, t, dumpFile, worker)
+		worker.Start()
+		return worker
+	}
+}
+
+//Starts the workers of a connector.
+func startWorkers(amount, maxWorkers int, gen func(workerId int) *Worker) *target.Workers {
+	return target.NewWorkers(amount, maxWorkers, func(workerID int) target.Worker {
+		return gen(workerID)
+	})
+}
+
+//IsReady tests the OpenTSDB again if it was down, unless the data is read anyway.
+func (worker Worker) IsReady() bool {
+	if worker.stopReadingDataIfDown && !worker.connector.IsAlive() {
+		worker.connector.TestIfIsAlive(worker.stopReadingDataIfDown)
+		return false
+	}
+	return true
+}
+
+//Encode converts the printable into JSON lines of datapoints.
+func (worker Worker) Encode(printable collector.Printable) []string {
+	return worker.connector.Encode(printable)
+}
+
+//Send sends the given printables as datapoints to the OpenTSDB.
+func (worker Worker) Send(printables []collector.Printable) {
+	var datapoints []Datapoint
+	for _, printable := range printables {
+		datapoints = append(datapoints, worker.castJobToDatapoints(printable)...)
+	}
+	if len(datapoints) == 0 {
+		return
+	}
+
+	startTime := time.Now()
+	dataToSend, _ := json.Marshal(datapoints)
+	result, sendErr := worker.sendData(dataToSend, true)
+	if sendErr != nil {
+		worker.connector.TestIfIsAlive(worker.stopReadingDataIfDown)
+		for i := 0; i < 2; i++ {
+			switch sendErr {
+			case errorBadRequest:
+				if result != nil && len(result.Errors) > 0 {
+					//The details name the rejected datapoints, the others are stored
+					var badDatapoints []string
+					for _, rejected := range result.Errors {
+						line, _ := json.Marshal(rejected.Datapoint)
+						badDatapoints = append(badDatapoints, rejected.Error+"\n", string(line)+"\n")
+					}
+					worker.DumpErrors("\n\nSome of the datapoints got rejected..\n", badDatapoints)
+				} else {
+					//No details, so send them one by one and find the bad one
+					var badDatapoints []string
+					for _, datapoint := range datapoints {
+						single, _ := json.Marshal(datapoint)
+						if _, queryErr := worker.sendData(single, false); queryErr != nil {
+							badDatapoints = append(badDatapoints, string(single)+"\n")
+						}
+					}
+					worker.DumpErrors("\n\nOne of the values is not clean..\n", badDatapoints)
+				}
+				sendErr = nil
+			case nil:
+				//Single point of exit
+				break
+			default:
+				if err := worker.WaitForQuitOrGoOn(); err != nil {
+					//No error handling, because it's time to terminate
+					worker.DumpRemaining(printables)
+					return
+				}
+				//Resend Data
+				result, sendErr = worker.sendData(dataToSend, false)
+			}
+		}
+		if sendErr != nil {
+			//if there is still an error spill the datapoints and go on
+			worker.Log.Info("Spilling datapoints which couldn't be sent to the write-ahead log")
+			worker.Spill(datapointsToLines(datapoints))
+			return
+		}
+	}
+	worker.Sent("OpenTSDB", len(dataToSend), startTime)
+}
+
+//sends the raw data to OpenTSDB and returns an err if given, for 400 the parsed details are returned as well.
+func (worker Worker) sendData(rawData []byte, log bool) (*PutResult, error) {
+	if log {
+		worker.Log.Debug("\n" + string(rawData))
+	}
+	req, err := http.NewRequest("POST", worker.connection, bytes.NewBuffer(rawData))
+	if err != nil {
+		worker.Log.Warn(err)
+		return nil, errorHTTPClient
+	}
+	req.Header.Set("User-Agent", "Nagflux")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := worker.httpClient.Do(req)
+	if err != nil {
+		worker.Log.Warn(err)
+		return nil, errorHTTPClient
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	worker.Log.Debug(resp.Status)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		//OK
+		return nil, nil
+	}
+	if log {
+		worker.Log.Warnf("OpenTSDB status: %s - %s", resp.Status, string(body))
+	}
+	if resp.StatusCode == 400 {
+		//Bad Request, with details the rejected datapoints are listed
+		var result PutResult
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, errorBadRequest
+		}
+		return &result, errorBadRequest
+	} else if resp.StatusCode >= 500 {
+		//Temporarily timeout
+		return nil, error500
+	}
+	//HTTP Error
+	return nil, errorFailedToSend
+}
+
+//datapointsToLines converts the datapoints to JSON lines.
+func datapointsToLines(datapoints []Datapoint) []string {
+	var lines []string
+	for _, datapoint := range datapoints {
+		if line, err := json.Marshal(datapoint); err == nil {
+			lines = append(lines, string(line)+"\n")
+		}
+	}
+	return lines
+}
+
+//Converts an collector.Printable to datapoints, everything besides perfdata and dumped datapoints is ignored.
+func (worker Worker) castJobToDatapoints(job collector.Printable) []Datapoint {
+	if printable, ok := job.(collector.SimplePrintable); ok {
+		if printable.Datatype != data.OpenTSDB || printable.Text == "" {
+			return nil
+		}
+		var datapoint Datapoint
+		if err := json.Unmarshal([]byte(printable.Text), &datapoint); err != nil {
+			worker.Log.Warn("Could not parse dumped datapoint: ", err)
+			return nil
+		}
+		return []Datapoint{datapoint}
+	}
+	return PrintableToDatapoints(job, worker.connector.metricTemplate)
+}
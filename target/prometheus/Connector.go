@@ -0,0 +1,98 @@
+package prometheus
+
+import (
+	"crypto/tls"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/target"
+	"github.com/kdar/factorlog"
+	"net/http"
+	"time"
+)
+
+//Connector makes the basic connection to a remote_write endpoint like Prometheus, VictoriaMetrics or Mimir.
+type Connector struct {
+	connectionHost string
+	metricPrefix   string
+	dumpFile       string
+	*target.Workers
+	jobs                  chan collector.Printable
+	log                   *factorlog.FactorLog
+	isAlive               bool
+	httpClient            http.Client
+	target                data.Target
+	stopReadingDataIfDown bool
+}
+
+//ConnectorFactory Constructor which will create some workers if the connection is established.
+func ConnectorFactory(jobs chan collector.Printable, connectionHost, metricPrefix, dumpFile string,
+	workerAmount, maxWorkers int, stopReadingDataIfDown bool, target data.Target, clientTimeout int) *Connector {
+	timeout := time.Duration(time.Duration(clientTimeout) * time.Second)
+	transport := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	client := http.Client{Timeout: timeout, Transport: transport}
+	s := &Connector{
+		connectionHost: connectionHost, metricPrefix: metricPrefix, dumpFile: dumpFile,
+		jobs: jobs, log: logging.GetLogger(), isAlive: false, httpClient: client, target: target,
+		stopReadingDataIfDown: stopReadingDataIfDown,
+	}
+
+	gen := WorkerGenerator(jobs, connectionHost, metricPrefix, dumpFile, s, target, stopReadingDataIfDown)
+	s.TestIfIsAlive(stopReadingDataIfDown)
+	if !s.isAlive && !stopReadingDataIfDown {
+		s.log.Warnf("PrometheusRemoteWrite(%s) is down but starting anyway due to 'stopReadingDataIfDown' = %t", target.Name, stopReadingDataIfDown)
+	} else {
+		if !s.isAlive {
+			s.log.Info("Waiting for PrometheusRemoteWrite(" + target.Name + ")")
+		}
+		for !s.isAlive {
+			s.TestIfIsAlive(stopReadingDataIfDown)
+			time.Sleep(time.Duration(5) * time.Second)
+			s.log.Debugln("Waiting for PrometheusRemoteWrite (" + target.Name + ")")
+		}
+		s.log.Debug("PrometheusRemoteWrite(" + target.Name + ") is running")
+	}
+
+	s.Workers = startWorkers(workerAmount, maxWorkers, gen)
+	return s
+}
+
+//IsAlive is the remote_write endpoint reachable.
+func (connector Connector) IsAlive() bool {
+	return connector.isAlive
+}
+
+//Encode converts the printable into entries of the write-ahead log, everything besides perfdata is ignored.
+func (connector Connector) Encode(printable collector.Printable) []string {
+	if p, ok := printable.(collector.SimplePrintable); ok {
+		if p.Datatype == data.PrometheusRemoteWrite {
+			return []string{p.Text}
+		}
+		return nil
+	}
+	return seriesToLines(PrintableToTimeSeries(printable, connector.metricPrefix))
+}
+
+//Stop the connector and its workers.
+func (connector *Connector) Stop() {
+	connector.StopWorkers()
+	connector.log.Debug("PrometheusConnectorFactory stopped")
+}
+
+//TestIfIsAlive test active if the endpoint is reachable. There is no common health endpoint,
+//so every answer which is not a server error counts as alive, a GET on the write endpoint answers mostly with 405.
+func (connector *Connector) TestIfIsAlive(stopReadingDataIfDown bool) bool {
+	result := false
+	resp, err := connector.httpClient.Get(connector.connectionHost)
+	if err == nil {
+		resp.Body.Close()
+		result = resp.StatusCode < 500
+	}
+	connector.isAlive = result
+	connector.log.Infof("Is PrometheusRemoteWrite(%s) running: %t", connector.target.Name, result)
+	if stopReadingDataIfDown {
+		config.StoreValue(connector.target, !result)
+	}
+	return result
+}
@@ -0,0 +1,69 @@
+package prometheus
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/config"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+//SanitizeName replaces every char which is not allowed in a metric or label name with an underscore.
+func SanitizeName(name string) string {
+	name = invalidNameChars.ReplaceAllString(name, "_")
+	if len(name) > 0 && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+//PointToTimeSeries creates one series per numeric field, the tags become labels. Just perfdata points are converted.
+func PointToTimeSeries(point collector.Point, metricPrefix string) []*TimeSeries {
+	if point.Kind != collector.PerfdataKind {
+		return nil
+	}
+	point = point.WithHostcheckAlias(config.GetConfig().InfluxDBGlobal.HostcheckAlias)
+	labels := map[string]string{}
+	for k, v := range point.Tags {
+		labels[SanitizeName(k)] = strings.Trim(v, `'`)
+	}
+
+	var result []*TimeSeries
+	for _, field := range point.FieldKeys() {
+		value, ok := point.Fields[field].Float64()
+		if !ok {
+			//e.g. unknown=true
+			continue
+		}
+		series := &TimeSeries{
+			Labels:  sortedLabels(labels, SanitizeName(metricPrefix+"_"+field)),
+			Samples: []*Sample{{Value: value, Timestamp: point.Timestamp}},
+		}
+		result = append(result, series)
+	}
+	return result
+}
+
+//PrintableToTimeSeries converts every point of the printable.
+func PrintableToTimeSeries(printable collector.Printable, metricPrefix string) []*TimeSeries {
+	var result []*TimeSeries
+	for _, point := range printable.Points() {
+		result = append(result, PointToTimeSeries(point, metricPrefix)...)
+	}
+	return result
+}
+
+//sortedLabels returns the labels plus the metric name ordered by name, like remote_write requires it.
+func sortedLabels(labels map[string]string, metricName string) []*Label {
+	result := []*Label{{Name: "__name__", Value: metricName}}
+	for name, value := range labels {
+		if value == "" {
+			continue
+		}
+		result = append(result, &Label{Name: name, Value: value})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
@@ -0,0 +1,71 @@
+package prometheus
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/collector/spoolfile"
+	"github.com/spitefulgrog/nagflux/config"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+	"reflect"
+	"testing"
+)
+
+func TestSanitizeName(t *testing.T) {
+	t.Parallel()
+	for input, expected := range map[string]string{"warn-min": "warn_min", "a b.c": "a_b_c", "1abc": "_1abc", "ok_1": "ok_1"} {
+		if actual := SanitizeName(input); actual != expected {
+			t.Errorf("SanitizeName(%s): expected:%s, actual:%s", input, expected, actual)
+		}
+	}
+}
+
+func TestPrintableToTimeSeries(t *testing.T) {
+	config.InitConfigFromString(`[InfluxDBGlobal]
+	HostcheckAlias = "hostcheck"`)
+	perf := spoolfile.PerformanceData{
+		Filterable:       collector.AllFilterable,
+		Hostname:         "host1",
+		Command:          "check_disk",
+		PerformanceLabel: "'C: used'",
+		Unit:             "%",
+		Time:             "1441791000000",
+		Tags:             map[string]string{"warn-fill": "none"},
+		Fields:           data.Fields{"value": data.Float(44.0), "warn": data.Float(89.0), "unknown": data.Bool(true)},
+	}
+	series := PrintableToTimeSeries(perf, "nagflux")
+	if len(series) != 2 {
+		t.Fatalf("Expected two series, got %d", len(series))
+	}
+	expectedLabels := []*Label{
+		{Name: "__name__", Value: "nagflux_value"},
+		{Name: "command", Value: "check_disk"},
+		{Name: "host", Value: "host1"},
+		{Name: "performanceLabel", Value: "C: used"},
+		{Name: "service", Value: "hostcheck"},
+		{Name: "unit", Value: "%"},
+		{Name: "warn_fill", Value: "none"},
+	}
+	if !reflect.DeepEqual(series[0].Labels, expectedLabels) {
+		t.Errorf("Labels do not match.\nExpected:%v\nResult:%v", expectedLabels, series[0].Labels)
+	}
+	if series[1].Labels[0].Value != "nagflux_warn" || series[1].Samples[0].Value != 89 || series[1].Samples[0].Timestamp != 1441791000000 {
+		t.Errorf("Second series does not match: %v", series[1])
+	}
+
+	compressed, err := encodeWriteRequest(series)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var request WriteRequest
+	if err := proto.Unmarshal(raw, &request); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(request.Timeseries, series) {
+		t.Errorf("Decoded request does not match.\nExpected:%v\nResult:%v", series, request.Timeseries)
+	}
+}
@@ -0,0 +1,206 @@
+package prometheus
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/target"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+//Worker sends the batches of its BatchWorker to the remote_write endpoint.
+type Worker struct {
+	*target.BatchWorker
+	connection            string
+	metricPrefix          string
+	connector             *Connector
+	httpClient            http.Client
+	stopReadingDataIfDown bool
+}
+
+var errorBadRequest = errors.New("400 Bad Request")
+var errorHTTPClient = errors.New("Http Client got an error")
+var errorFailedToSend = errors.New("Could not send data")
+var error500 = errors.New("Error 500")
+
+//WorkerGenerator generates a new Worker and starts it.
+func WorkerGenerator(jobs chan collector.Printable, connection, metricPrefix, dumpFile string,
+	connector *Connector, t data.Target, stopReadingDataIfDown bool) func(workerId int) *Worker {
+	return func(workerId int) *Worker {
+		timeout := connector.httpClient.Timeout
+		transport := &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+		}
+		client := http.Client{Timeout: timeout, Transport: transport}
+		worker := &Worker{
+			connection: connection, metricPrefix: metricPrefix, connector: connector, httpClient: client,
+			stopReadingDataIfDown: stopReadingDataIfDown,
+		}
+		worker.BatchWorker = target.NewBatchWorker(workerId, jobs, t, dumpFile, worker)
+		worker.Start()
+		return worker
+	}
+}
+
+//Starts the workers of a connector.
+func startWorkers(amount, maxWorkers int, gen func(workerId int) *Worker) *target.Workers {
+	return target.NewWorkers(amount, maxWorkers, func(workerID int) target.Worker {
+		return gen(workerID)
+	})
+}
+
+//IsReady tests the endpoint again if it was down, unless the data is read anyway.
+func (worker Worker) IsReady() bool {
+	if worker.stopReadingDataIfDown && !worker.connector.IsAlive() {
+		worker.connector.TestIfIsAlive(worker.stopReadingDataIfDown)
+		return false
+	}
+	return true
+}
+
+//Encode converts the printable into JSON lines of series.
+func (worker Worker) Encode(printable collector.Printable) []string {
+	return worker.connector.Encode(printable)
+}
+
+//Send sends the given printables as series to the remote_write endpoint.
+func (worker Worker) Send(printables []collector.Printable) {
+	var series []*TimeSeries
+	for _, printable := range printables {
+		series = append(series, worker.castJobToTimeSeries(printable)...)
+	}
+	if len(series) == 0 {
+		return
+	}
+
+	startTime := time.Now()
+	dataToSend, err := encodeWriteRequest(series)
+	if err != nil {
+		worker.Log.Warn(err)
+		worker.DumpErrors("\n\nCould not encode the series..\n", seriesToLines(series))
+		return
+	}
+	sendErr := worker.sendData(dataToSend, true)
+	if sendErr != nil {
+		worker.connector.TestIfIsAlive(worker.stopReadingDataIfDown)
+		for i := 0; i < 2; i++ {
+			switch sendErr {
+			case errorBadRequest:
+				//Maybe just a few series are wrong, so send them one by one and find the bad one
+				var badSeries []*TimeSeries
+				for _, single := range series {
+					singleData, _ := encodeWriteRequest([]*TimeSeries{single})
+					if queryErr := worker.sendData(singleData, false); queryErr != nil {
+						badSeries = append(badSeries, single)
+					}
+				}
+				worker.DumpErrors("\n\nOne of the series was rejected..\n", seriesToLines(badSeries))
+				sendErr = nil
+			case nil:
+				//Single point of exit
+				break
+			default:
+				if err := worker.WaitForQuitOrGoOn(); err != nil {
+					//No error handling, because it's time to terminate
+					worker.DumpRemaining(printables)
+					return
+				}
+				//Resend Data
+				sendErr = worker.sendData(dataToSend, false)
+			}
+		}
+		if sendErr != nil {
+			//if there is still an error spill the series and go on
+			worker.Log.Info("Spilling series which couldn't be sent to the write-ahead log")
+			worker.Spill(seriesToLines(series))
+			return
+		}
+	}
+	worker.Sent("PrometheusRemoteWrite", len(dataToSend), startTime)
+}
+
+//encodeWriteRequest returns the snappy compressed protobuf WriteRequest.
+func encodeWriteRequest(series []*TimeSeries) ([]byte, error) {
+	raw, err := proto.Marshal(&WriteRequest{Timeseries: series})
+	if err != nil {
+		return nil, err
+	}
+	return snappy.Encode(nil, raw), nil
+}
+
+//sends the raw data to the endpoint and returns an err if given.
+func (worker Worker) sendData(rawData []byte, log bool) error {
+	req, err := http.NewRequest("POST", worker.connection, bytes.NewBuffer(rawData))
+	if err != nil {
+		worker.Log.Warn(err)
+		return errorHTTPClient
+	}
+	req.Header.Set("User-Agent", "Nagflux")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	resp, err := worker.httpClient.Do(req)
+	if err != nil {
+		worker.Log.Warn(err)
+		return errorHTTPClient
+	}
+	defer resp.Body.Close()
+	worker.Log.Debug(resp.Status)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		//OK
+		return nil
+	}
+	if log {
+		worker.logHTTPResponse(resp)
+	}
+	if resp.StatusCode >= 500 || resp.StatusCode == 429 {
+		//Temporarily, retry
+		return error500
+	} else if resp.StatusCode >= 400 {
+		//The data got rejected
+		return errorBadRequest
+	}
+	return errorFailedToSend
+}
+
+//Logs a http response to warn.
+func (worker Worker) logHTTPResponse(resp *http.Response) {
+	body, _ := ioutil.ReadAll(resp.Body)
+	worker.Log.Warnf("PrometheusRemoteWrite status: %s - %s", resp.Status, string(body))
+}
+
+//seriesToLines converts the series to JSON lines.
+func seriesToLines(series []*TimeSeries) []string {
+	var lines []string
+	for _, s := range series {
+		if line, err := json.Marshal(s); err == nil {
+			lines = append(lines, string(line)+"\n")
+		}
+	}
+	return lines
+}
+
+//Converts an collector.Printable to time series, everything besides perfdata and dumped series is ignored.
+func (worker Worker) castJobToTimeSeries(job collector.Printable) []*TimeSeries {
+	if printable, ok := job.(collector.SimplePrintable); ok {
+		if printable.Datatype != data.PrometheusRemoteWrite || printable.Text == "" {
+			return nil
+		}
+		var series TimeSeries
+		if err := json.Unmarshal([]byte(printable.Text), &series); err != nil {
+			worker.Log.Warn("Could not parse dumped series: ", err)
+			return nil
+		}
+		return []*TimeSeries{&series}
+	}
+	return PrintableToTimeSeries(job, worker.metricPrefix)
+}
@@ -0,0 +1,66 @@
+package prometheus
+
+import (
+	"github.com/golang/protobuf/proto"
+)
+
+//The types in this file are the messages of the remote_write protocol (prompb/remote.proto and prompb/types.proto).
+
+//WriteRequest is the body of a remote_write request.
+type WriteRequest struct {
+	Timeseries []*TimeSeries `protobuf:"bytes,1,rep,name=timeseries" json:"timeseries,omitempty"`
+}
+
+//Reset implements proto.Message.
+func (m *WriteRequest) Reset() { *m = WriteRequest{} }
+
+//String implements proto.Message.
+func (m *WriteRequest) String() string { return proto.CompactTextString(m) }
+
+//ProtoMessage implements proto.Message.
+func (*WriteRequest) ProtoMessage() {}
+
+//TimeSeries is a labelset with its samples.
+type TimeSeries struct {
+	Labels  []*Label  `protobuf:"bytes,1,rep,name=labels" json:"labels,omitempty"`
+	Samples []*Sample `protobuf:"bytes,2,rep,name=samples" json:"samples,omitempty"`
+}
+
+//Reset implements proto.Message.
+func (m *TimeSeries) Reset() { *m = TimeSeries{} }
+
+//String implements proto.Message.
+func (m *TimeSeries) String() string { return proto.CompactTextString(m) }
+
+//ProtoMessage implements proto.Message.
+func (*TimeSeries) ProtoMessage() {}
+
+//Label is a name value pair.
+type Label struct {
+	Name  string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+//Reset implements proto.Message.
+func (m *Label) Reset() { *m = Label{} }
+
+//String implements proto.Message.
+func (m *Label) String() string { return proto.CompactTextString(m) }
+
+//ProtoMessage implements proto.Message.
+func (*Label) ProtoMessage() {}
+
+//Sample is a value with its timestamp in ms.
+type Sample struct {
+	Value     float64 `protobuf:"fixed64,1,opt,name=value,proto3" json:"value"`
+	Timestamp int64   `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp"`
+}
+
+//Reset implements proto.Message.
+func (m *Sample) Reset() { *m = Sample{} }
+
+//String implements proto.Message.
+func (m *Sample) String() string { return proto.CompactTextString(m) }
+
+//ProtoMessage implements proto.Message.
+func (*Sample) ProtoMessage() {}
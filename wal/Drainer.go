@@ -0,0 +1,265 @@
+package wal
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/spitefulgrog/nagflux/processor"
+	"github.com/spitefulgrog/nagflux/routing"
+	"github.com/kdar/factorlog"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Encoder converts a printable into the entries of the write-ahead log, these are replayed as collector.SimplePrintable
+//with the Datatype of the target. It's the same format the targets used for their dumpfiles.
+type Encoder func(printable collector.Printable) []string
+
+//Interval in which the drainer checks if the target is alive, while there is data to drain.
+const aliveCheckInterval = time.Duration(10) * time.Second
+
+//Entries which are moved in one step from the disk to the in-memory queue.
+const drainBatchSize = 500
+
+var drainers = map[data.Target]*Drainer{}
+var drainersMutex = &sync.RWMutex{}
+
+//Drainer spills the data of a target to its write-ahead log and moves it back to the in-memory queue once the target is alive.
+type Drainer struct {
+	quit      chan bool
+	replay    chan bool
+	jobs      chan collector.Printable
+	queue     *Queue
+	encode    Encoder
+	isAlive   func() bool
+	target    data.Target
+	log       *factorlog.FactorLog
+	IsRunning bool
+}
+
+//NewDrainer registers the queue for the target and starts draining it. isAlive should test the target actively.
+func NewDrainer(target data.Target, queue *Queue, jobs chan collector.Printable, encode Encoder, isAlive func() bool) *Drainer {
+	d := &Drainer{
+		quit: make(chan bool), replay: make(chan bool, 1), jobs: jobs, queue: queue, encode: encode, isAlive: isAlive,
+		target: target, log: logging.GetLogger(), IsRunning: true,
+	}
+	drainersMutex.Lock()
+	drainers[target] = d
+	drainersMutex.Unlock()
+	go d.run()
+	return d
+}
+
+//Stop stops draining, the queue stays registered so the workers can still spill their remaining data.
+func (d *Drainer) Stop() {
+	if d.IsRunning {
+		d.quit <- true
+		<-d.quit
+		d.IsRunning = false
+		d.log.Debug("Drainer(" + d.target.Name + ") stopped")
+	}
+}
+
+//Close stops draining, unregisters the queue and closes it. Data of the target can't be spilled afterwards.
+func (d *Drainer) Close() error {
+	d.Stop()
+	drainersMutex.Lock()
+	if drainers[d.target] == d {
+		delete(drainers, d.target)
+	}
+	drainersMutex.Unlock()
+	return d.queue.Close()
+}
+
+//Replay imports the dumpfile if there is one and drains the queue without waiting for the next alive check.
+func (d *Drainer) Replay(dumpFile string, wholeFile bool) error {
+	if err := ImportDumpfile(d.queue, dumpFile, wholeFile); err != nil {
+		return err
+	}
+	select {
+	case d.replay <- true:
+	default:
+	}
+	return nil
+}
+
+//Pending returns the amount of bytes in the write-ahead log which are not drained yet.
+func (d *Drainer) Pending() int64 {
+	return d.queue.Pending()
+}
+
+//Evicted returns the amount of bytes which got dropped due to the size limit.
+func (d *Drainer) Evicted() int64 {
+	return d.queue.Evicted()
+}
+
+func (d *Drainer) run() {
+	var lastCheck time.Time
+	alive := false
+	wait := time.Duration(0)
+	for {
+		select {
+		case <-d.quit:
+			d.quit <- true
+			return
+		case <-d.replay:
+			lastCheck = time.Time{}
+		case <-time.After(wait):
+		}
+		wait = time.Duration(1) * time.Second
+		if d.queue.Pending() == 0 {
+			continue
+		}
+		if time.Since(lastCheck) >= aliveCheckInterval {
+			alive = d.isAlive()
+			lastCheck = time.Now()
+		}
+		if !alive {
+			continue
+		}
+		//Leave room for the live data
+		free := cap(d.jobs)/2 - len(d.jobs)
+		if cap(d.jobs) < 2 {
+			free = 1
+		}
+		if free <= 0 {
+			continue
+		}
+		if free > drainBatchSize {
+			free = drainBatchSize
+		}
+		entries := d.queue.Peek(free)
+		sent := 0
+		for _, entry := range entries {
+			select {
+			case <-d.quit:
+				d.queue.Ack(sent)
+				d.quit <- true
+				return
+			case d.jobs <- collector.SimplePrintable{Filterable: collector.AllFilterable, Text: string(entry), Datatype: d.target.Datatype}:
+				sent++
+			}
+		}
+		d.queue.Ack(sent)
+		if sent > 0 {
+			d.log.Debugf("Drained %d entries of %s", sent, d.target.Name)
+			wait = time.Duration(10) * time.Millisecond
+		}
+	}
+}
+
+func getDrainer(target data.Target) *Drainer {
+	drainersMutex.RLock()
+	defer drainersMutex.RUnlock()
+	return drainers[target]
+}
+
+//Append writes the already encoded entries to the write-ahead log of the target, a trailing newline is removed.
+func Append(target data.Target, entries []string) error {
+	d := getDrainer(target)
+	if d == nil {
+		return fmt.Errorf("There is no write-ahead log for %s(%s)", target.Name, target.Datatype)
+	}
+	raw := make([][]byte, 0, len(entries))
+	for _, entry := range entries {
+		if entry = strings.TrimSuffix(entry, "\n"); entry != "" {
+			raw = append(raw, []byte(entry))
+		}
+	}
+	return d.queue.Append(raw...)
+}
+
+//Spill encodes the printable and writes it to the write-ahead log of the target.
+//Returns false if the target has no log, printables which are not meant for this target count as spilled.
+func Spill(target data.Target, printable collector.Printable) bool {
+	d := getDrainer(target)
+	if d == nil {
+		return false
+	}
+	if !printable.TestTargetFilter(target.Name) {
+		return true
+	}
+	if err := Append(target, d.encode(printable)); err != nil {
+		d.log.Critical(err)
+		return false
+	}
+	return true
+}
+
+//Deliver routes the printable, runs the processors of the target and puts it into its queue. If the queue is full it's spilled
+//to the write-ahead log, targets without log are waited for till the timeout. Returns false if a quit signal arrives, quit may be nil.
+func Deliver(target data.Target, queue chan collector.Printable, printable collector.Printable, quit chan bool, timeout time.Duration) bool {
+	if printable = routing.Route(target, printable); printable == nil {
+		//nothing is routed to this target
+		return true
+	}
+	if printable.TestTargetFilter(target.Name) {
+		if printable = processor.Process(target, printable); printable == nil {
+			//every point got dropped
+			return true
+		}
+	}
+	select {
+	case queue <- printable:
+		return true
+	default:
+	}
+	if Spill(target, printable) {
+		return true
+	}
+	select {
+	case <-quit:
+		return false
+	case queue <- printable:
+	case <-time.After(timeout):
+		logging.GetLogger().Warnf("Could not write to the buffer of %s(%s)", target.Name, target.Datatype)
+	}
+	return true
+}
+
+//ImportDumpfile moves the entries of a dumpfile of older versions into the queue and removes the file afterwards.
+//If wholeFile is set the file is one entry, otherwise every line.
+func ImportDumpfile(queue *Queue, filename string, wholeFile bool) error {
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+	var entries [][]byte
+	if wholeFile {
+		content, err := ioutil.ReadAll(file)
+		if err != nil {
+			return err
+		}
+		if content = bytes.TrimSuffix(content, []byte("\n")); len(content) > 0 {
+			entries = append(entries, content)
+		}
+	} else {
+		reader := bufio.NewReader(file)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if line = bytes.TrimSuffix(line, []byte("\n")); len(line) > 0 {
+				entries = append(entries, line)
+			}
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+	if err := queue.Append(entries...); err != nil {
+		return err
+	}
+	logging.GetLogger().Infof("Imported %d entries of the dumpfile %s", len(entries), filename)
+	return os.Remove(filename)
+}
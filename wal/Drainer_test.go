@@ -0,0 +1,117 @@
+package wal
+
+import (
+	"github.com/spitefulgrog/nagflux/collector"
+	"github.com/spitefulgrog/nagflux/data"
+	"github.com/spitefulgrog/nagflux/logging"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+//The tests pass just SimplePrintables through the log.
+func encodeText(p collector.Printable) []string {
+	return []string{p.(collector.SimplePrintable).Text}
+}
+
+func TestDrainerSpillsAndDrains(t *testing.T) {
+	logging.InitTestLogger()
+	dir, _ := ioutil.TempDir("", "wal")
+	defer os.RemoveAll(dir)
+	q, err := Open(dir, 1<<20, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	target := data.Target{Name: "test", Datatype: data.InfluxDB}
+	jobs := make(chan collector.Printable, 4)
+	drainer := NewDrainer(target, q, jobs, encodeText, func() bool { return true })
+	defer drainer.Stop()
+
+	for i := 0; i < 6; i++ {
+		printable := collector.SimplePrintable{Filterable: collector.AllFilterable, Text: "line", Datatype: data.InfluxDB}
+		if !Deliver(target, jobs, printable, nil, time.Second) {
+			t.Fatal("Deliver should not quit")
+		}
+	}
+	//not meant for this target, so it's neither queued nor stored
+	Deliver(target, jobs, collector.SimplePrintable{Filterable: collector.Filterable{Filter: "other"}, Datatype: data.InfluxDB}, nil, time.Second)
+	if len(jobs) != 4 || q.Pending() == 0 {
+		t.Fatalf("Expected a full queue and spilled data, queue: %d, pending: %d", len(jobs), q.Pending())
+	}
+
+	received := 0
+	timeout := time.After(time.Duration(5) * time.Second)
+	for received < 6 {
+		select {
+		case job := <-jobs:
+			if job.(collector.SimplePrintable).Text != "line" {
+				t.Errorf("Unexpected job: %v", job)
+			}
+			received++
+		case <-timeout:
+			t.Fatalf("Received just %d jobs, pending: %d", received, q.Pending())
+		}
+	}
+	if q.Pending() != 0 {
+		t.Errorf("Expected everything to be drained, pending: %d", q.Pending())
+	}
+}
+
+func TestImportDumpfile(t *testing.T) {
+	logging.InitTestLogger()
+	dir, _ := ioutil.TempDir("", "wal")
+	defer os.RemoveAll(dir)
+	q, err := Open(path.Join(dir, "wal"), 1<<20, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	dumpfile := path.Join(dir, "dump")
+	ioutil.WriteFile(dumpfile, []byte("a\n\nb\nc"), 0600)
+	if err := ImportDumpfile(q, dumpfile, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dumpfile); !os.IsNotExist(err) {
+		t.Error("The dumpfile should be removed")
+	}
+	entries := q.Peek(10)
+	if len(entries) != 3 || string(entries[0]) != "a" || string(entries[2]) != "c" {
+		t.Errorf("Unexpected entries: %q", entries)
+	}
+}
+
+func TestDrainerClose(t *testing.T) {
+	logging.InitTestLogger()
+	dir, _ := ioutil.TempDir("", "wal")
+	defer os.RemoveAll(dir)
+	q, err := Open(dir, 1<<20, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := data.Target{Name: "closed", Datatype: data.InfluxDB}
+	drainer := NewDrainer(target, q, make(chan collector.Printable), encodeText, func() bool { return false })
+	if err := Append(target, []string{"line\n"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := drainer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if Append(target, []string{"line\n"}) == nil {
+		t.Error("A closed queue should not be used anymore")
+	}
+
+	//the data survives for the next drainer
+	q, err = Open(dir, 1<<20, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	if entries := q.Peek(10); len(entries) != 1 {
+		t.Errorf("Expected the spilled entry, got: %q", entries)
+	}
+}
@@ -0,0 +1,325 @@
+package wal
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/spitefulgrog/nagflux/logging"
+	"github.com/kdar/factorlog"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//Every record starts with the length and the CRC32-C of the payload.
+const recordHeaderSize = 8
+
+const segmentSuffix = ".seg"
+const cursorFile = "cursor"
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+type position struct {
+	segment int64
+	offset  int64
+}
+
+//Queue is a segmented on-disk FIFO. Records are appended to the newest segment, read from the oldest one
+//and segments are deleted once they are consumed. If the size exceeds the limit the oldest segments are evicted.
+type Queue struct {
+	dir         string
+	segmentSize int64
+	maxSize     int64
+	mutex       sync.Mutex
+	segments    []int64
+	sizes       map[int64]int64
+	size        int64
+	writer      *os.File
+	read        position
+	peeked      []position
+	evicted     int64
+	log         *factorlog.FactorLog
+}
+
+//Open opens or creates the queue within the given directory, writing always starts in a new segment.
+func Open(dir string, segmentSize, maxSize int64) (*Queue, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	q := &Queue{dir: dir, segmentSize: segmentSize, maxSize: maxSize, sizes: map[int64]int64{}, log: logging.GetLogger()}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), segmentSuffix) {
+			continue
+		}
+		if id, err := strconv.ParseInt(strings.TrimSuffix(file.Name(), segmentSuffix), 10, 64); err == nil {
+			q.segments = append(q.segments, id)
+			q.sizes[id] = file.Size()
+			q.size += file.Size()
+		}
+	}
+	sort.Slice(q.segments, func(i, j int) bool { return q.segments[i] < q.segments[j] })
+	q.read = q.loadCursor()
+	if err := q.roll(); err != nil {
+		return nil, err
+	}
+	q.normalizeRead()
+	return q, nil
+}
+
+//Append writes the entries to the newest segment and evicts the oldest segments if the size limit is exceeded.
+func (q *Queue) Append(entries ...[]byte) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for _, entry := range entries {
+		record := make([]byte, recordHeaderSize+len(entry))
+		binary.BigEndian.PutUint32(record[0:4], uint32(len(entry)))
+		binary.BigEndian.PutUint32(record[4:8], crc32.Checksum(entry, crcTable))
+		copy(record[recordHeaderSize:], entry)
+
+		active := q.activeSegment()
+		if q.sizes[active] > 0 && q.sizes[active]+int64(len(record)) > q.segmentSize {
+			if err := q.roll(); err != nil {
+				return err
+			}
+			active = q.activeSegment()
+		}
+		if _, err := q.writer.Write(record); err != nil {
+			return err
+		}
+		q.sizes[active] += int64(len(record))
+		q.size += int64(len(record))
+	}
+	return q.evict()
+}
+
+//Peek returns up to max entries starting at the read position without consuming them, Ack consumes them.
+func (q *Queue) Peek(max int) [][]byte {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.peeked = q.peeked[:0]
+	var entries [][]byte
+	current := q.read
+	for len(entries) < max {
+		if current.offset >= q.sizes[current.segment] {
+			next, ok := q.nextSegment(current.segment)
+			if !ok {
+				break
+			}
+			current = position{segment: next}
+			continue
+		}
+		segmentEntries, positions, err := q.readSegment(current, max-len(entries))
+		entries = append(entries, segmentEntries...)
+		q.peeked = append(q.peeked, positions...)
+		if len(positions) > 0 {
+			current = positions[len(positions)-1]
+		}
+		if err != nil {
+			if current.segment == q.activeSegment() {
+				q.log.Warnf("Could not read the write-ahead log %s: %s", q.dir, err)
+				break
+			}
+			//Skip the rest of the segment, it was not written completely or got damaged
+			q.log.Warnf("Skipping the rest of segment %d of the write-ahead log %s: %s", current.segment, q.dir, err)
+			current = position{segment: current.segment, offset: q.sizes[current.segment]}
+			if len(q.peeked) > 0 {
+				q.peeked[len(q.peeked)-1] = current
+			} else {
+				q.read = current
+			}
+		}
+	}
+	return entries
+}
+
+//Ack consumes the first n entries of the last Peek, consumed segments are deleted.
+func (q *Queue) Ack(n int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if n > len(q.peeked) {
+		n = len(q.peeked)
+	}
+	if n > 0 {
+		q.read = q.peeked[n-1]
+	}
+	q.peeked = q.peeked[:0]
+	q.normalizeRead()
+	for len(q.segments) > 1 && q.segments[0] < q.read.segment {
+		q.removeOldest()
+	}
+	q.storeCursor()
+}
+
+//Pending returns the amount of bytes which are not consumed yet.
+func (q *Queue) Pending() int64 {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	pending := -q.read.offset
+	for _, segment := range q.segments {
+		if segment >= q.read.segment {
+			pending += q.sizes[segment]
+		}
+	}
+	return pending
+}
+
+//Evicted returns the amount of bytes which got dropped due to the size limit.
+func (q *Queue) Evicted() int64 {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.evicted
+}
+
+//Close closes the active segment and stores the read position.
+func (q *Queue) Close() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.storeCursor()
+	return q.writer.Close()
+}
+
+func (q *Queue) activeSegment() int64 {
+	return q.segments[len(q.segments)-1]
+}
+
+func (q *Queue) segmentPath(segment int64) string {
+	return path.Join(q.dir, fmt.Sprintf("%020d%s", segment, segmentSuffix))
+}
+
+func (q *Queue) nextSegment(segment int64) (int64, bool) {
+	for _, s := range q.segments {
+		if s > segment {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+//Starts a new segment for writing.
+func (q *Queue) roll() error {
+	next := int64(1)
+	if len(q.segments) > 0 {
+		next = q.activeSegment() + 1
+	}
+	file, err := os.OpenFile(q.segmentPath(next), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if q.writer != nil {
+		q.writer.Close()
+	}
+	q.writer = file
+	q.segments = append(q.segments, next)
+	q.sizes[next] = 0
+	return nil
+}
+
+//Removes the oldest segments till the size fits. The active segment is never removed, it may exceed the size on its
+//own if a single entry is larger than the limit.
+func (q *Queue) evict() error {
+	for q.size > q.maxSize && len(q.segments) > 1 {
+		evicted := q.sizes[q.segments[0]]
+		if q.segments[0] == q.read.segment {
+			evicted -= q.read.offset
+		} else if q.segments[0] < q.read.segment {
+			evicted = 0
+		}
+		q.evicted += evicted
+		q.log.Warnf("The write-ahead log %s exceeds %d bytes, dropping %d bytes of the oldest data", q.dir, q.maxSize, evicted)
+		q.removeOldest()
+		q.normalizeRead()
+		q.storeCursor()
+	}
+	return nil
+}
+
+func (q *Queue) removeOldest() {
+	oldest := q.segments[0]
+	if err := os.Remove(q.segmentPath(oldest)); err != nil {
+		q.log.Warn(err)
+	}
+	q.size -= q.sizes[oldest]
+	delete(q.sizes, oldest)
+	q.segments = q.segments[1:]
+}
+
+//Moves the read position to an existing segment and over the end of completely read segments.
+func (q *Queue) normalizeRead() {
+	if q.read.segment < q.segments[0] {
+		q.read = position{segment: q.segments[0]}
+	}
+	for q.read.offset >= q.sizes[q.read.segment] && q.read.segment != q.activeSegment() {
+		next, _ := q.nextSegment(q.read.segment)
+		q.read = position{segment: next}
+	}
+}
+
+//Reads up to max records of the segment starting at the given position.
+func (q *Queue) readSegment(start position, max int) ([][]byte, []position, error) {
+	file, err := os.Open(q.segmentPath(start.segment))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	if _, err := file.Seek(start.offset, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+	var entries [][]byte
+	var positions []position
+	offset := start.offset
+	header := make([]byte, recordHeaderSize)
+	for len(entries) < max && offset < q.sizes[start.segment] {
+		if _, err := io.ReadFull(file, header); err != nil {
+			return entries, positions, err
+		}
+		length := int64(binary.BigEndian.Uint32(header[0:4]))
+		if offset+recordHeaderSize+length > q.sizes[start.segment] {
+			return entries, positions, fmt.Errorf("record at %d exceeds the segment", offset)
+		}
+		entry := make([]byte, length)
+		if _, err := io.ReadFull(file, entry); err != nil {
+			return entries, positions, err
+		}
+		if crc32.Checksum(entry, crcTable) != binary.BigEndian.Uint32(header[4:8]) {
+			return entries, positions, fmt.Errorf("checksum mismatch at %d", offset)
+		}
+		offset += recordHeaderSize + length
+		entries = append(entries, entry)
+		positions = append(positions, position{segment: start.segment, offset: offset})
+	}
+	return entries, positions, nil
+}
+
+func (q *Queue) loadCursor() position {
+	content, err := ioutil.ReadFile(path.Join(q.dir, cursorFile))
+	if err != nil {
+		return position{}
+	}
+	var cursor position
+	if _, err := fmt.Sscanf(string(content), "%d %d", &cursor.segment, &cursor.offset); err != nil {
+		q.log.Warnf("Could not parse the cursor of the write-ahead log %s: %s", q.dir, err)
+		return position{}
+	}
+	return cursor
+}
+
+//Stores the read position, the rename makes sure that the file is always complete.
+func (q *Queue) storeCursor() {
+	tmp := path.Join(q.dir, cursorFile+".tmp")
+	if err := ioutil.WriteFile(tmp, []byte(fmt.Sprintf("%d %d\n", q.read.segment, q.read.offset)), 0600); err != nil {
+		q.log.Warn(err)
+		return
+	}
+	if err := os.Rename(tmp, path.Join(q.dir, cursorFile)); err != nil {
+		q.log.Warn(err)
+	}
+}
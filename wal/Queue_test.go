@@ -0,0 +1,152 @@
+package wal
+
+import (
+	"fmt"
+	"github.com/spitefulgrog/nagflux/logging"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func genEntries(from, to int) [][]byte {
+	var entries [][]byte
+	for i := from; i < to; i++ {
+		entries = append(entries, []byte(fmt.Sprintf("entry %03d", i)))
+	}
+	return entries
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result []string
+	for _, file := range files {
+		if path.Ext(file.Name()) == segmentSuffix {
+			result = append(result, file.Name())
+		}
+	}
+	return result
+}
+
+func TestQueueRoundTrip(t *testing.T) {
+	logging.InitTestLogger()
+	dir, _ := ioutil.TempDir("", "wal")
+	defer os.RemoveAll(dir)
+
+	//every record is 17 bytes, so a segment takes 3 of them
+	q, err := Open(dir, 60, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Append(genEntries(0, 10)...); err != nil {
+		t.Fatal(err)
+	}
+	if len(segmentFiles(t, dir)) != 4 {
+		t.Errorf("Expected 4 segments, got: %v", segmentFiles(t, dir))
+	}
+	if actual := q.Peek(4); !reflect.DeepEqual(actual, genEntries(0, 4)) {
+		t.Errorf("Unexpected entries: %q", actual)
+	}
+	q.Ack(4)
+	if len(segmentFiles(t, dir)) != 3 {
+		t.Errorf("The consumed segment should be deleted, got: %v", segmentFiles(t, dir))
+	}
+	//not acknowledged entries are returned again
+	q.Peek(2)
+	q.Close()
+
+	q, err = Open(dir, 60, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	q.Append(genEntries(10, 12)...)
+	if actual := q.Peek(100); !reflect.DeepEqual(actual, genEntries(4, 12)) {
+		t.Errorf("Unexpected entries after reopen: %q", actual)
+	}
+	q.Ack(100)
+	if pending := q.Pending(); pending != 0 {
+		t.Errorf("Expected nothing pending, got: %d", pending)
+	}
+	if actual := q.Peek(100); len(actual) != 0 {
+		t.Errorf("Expected no entries, got: %q", actual)
+	}
+}
+
+func TestQueueEviction(t *testing.T) {
+	logging.InitTestLogger()
+	dir, _ := ioutil.TempDir("", "wal")
+	defer os.RemoveAll(dir)
+
+	q, err := Open(dir, 60, 120)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	q.Append(genEntries(0, 20)...)
+	if q.Evicted() == 0 {
+		t.Error("Expected evicted data")
+	}
+	actual := q.Peek(100)
+	if len(actual) == 0 || len(actual) > 7 {
+		t.Fatalf("Expected the newest entries, got: %q", actual)
+	}
+	if !reflect.DeepEqual(actual, genEntries(20-len(actual), 20)) {
+		t.Errorf("The oldest entries should be dropped, got: %q", actual)
+	}
+}
+
+//An entry which exceeds the size on its own is kept till the next one arrives.
+func TestQueueKeepsActiveSegment(t *testing.T) {
+	logging.InitTestLogger()
+	dir, _ := ioutil.TempDir("", "wal")
+	defer os.RemoveAll(dir)
+
+	q, err := Open(dir, 30, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	large := []byte("an entry which is larger than the write-ahead log")
+	q.Append(large)
+	if actual := q.Peek(100); !reflect.DeepEqual(actual, [][]byte{large}) || q.Evicted() != 0 {
+		t.Fatalf("The entry which was just written should be kept, got: %q", actual)
+	}
+	q.Append(genEntries(0, 1)...)
+	if actual := q.Peek(100); !reflect.DeepEqual(actual, genEntries(0, 1)) {
+		t.Errorf("The large entry should be evicted by the next one, got: %q", actual)
+	}
+}
+
+func TestQueueSkipsCorruptedSegment(t *testing.T) {
+	logging.InitTestLogger()
+	dir, _ := ioutil.TempDir("", "wal")
+	defer os.RemoveAll(dir)
+
+	q, err := Open(dir, 60, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Append(genEntries(0, 6)...)
+	q.Close()
+
+	//damage the second record of the first segment
+	first := path.Join(dir, segmentFiles(t, dir)[0])
+	content, _ := ioutil.ReadFile(first)
+	content[17+recordHeaderSize] ^= 0xff
+	ioutil.WriteFile(first, content, 0600)
+
+	q, err = Open(dir, 60, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	expected := append(genEntries(0, 1), genEntries(3, 6)...)
+	if actual := q.Peek(100); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected: %q, got: %q", expected, actual)
+	}
+}